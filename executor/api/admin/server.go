@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ENV_ADMIN_TOKEN is the bearer token requests to the admin API must carry.
+	ENV_ADMIN_TOKEN = "EXECUTOR_ADMIN_TOKEN"
+
+	contentTypeJSON = "application/json"
+)
+
+// Version is the version of the executor, set when it is built.
+var Version = "unknown"
+
+// KafkaConsumer is the consumer of a Kafka server whose assigned partitions are reported.
+type KafkaConsumer interface {
+	Assignment() ([]kafka.TopicPartition, error)
+}
+
+// SeldonAdminApi serves the state of the running executor to operators on its own listener. Every request must carry
+// the admin token as a bearer token.
+type SeldonAdminApi struct {
+	Router    *mux.Router
+	Log       logr.Logger
+	predictor *v1.PredictorSpec
+	token     string
+	kafka     KafkaConsumer
+}
+
+// NewAdminApi creates the admin API for the predictor. The Kafka consumer is nil unless the executor serves Kafka.
+func NewAdminApi(predictor *v1.PredictorSpec, token string, kafka KafkaConsumer) *SeldonAdminApi {
+	return &SeldonAdminApi{
+		Router:    mux.NewRouter(),
+		Log:       logf.Log.WithName("SeldonAdminApi"),
+		predictor: predictor,
+		token:     token,
+		kafka:     kafka,
+	}
+}
+
+func (a *SeldonAdminApi) Initialise() {
+	a.Router.Use(a.authenticate)
+	admin := a.Router.PathPrefix("/admin").Methods("GET").Subrouter()
+	admin.HandleFunc("/predictor", a.activePredictor)
+	admin.HandleFunc("/health", a.health)
+	admin.HandleFunc("/routing", a.routing)
+	admin.HandleFunc("/logger", a.logger)
+	admin.HandleFunc("/kafka", a.kafkaAssignment)
+	admin.HandleFunc("/version", a.version)
+}
+
+func (a *SeldonAdminApi) CreateHttpServer(port int) *http.Server {
+	address := fmt.Sprintf("0.0.0.0:%d", port)
+	a.Log.Info("Listening", "Address", address)
+	return &http.Server{
+		Handler:      a.Router,
+		Addr:         address,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+}
+
+// authenticate rejects requests without the admin token. The token is compared in constant time.
+func (a *SeldonAdminApi) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(auth, "Bearer ")
+		if a.token == "" || token == auth || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			a.respondWithError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *SeldonAdminApi) activePredictor(w http.ResponseWriter, r *http.Request) {
+	a.respondWithJSON(w, http.StatusOK, predictor.ActivePredictor(a.predictor))
+}
+
+func (a *SeldonAdminApi) health(w http.ResponseWriter, r *http.Request) {
+	a.respondWithJSON(w, http.StatusOK, predictor.GraphHealth(&predictor.ActivePredictor(a.predictor).Graph))
+}
+
+func (a *SeldonAdminApi) routing(w http.ResponseWriter, r *http.Request) {
+	a.respondWithJSON(w, http.StatusOK, predictor.RoutingTable())
+}
+
+func (a *SeldonAdminApi) logger(w http.ResponseWriter, r *http.Request) {
+	a.respondWithJSON(w, http.StatusOK, payloadLogger.GetQueueStats())
+}
+
+type topicPartition struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    string `json:"offset"`
+}
+
+func (a *SeldonAdminApi) kafkaAssignment(w http.ResponseWriter, r *http.Request) {
+	if a.kafka == nil {
+		a.respondWithError(w, http.StatusNotFound, "executor is not serving kafka")
+		return
+	}
+	assignment, err := a.kafka.Assignment()
+	if err != nil {
+		a.respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	partitions := make([]topicPartition, 0, len(assignment))
+	for _, tp := range assignment {
+		var topic string
+		if tp.Topic != nil {
+			topic = *tp.Topic
+		}
+		partitions = append(partitions, topicPartition{Topic: topic, Partition: tp.Partition, Offset: tp.Offset.String()})
+	}
+	a.respondWithJSON(w, http.StatusOK, partitions)
+}
+
+type versionInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+	GraphHash string `json:"graphHash"`
+}
+
+func (a *SeldonAdminApi) version(w http.ResponseWriter, r *http.Request) {
+	a.respondWithJSON(w, http.StatusOK, versionInfo{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		GraphHash: predictor.GetActiveGraph(a.predictor).Hash,
+	})
+}
+
+func (a *SeldonAdminApi) respondWithJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		a.respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	if _, err = w.Write(data); err != nil {
+		a.Log.Error(err, "Failed to write response")
+	}
+}
+
+func (a *SeldonAdminApi) respondWithError(w http.ResponseWriter, code int, msg string) {
+	data, _ := json.Marshal(map[string]string{"error": msg})
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		a.Log.Error(err, "Failed to write response")
+	}
+}
@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const testToken = "secret"
+
+type testKafkaConsumer struct {
+	assignment []kafka.TopicPartition
+	err        error
+}
+
+func (c *testKafkaConsumer) Assignment() ([]kafka.TopicPartition, error) {
+	return c.assignment, c.err
+}
+
+func createTestAdminApi(kafkaConsumer KafkaConsumer) *SeldonAdminApi {
+	model := v1.MODEL
+	spec := &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "classifier",
+			Type: &model,
+		},
+	}
+	a := NewAdminApi(spec, testToken, kafkaConsumer)
+	a.Initialise()
+	return a
+}
+
+func adminRequest(a *SeldonAdminApi, path string, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	a.Router.ServeHTTP(res, req)
+	return res
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	a := createTestAdminApi(nil)
+	g.Expect(adminRequest(a, "/admin/predictor", "").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(adminRequest(a, "/admin/predictor", "wrong").Code).To(Equal(http.StatusUnauthorized))
+	g.Expect(adminRequest(a, "/admin/predictor", testToken).Code).To(Equal(http.StatusOK))
+
+	// A token is always required, even if none is configured
+	a = NewAdminApi(a.predictor, "", nil)
+	a.Initialise()
+	g.Expect(adminRequest(a, "/admin/version", "").Code).To(Equal(http.StatusUnauthorized))
+}
+
+func TestAdminPredictor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	res := adminRequest(createTestAdminApi(nil), "/admin/predictor", testToken)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+	var spec v1.PredictorSpec
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &spec)).To(Succeed())
+	g.Expect(spec.Name).To(Equal("p"))
+	g.Expect(spec.Graph.Name).To(Equal("classifier"))
+}
+
+func TestAdminVersion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	res := adminRequest(createTestAdminApi(nil), "/admin/version", testToken)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+	var info versionInfo
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &info)).To(Succeed())
+	g.Expect(info.Version).To(Equal(Version))
+	g.Expect(info.GoVersion).ToNot(BeEmpty())
+	g.Expect(info.GraphHash).ToNot(BeEmpty())
+}
+
+func TestAdminLogger(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	res := adminRequest(createTestAdminApi(nil), "/admin/logger", testToken)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+	g.Expect(res.Body.String()).To(ContainSubstring(`"dropped":`))
+}
+
+func TestAdminKafka(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(adminRequest(createTestAdminApi(nil), "/admin/kafka", testToken).Code).To(Equal(http.StatusNotFound))
+
+	topic := "input"
+	consumer := &testKafkaConsumer{assignment: []kafka.TopicPartition{{Topic: &topic, Partition: 2, Offset: kafka.OffsetStored}}}
+	res := adminRequest(createTestAdminApi(consumer), "/admin/kafka", testToken)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+	var partitions []topicPartition
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &partitions)).To(Succeed())
+	g.Expect(partitions).To(Equal([]topicPartition{{Topic: "input", Partition: 2, Offset: "stored"}}))
+
+	consumer = &testKafkaConsumer{err: errors.New("kafka server is not consuming")}
+	g.Expect(adminRequest(createTestAdminApi(consumer), "/admin/kafka", testToken).Code).To(Equal(http.StatusServiceUnavailable))
+}
@@ -0,0 +1,46 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+)
+
+// checkpoint records how far through the input the output file has been written. Rows before Row in File, and all
+// rows of the files before it, have been written to the first Offset bytes of the output.
+type checkpoint struct {
+	File   string `json:"file"`
+	Row    int    `json:"row"`
+	Offset int64  `json:"offset"`
+	Done   bool   `json:"done"`
+}
+
+// loadCheckpoint returns nil if there is no checkpoint file.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// save replaces the checkpoint file so a crash while writing leaves the previous checkpoint in place.
+func (cp *checkpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
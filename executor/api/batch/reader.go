@@ -0,0 +1,160 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/seldonio/seldon-core/executor/api"
+)
+
+const maxLineSize = 64 * 1024 * 1024
+
+// inputRow is a request read from an input file. Rows that could not be read have err set and are written to the
+// output with the error.
+type inputRow struct {
+	file string
+	row  int
+	data []byte
+	err  error
+}
+
+// rowReader reads the requests of an input file, returning io.EOF at the end of the file.
+type rowReader interface {
+	next() ([]byte, error)
+}
+
+// listInputFiles returns the input file or the JSONL and CSV files in the input directory in name order.
+func listInputFiles(inputPath string) ([]string, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if !isInputFile(inputPath) {
+			return nil, fmt.Errorf("input file %s must have a .jsonl, .ndjson, .json or .csv extension", inputPath)
+		}
+		return []string{inputPath}, nil
+	}
+	entries, err := os.ReadDir(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isInputFile(entry.Name()) {
+			files = append(files, filepath.Join(inputPath, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func isInputFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jsonl", ".ndjson", ".json", ".csv":
+		return true
+	}
+	return false
+}
+
+func newRowReader(file io.Reader, name string, protocol string) (rowReader, error) {
+	if strings.ToLower(filepath.Ext(name)) == ".csv" {
+		return newCsvReader(file, protocol)
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &jsonlReader{scanner: scanner}, nil
+}
+
+// jsonlReader reads a request from each line that is not blank.
+type jsonlReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *jsonlReader) next() ([]byte, error) {
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if line != "" {
+			return []byte(line), nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// csvReader turns each row after the header into a request for the protocol, using the header as the feature
+// names. Values are sent as numbers where they can be parsed as one and otherwise as strings.
+type csvReader struct {
+	reader   *csv.Reader
+	header   []string
+	protocol string
+}
+
+func newCsvReader(file io.Reader, protocol string) (*csvReader, error) {
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	return &csvReader{reader: reader, header: header, protocol: protocol}, nil
+}
+
+// csvRowError is a row that could not be parsed. The rest of the file can still be read.
+type csvRowError struct {
+	err error
+}
+
+func (e *csvRowError) Error() string {
+	return e.err.Error()
+}
+
+func (r *csvReader) next() ([]byte, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &csvRowError{err: err}
+		}
+		return nil, err
+	}
+	values := make([]interface{}, len(record))
+	for i, field := range record {
+		if f, err := strconv.ParseFloat(field, 64); err == nil {
+			values[i] = f
+		} else {
+			values[i] = field
+		}
+	}
+
+	switch r.protocol {
+	case api.ProtocolTensorflow:
+		instance := make(map[string]interface{}, len(values))
+		for i, name := range r.header {
+			instance[name] = values[i]
+		}
+		return json.Marshal(map[string]interface{}{"instances": []interface{}{instance}})
+	case api.ProtocolV2, api.ProtocolKFServing:
+		inputs := make([]map[string]interface{}, len(values))
+		for i, name := range r.header {
+			datatype := "FP64"
+			if _, ok := values[i].(string); ok {
+				datatype = "BYTES"
+			}
+			inputs[i] = map[string]interface{}{"name": name, "shape": []int{1}, "datatype": datatype, "data": []interface{}{values[i]}}
+		}
+		return json.Marshal(map[string]interface{}{"inputs": inputs})
+	default:
+		return json.Marshal(map[string]interface{}{"data": map[string]interface{}{"names": r.header, "ndarray": [][]interface{}{values}}})
+	}
+}
@@ -0,0 +1,453 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang/protobuf/jsonpb"
+	protoV1 "github.com/golang/protobuf/proto"
+	guuid "github.com/google/uuid"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/rest"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/trace"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	ENV_BATCH_INPUT_PATH      = "BATCH_INPUT_PATH"
+	ENV_BATCH_OUTPUT_PATH     = "BATCH_OUTPUT_PATH"
+	ENV_BATCH_CHECKPOINT_PATH = "BATCH_CHECKPOINT_PATH"
+
+	checkpointInterval = time.Second
+)
+
+// SeldonBatchServer sends the rows of JSONL or CSV input files through the graph and writes a line of JSON with the
+// response or error of each row to an output file, in the order of the input. Progress is saved to a checkpoint file
+// so a job that is stopped or crashes carries on where it left off when run again.
+type SeldonBatchServer struct {
+	Client          client.SeldonApiClient
+	DeploymentName  string
+	Namespace       string
+	Transport       string
+	Predictor       *v1.PredictorSpec
+	InputPath       string
+	OutputPath      string
+	CheckpointPath  string
+	ServerUrl       *url.URL
+	Workers         int
+	BatchSize       int
+	Log             logr.Logger
+	Protocol        string
+	FullHealthCheck bool
+}
+
+func NewBatchServer(
+	workers int,
+	batchSize int,
+	deploymentName,
+	namespace,
+	protocol,
+	transport string,
+	annotations map[string]string,
+	serverUrl *url.URL,
+	predictor *v1.PredictorSpec,
+	inputPath,
+	outputPath,
+	checkpointPath string,
+	log logr.Logger,
+	fullHealthCheck bool,
+) (*SeldonBatchServer, error) {
+	var apiClient client.SeldonApiClient
+	var err error
+	switch transport {
+	case api.TransportRest:
+		apiClient, err = rest.NewJSONRestClient(protocol, deploymentName, predictor, annotations)
+		if err != nil {
+			return nil, err
+		}
+	case api.TransportGrpc:
+		// Rows are read as JSON so can only be converted to protobuf for the Seldon protocol
+		if protocol != api.ProtocolSeldon {
+			return nil, fmt.Errorf("Batch mode only supports the grpc transport with the seldon protocol")
+		}
+		apiClient = seldon.NewSeldonGrpcClient(predictor, deploymentName, annotations)
+	default:
+		return nil, fmt.Errorf("Unknown transport %s", transport)
+	}
+
+	if checkpointPath == "" {
+		checkpointPath = outputPath + ".checkpoint"
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	return &SeldonBatchServer{
+		Client:          apiClient,
+		DeploymentName:  deploymentName,
+		Namespace:       namespace,
+		Transport:       transport,
+		Predictor:       predictor,
+		InputPath:       inputPath,
+		OutputPath:      outputPath,
+		CheckpointPath:  checkpointPath,
+		ServerUrl:       serverUrl,
+		Workers:         workers,
+		BatchSize:       batchSize,
+		Log:             log.WithName("BatchServer"),
+		Protocol:        protocol,
+		FullHealthCheck: fullHealthCheck,
+	}, nil
+}
+
+// outputRow is the line written to the output for each input row.
+type outputRow struct {
+	Puid     string          `json:"puid,omitempty"`
+	File     string          `json:"file"`
+	Row      int             `json:"row"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// rowBatch is a mini-batch of rows sent to the graph together. Its output is sent on result.
+type rowBatch struct {
+	rows   []*inputRow
+	result chan []outputRow
+}
+
+// Serve processes the input and returns once every row has been written to the output or the process is signalled
+// to stop.
+func (bs *SeldonBatchServer) Serve() error {
+	files, err := listInputFiles(bs.InputPath)
+	if err != nil {
+		return err
+	}
+	cp, err := loadCheckpoint(bs.CheckpointPath)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint %s: %w", bs.CheckpointPath, err)
+	}
+	if cp != nil && cp.Done {
+		bs.Log.Info("Batch already complete", "checkpoint", bs.CheckpointPath)
+		return nil
+	}
+
+	// A checkpoint saved before any row was written has no file and starts afresh
+	startFile, startRow := 0, 0
+	if cp != nil && cp.File != "" {
+		startFile = -1
+		for i, file := range files {
+			if filepath.Base(file) == cp.File {
+				startFile = i
+			}
+		}
+		if startFile < 0 {
+			return fmt.Errorf("file %s in checkpoint %s not found in input", cp.File, bs.CheckpointPath)
+		}
+		startRow = cp.Row
+		bs.Log.Info("Resuming from checkpoint", "file", cp.File, "row", cp.Row)
+	} else {
+		cp = &checkpoint{}
+	}
+	out, err := openOutput(bs.OutputPath, cp)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	//wait for graph to be ready
+	for {
+		err := predictor.Ready(bs.Protocol, &bs.Predictor.Graph, bs.FullHealthCheck)
+		if err == nil {
+			break
+		}
+		bs.Log.Info("Waiting for graph to be ready")
+		time.Sleep(2 * time.Second)
+	}
+
+	stop := make(chan struct{})
+	sigchan := make(chan os.Signal, 1)
+	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigchan)
+	go func() {
+		if sig, ok := <-sigchan; ok {
+			bs.Log.Info("Stopping after rows in progress", "signal", sig)
+			close(stop)
+		}
+	}()
+
+	// Batches are queued for writing in input order before being handed to a worker
+	pending := make(chan *rowBatch, bs.Workers*2)
+	jobs := make(chan *rowBatch)
+	for i := 0; i < bs.Workers; i++ {
+		go func() {
+			for batch := range jobs {
+				batch.result <- bs.process(batch.rows)
+			}
+		}()
+	}
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		readErr <- bs.readBatches(files[startFile:], startRow, stop, func(rows []*inputRow) {
+			batch := &rowBatch{rows: rows, result: make(chan []outputRow, 1)}
+			pending <- batch
+			jobs <- batch
+		})
+	}()
+
+	writer := bufio.NewWriter(out)
+	saveCheckpoint := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if err := out.Sync(); err != nil {
+			return err
+		}
+		return cp.save(bs.CheckpointPath)
+	}
+	lastSaved := time.Now()
+	cnt := 0
+	for batch := range pending {
+		for _, row := range <-batch.result {
+			line, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			n, err := writer.Write(append(line, '\n'))
+			if err != nil {
+				return err
+			}
+			cp.Offset += int64(n)
+			cnt++
+			if cnt%1000 == 0 {
+				bs.Log.Info("Processed", "rows", cnt)
+			}
+		}
+		last := batch.rows[len(batch.rows)-1]
+		cp.File = filepath.Base(last.file)
+		cp.Row = last.row + 1
+		if time.Since(lastSaved) >= checkpointInterval {
+			if err := saveCheckpoint(); err != nil {
+				return err
+			}
+			lastSaved = time.Now()
+		}
+	}
+
+	err = <-readErr
+	select {
+	case <-stop:
+	default:
+		cp.Done = err == nil
+	}
+	if saveErr := saveCheckpoint(); saveErr != nil {
+		return saveErr
+	}
+	bs.Log.Info("Final Processed", "rows", cnt, "complete", cp.Done)
+	return err
+}
+
+// openOutput truncates the output to the end of the last row recorded in the checkpoint, dropping rows written
+// after it, or creates a new output if there is no checkpoint.
+func openOutput(path string, cp *checkpoint) (*os.File, error) {
+	if cp.File == "" {
+		return os.Create(path)
+	}
+	out, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := out.Truncate(cp.Offset); err != nil {
+		out.Close()
+		return nil, err
+	}
+	if _, err := out.Seek(cp.Offset, io.SeekStart); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+// readBatches reads the rows of each file, skipping the first startRow rows of the first file, and passes them to
+// send in batches of up to BatchSize rows from the same file.
+func (bs *SeldonBatchServer) readBatches(files []string, startRow int, stop <-chan struct{}, send func(rows []*inputRow)) error {
+	for i, file := range files {
+		skip := 0
+		if i == 0 {
+			skip = startRow
+		}
+		if err := bs.readFile(file, skip, stop, send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bs *SeldonBatchServer) readFile(file string, skip int, stop <-chan struct{}, send func(rows []*inputRow)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader, err := newRowReader(f, file, bs.Protocol)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var rows []*inputRow
+	for row := 0; ; row++ {
+		select {
+		case <-stop:
+			if len(rows) > 0 {
+				send(rows)
+			}
+			return nil
+		default:
+		}
+		data, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(*csvRowError); !ok && err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if row < skip {
+			continue
+		}
+		rows = append(rows, &inputRow{file: file, row: row, data: data, err: err})
+		if len(rows) == bs.BatchSize {
+			send(rows)
+			rows = nil
+		}
+	}
+	if len(rows) > 0 {
+		send(rows)
+	}
+	return nil
+}
+
+// process sends a batch of rows to the graph. Rows that can be merged are sent as one request, and are sent one at a
+// time if the merged request fails so each row gets its own response or error.
+func (bs *SeldonBatchServer) process(rows []*inputRow) []outputRow {
+	out := make([]outputRow, len(rows))
+	var msgs []payload.SeldonPayload
+	var idx []int
+	for i, row := range rows {
+		out[i] = outputRow{File: filepath.Base(row.file), Row: row.row}
+		if row.err != nil {
+			out[i].Error = row.err.Error()
+			continue
+		}
+		msg, err := bs.toPayload(row.data)
+		if err != nil {
+			out[i].Error = err.Error()
+			continue
+		}
+		msgs = append(msgs, msg)
+		idx = append(idx, i)
+	}
+
+	if len(msgs) > 1 {
+		if merged, split, ok := predictor.MergePayloads(msgs); ok {
+			puid := guuid.New().String()
+			res, err := bs.predict(puid, merged)
+			var parts []payload.SeldonPayload
+			if err == nil {
+				parts, err = split(res)
+			}
+			if err == nil {
+				for j, i := range idx {
+					out[i].setResponse(puid, parts[j], nil)
+				}
+				return out
+			}
+			bs.Log.Info("Batch failed, sending rows one at a time", "error", err.Error())
+		}
+	}
+	for j, i := range idx {
+		puid := guuid.New().String()
+		res, err := bs.predict(puid, msgs[j])
+		out[i].setResponse(puid, res, err)
+	}
+	return out
+}
+
+func (bs *SeldonBatchServer) toPayload(data []byte) (payload.SeldonPayload, error) {
+	if bs.Transport == api.TransportGrpc {
+		var sm proto.SeldonMessage
+		if err := jsonpb.UnmarshalString(string(data), &sm); err != nil {
+			return nil, err
+		}
+		return &payload.ProtoPayload{Msg: &sm}, nil
+	}
+	return bs.Client.Unmarshall(data, rest.ContentTypeJSON)
+}
+
+func (bs *SeldonBatchServer) predict(puid string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	ctx := context.WithValue(context.Background(), payload.SeldonPUIDHeader, puid)
+
+	// Apply tracing if active
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
+		ctx, serverSpan = tracing.Tracer().Start(ctx, "batchServer", trace.WithSpanKind(trace.SpanKindServer))
+		defer serverSpan.End()
+	}
+
+	headers := map[string][]string{payload.SeldonPUIDHeader: {puid}}
+	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, bs.Client, logf.Log.WithName("BatchClient"), bs.ServerUrl, bs.Namespace, headers, "")
+	return seldonPredictorProcess.Predict(&bs.Predictor.Graph, msg)
+}
+
+// setResponse records the response of a row, which may be an error response from the model.
+func (o *outputRow) setResponse(puid string, msg payload.SeldonPayload, err error) {
+	o.Puid = puid
+	if err != nil {
+		o.Error = err.Error()
+	}
+	if msg == nil || msg.GetPayload() == nil {
+		return
+	}
+	var data []byte
+	if pm, ok := msg.GetPayload().(protoV1.Message); ok {
+		ma := jsonpb.Marshaler{}
+		s, merr := ma.MarshalToString(pm)
+		if merr != nil {
+			o.Error = merr.Error()
+			return
+		}
+		data = []byte(s)
+	} else {
+		var derr error
+		data, derr = payload.DecompressSeldonPayload(msg)
+		if derr != nil {
+			o.Error = derr.Error()
+			return
+		}
+	}
+	if !json.Valid(data) {
+		// Responses that are not JSON are written as a string
+		data, _ = json.Marshal(string(data))
+	}
+	o.Response = data
+}
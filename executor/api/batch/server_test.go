@@ -0,0 +1,213 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// newTestModel returns a model that echoes valid JSON requests and rejects anything else.
+func newTestModel(g *WithT, calls *int32) (*httptest.Server, *v1.PredictorSpec) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if !json.Valid(body) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":{"code":400,"info":"invalid json"}}`))
+			return
+		}
+		w.Write(body)
+	})
+	server := httptest.NewServer(handler)
+	serverUrl, err := url.Parse(server.URL)
+	g.Expect(err).Should(BeNil())
+	urlParts := strings.Split(serverUrl.Host, ":")
+	port, err := strconv.Atoi(urlParts[1])
+	g.Expect(err).Should(BeNil())
+
+	model := v1.MODEL
+	p := &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: urlParts[0],
+				ServicePort: int32(port),
+				Type:        v1.REST,
+				HttpPort:    int32(port),
+			},
+		},
+	}
+	return server, p
+}
+
+func readOutput(g *WithT, path string) []outputRow {
+	f, err := os.Open(path)
+	g.Expect(err).Should(BeNil())
+	defer f.Close()
+	var rows []outputRow
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var row outputRow
+		g.Expect(json.Unmarshal(scanner.Bytes(), &row)).Should(BeNil())
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func newTestBatchServer(g *WithT, p *v1.PredictorSpec, dir string, batchSize int) *SeldonBatchServer {
+	serverUrl, _ := url.Parse("http://localhost")
+	bs, err := NewBatchServer(2, batchSize, "dep", "default", api.ProtocolSeldon, api.TransportRest, nil, serverUrl, p,
+		filepath.Join(dir, "input"), filepath.Join(dir, "output.jsonl"), "", logf.Log, false)
+	g.Expect(err).Should(BeNil())
+	return bs
+}
+
+func TestBatchServer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var calls int32
+	server, p := newTestModel(g, &calls)
+	defer server.Close()
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, "input"), 0755)).Should(BeNil())
+	input := `{"data":{"ndarray":[[1]]}}
+not json
+
+{"data":{"ndarray":[[3]]}}
+`
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "input", "a.jsonl"), []byte(input), 0644)).Should(BeNil())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "input", "b.csv"), []byte("f1,f2\n1,x\n"), 0644)).Should(BeNil())
+
+	bs := newTestBatchServer(g, p, dir, 1)
+	g.Expect(bs.Serve()).Should(BeNil())
+
+	rows := readOutput(g, bs.OutputPath)
+	g.Expect(len(rows)).To(Equal(4))
+	g.Expect(rows[0].File).To(Equal("a.jsonl"))
+	g.Expect(rows[0].Row).To(Equal(0))
+	g.Expect(rows[0].Puid).ToNot(BeEmpty())
+	g.Expect(rows[0].Error).To(BeEmpty())
+	g.Expect(string(rows[0].Response)).To(Equal(`{"data":{"ndarray":[[1]]}}`))
+	g.Expect(rows[1].Row).To(Equal(1))
+	g.Expect(rows[1].Error).ToNot(BeEmpty())
+	g.Expect(rows[2].Row).To(Equal(2))
+	g.Expect(string(rows[2].Response)).To(Equal(`{"data":{"ndarray":[[3]]}}`))
+	g.Expect(rows[3].File).To(Equal("b.csv"))
+	g.Expect(string(rows[3].Response)).To(Equal(`{"data":{"names":["f1","f2"],"ndarray":[[1,"x"]]}}`))
+	g.Expect(rows[0].Puid).ToNot(Equal(rows[2].Puid))
+
+	cp, err := loadCheckpoint(bs.CheckpointPath)
+	g.Expect(err).Should(BeNil())
+	g.Expect(cp.Done).To(BeTrue())
+
+	// A completed batch is not run again
+	calls = 0
+	g.Expect(bs.Serve()).Should(BeNil())
+	g.Expect(calls).To(Equal(int32(0)))
+	g.Expect(len(readOutput(g, bs.OutputPath))).To(Equal(4))
+}
+
+func TestBatchServerMiniBatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var calls int32
+	server, p := newTestModel(g, &calls)
+	defer server.Close()
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, "input"), 0755)).Should(BeNil())
+	input := `{"data":{"ndarray":[[1]]}}
+{"data":{"ndarray":[[2]]}}
+{"data":{"ndarray":[[3]]}}
+`
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "input", "a.jsonl"), []byte(input), 0644)).Should(BeNil())
+
+	bs := newTestBatchServer(g, p, dir, 2)
+	g.Expect(bs.Serve()).Should(BeNil())
+	g.Expect(calls).To(Equal(int32(2)))
+
+	rows := readOutput(g, bs.OutputPath)
+	g.Expect(len(rows)).To(Equal(3))
+	for i, row := range rows {
+		g.Expect(row.Row).To(Equal(i))
+		g.Expect(row.Error).To(BeEmpty())
+		g.Expect(string(row.Response)).To(Equal(`{"data":{"ndarray":[[` + strconv.Itoa(i+1) + `]]}}`))
+	}
+	// Rows sent in the same request share its PUID
+	g.Expect(rows[0].Puid).To(Equal(rows[1].Puid))
+	g.Expect(rows[2].Puid).ToNot(Equal(rows[0].Puid))
+}
+
+func TestBatchServerResume(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var calls int32
+	server, p := newTestModel(g, &calls)
+	defer server.Close()
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, "input"), 0755)).Should(BeNil())
+	input := `{"data":{"ndarray":[[1]]}}
+{"data":{"ndarray":[[2]]}}
+{"data":{"ndarray":[[3]]}}
+`
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "input", "a.jsonl"), []byte(input), 0644)).Should(BeNil())
+
+	// Simulate a crash after the first row was checkpointed and a partial second row was written
+	first := `{"puid":"1","file":"a.jsonl","row":0,"response":{"data":{"ndarray":[[1]]}}}` + "\n"
+	outputPath := filepath.Join(dir, "output.jsonl")
+	g.Expect(ioutil.WriteFile(outputPath, []byte(first+`{"puid":"2","fi`), 0644)).Should(BeNil())
+	cp := &checkpoint{File: "a.jsonl", Row: 1, Offset: int64(len(first))}
+	g.Expect(cp.save(outputPath + ".checkpoint")).Should(BeNil())
+
+	bs := newTestBatchServer(g, p, dir, 1)
+	g.Expect(bs.Serve()).Should(BeNil())
+	g.Expect(calls).To(Equal(int32(2)))
+
+	rows := readOutput(g, outputPath)
+	g.Expect(len(rows)).To(Equal(3))
+	g.Expect(rows[0].Puid).To(Equal("1"))
+	g.Expect(rows[1].Row).To(Equal(1))
+	g.Expect(string(rows[1].Response)).To(Equal(`{"data":{"ndarray":[[2]]}}`))
+	g.Expect(rows[2].Row).To(Equal(2))
+}
+
+func TestBatchServerEmptyCheckpoint(t *testing.T) {
+	g := NewGomegaWithT(t)
+	var calls int32
+	server, p := newTestModel(g, &calls)
+	defer server.Close()
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, "input"), 0755)).Should(BeNil())
+	g.Expect(ioutil.WriteFile(filepath.Join(dir, "input", "a.jsonl"), []byte(`{"data":{"ndarray":[[1]]}}`+"\n"), 0644)).Should(BeNil())
+
+	// Simulate a stop before the first row was written
+	outputPath := filepath.Join(dir, "output.jsonl")
+	g.Expect(ioutil.WriteFile(outputPath, []byte(`{"puid":"1","fi`), 0644)).Should(BeNil())
+	cp := &checkpoint{}
+	g.Expect(cp.save(outputPath + ".checkpoint")).Should(BeNil())
+
+	bs := newTestBatchServer(g, p, dir, 1)
+	g.Expect(bs.Serve()).Should(BeNil())
+	g.Expect(calls).To(Equal(int32(1)))
+
+	rows := readOutput(g, outputPath)
+	g.Expect(len(rows)).To(Equal(1))
+	g.Expect(rows[0].Row).To(Equal(0))
+}
@@ -30,8 +30,20 @@ func (s *KFServingGrpcClient) IsGrpc() bool {
 	return true
 }
 
+// Return model's metadata decoded to payload.ModelMetadata (to build GraphMetadata)
 func (s *KFServingGrpcClient) ModelMetadata(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.ModelMetadata, error) {
-	panic("implement me")
+	resPayload, err := s.Metadata(ctx, modelName, host, port, &payload.ProtoPayload{Msg: &inference.ModelMetadataRequest{Name: modelName}}, meta)
+	if err != nil {
+		return payload.ModelMetadata{}, err
+	}
+	resp := resPayload.GetPayload().(*inference.ModelMetadataResponse)
+	return payload.ModelMetadata{
+		Name:     resp.GetName(),
+		Platform: resp.GetPlatform(),
+		Versions: resp.GetVersions(),
+		Inputs:   resp.GetInputs(),
+		Outputs:  resp.GetOutputs(),
+	}, nil
 }
 
 func NewKFServingGrpcClient(predictor *v1.PredictorSpec, deploymentName string, annotations map[string]string) client.SeldonApiClient {
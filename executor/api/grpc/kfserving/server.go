@@ -2,7 +2,11 @@ package kfserving
 
 import (
 	"context"
+	"io"
+	"net/url"
+
 	"github.com/go-logr/logr"
+	guuid "github.com/google/uuid"
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
 	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
@@ -10,11 +14,19 @@ import (
 	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	protoGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	protoGrpcMetadata "google.golang.org/grpc/metadata"
-	"net/url"
+	"google.golang.org/grpc/status"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// Name returned by the ServerMetadata RPC.
+const serverName = "seldon-core-executor"
+
+// Maximum number of streamed requests processed at the same time. No further requests are read from the stream until
+// the oldest one has been answered, so a slow graph slows down the client.
+const streamInferMaxInFlight = 16
+
 type GrpcKFServingServer struct {
 	Client    client.SeldonApiClient
 	predictor *v1.PredictorSpec
@@ -34,11 +46,15 @@ func NewGrpcKFServingServer(predictor *v1.PredictorSpec, client client.SeldonApi
 }
 
 func (g GrpcKFServingServer) ServerLive(ctx context.Context, request *inference.ServerLiveRequest) (*inference.ServerLiveResponse, error) {
-	panic("Not Implemented")
+	return &inference.ServerLiveResponse{Live: true}, nil
 }
 
 func (g GrpcKFServingServer) ServerReady(ctx context.Context, request *inference.ServerReadyRequest) (*inference.ServerReadyResponse, error) {
-	panic("Not Implemented")
+	err := predictor.ReadyTCP(&predictor.ActivePredictor(g.predictor).Graph)
+	if err != nil {
+		g.Log.V(1).Info("Not ready", "error", err.Error())
+	}
+	return &inference.ServerReadyResponse{Ready: err == nil}, nil
 }
 
 func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.ModelReadyRequest) (*inference.ModelReadyResponse, error) {
@@ -48,7 +64,7 @@ func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Status(&g.predictor.Graph, request.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Status(&predictor.ActivePredictor(g.predictor).Graph, request.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +72,7 @@ func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.
 }
 
 func (g GrpcKFServingServer) ServerMetadata(ctx context.Context, request *inference.ServerMetadataRequest) (*inference.ServerMetadataResponse, error) {
-	panic("Not Implemented")
+	return &inference.ServerMetadataResponse{Name: serverName}, nil
 }
 
 func (g GrpcKFServingServer) ModelMetadata(ctx context.Context, request *inference.ModelMetadataRequest) (*inference.ModelMetadataResponse, error) {
@@ -66,7 +82,7 @@ func (g GrpcKFServingServer) ModelMetadata(ctx context.Context, request *inferen
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, request.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Metadata(&predictor.ActivePredictor(g.predictor).Graph, request.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -78,59 +94,132 @@ func (g GrpcKFServingServer) ModelInfer(ctx context.Context, request *inference.
 	header := protoGrpcMetadata.Pairs(payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	protoGrpc.SetHeader(ctx, header)
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
+	return g.infer(ctx, md, request)
+}
+
+func (g GrpcKFServingServer) infer(ctx context.Context, md protoGrpcMetadata.MD, request *inference.ModelInferRequest) (*inference.ModelInferResponse, error) {
+	spec := predictor.ActivePredictor(g.predictor)
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetModelName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	if _, err := seldonPredictorProcess.ValidateRequest(&spec.Graph, &reqPayload); err != nil {
+		return nil, err
+	}
+	resPayload, err := seldonPredictorProcess.Predict(&spec.Graph, &reqPayload)
+	if trace, ok := seldonPredictorProcess.TraceHeader(); ok {
+		// Headers can not be set once a stream has sent its first response so traces are only returned for unary calls
+		_ = protoGrpc.SetHeader(ctx, protoGrpcMetadata.Pairs(payload.SeldonTraceHeader, trace))
+	}
 	if err != nil {
 		return nil, err
 	}
-	return resPayload.GetPayload().(*inference.ModelInferResponse), nil
+	res, ok := resPayload.GetPayload().(*inference.ModelInferResponse)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "unexpected response of type %T", resPayload.GetPayload())
+	}
+	return res, nil
 }
 
+// ModelStreamInfer runs each request on the stream through the graph. Requests are processed concurrently but the
+// responses are sent in the order the requests were received. Failed requests are answered with an error message and
+// the stream carries on. The PUID returned in the stream's header identifies the stream, and each request is given its
+// own PUID.
 func (g GrpcKFServingServer) ModelStreamInfer(server inference.GRPCInferenceService_ModelStreamInferServer) error {
-	panic("Not Implemented")
+	md := grpc.CollectMetadata(server.Context())
+	err := server.SetHeader(protoGrpcMetadata.Pairs(payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0]))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(server.Context())
+	defer cancel()
+
+	// Holds the pending response of each request in order. Its capacity limits the requests in flight.
+	pending := make(chan chan *inference.ModelStreamInferResponse, streamInferMaxInFlight)
+	sendErr := make(chan error, 1)
+	go func() {
+		for result := range pending {
+			if err := server.Send(<-result); err != nil {
+				cancel()
+				for range pending {
+				}
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- nil
+	}()
+
+	for ctx.Err() == nil {
+		request, err := server.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cancel()
+			close(pending)
+			<-sendErr
+			return err
+		}
+
+		puid := guuid.New().String()
+		requestMd := md.Copy()
+		requestMd.Set(payload.SeldonPUIDHeader, puid)
+		requestCtx := context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
+
+		result := make(chan *inference.ModelStreamInferResponse, 1)
+		pending <- result
+		go func() {
+			res, err := g.infer(requestCtx, requestMd, request)
+			if err != nil {
+				result <- &inference.ModelStreamInferResponse{ErrorMessage: err.Error()}
+			} else {
+				result <- &inference.ModelStreamInferResponse{InferResponse: res}
+			}
+		}()
+	}
+	close(pending)
+	return <-sendErr
 }
 
 func (g GrpcKFServingServer) ModelConfig(ctx context.Context, request *inference.ModelConfigRequest) (*inference.ModelConfigResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "ModelConfig is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) ModelStatistics(ctx context.Context, request *inference.ModelStatisticsRequest) (*inference.ModelStatisticsResponse, error) {
-	panic("Not Implemented")
+	return predictor.ModelStatistics(request.GetName())
 }
 
 func (g GrpcKFServingServer) RepositoryIndex(ctx context.Context, request *inference.RepositoryIndexRequest) (*inference.RepositoryIndexResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "RepositoryIndex is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) RepositoryModelLoad(ctx context.Context, request *inference.RepositoryModelLoadRequest) (*inference.RepositoryModelLoadResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "RepositoryModelLoad is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) RepositoryModelUnload(ctx context.Context, request *inference.RepositoryModelUnloadRequest) (*inference.RepositoryModelUnloadResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "RepositoryModelUnload is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) SystemSharedMemoryStatus(ctx context.Context, request *inference.SystemSharedMemoryStatusRequest) (*inference.SystemSharedMemoryStatusResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "SystemSharedMemoryStatus is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) SystemSharedMemoryRegister(ctx context.Context, request *inference.SystemSharedMemoryRegisterRequest) (*inference.SystemSharedMemoryRegisterResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "SystemSharedMemoryRegister is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) SystemSharedMemoryUnregister(ctx context.Context, request *inference.SystemSharedMemoryUnregisterRequest) (*inference.SystemSharedMemoryUnregisterResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "SystemSharedMemoryUnregister is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) CudaSharedMemoryStatus(ctx context.Context, request *inference.CudaSharedMemoryStatusRequest) (*inference.CudaSharedMemoryStatusResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "CudaSharedMemoryStatus is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) CudaSharedMemoryRegister(ctx context.Context, request *inference.CudaSharedMemoryRegisterRequest) (*inference.CudaSharedMemoryRegisterResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "CudaSharedMemoryRegister is not supported by the executor")
 }
 
 func (g GrpcKFServingServer) CudaSharedMemoryUnregister(ctx context.Context, request *inference.CudaSharedMemoryUnregisterRequest) (*inference.CudaSharedMemoryUnregisterResponse, error) {
-	panic("Not Implemented")
+	return nil, status.Error(codes.Unimplemented, "CudaSharedMemoryUnregister is not supported by the executor")
 }
@@ -0,0 +1,171 @@
+package kfserving
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	protoGrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// inferTestClient answers each request with a response with the same id after sleeping for the number of
+// milliseconds in the id. Requests with the id "fail" return an error. The PUID of each request is recorded by id if
+// puids is set.
+type inferTestClient struct {
+	test.SeldonMessageTestClient
+	puids *sync.Map
+}
+
+func (c inferTestClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	req := msg.GetPayload().(*inference.ModelInferRequest)
+	if req.Id == "fail" {
+		return nil, errors.New("failed")
+	}
+	if c.puids != nil {
+		c.puids.Store(req.Id, ctx.Value(payload.SeldonPUIDHeader))
+	}
+	delay, _ := strconv.Atoi(req.Id)
+	time.Sleep(time.Duration(delay) * time.Millisecond)
+	return &payload.ProtoPayload{Msg: &inference.ModelInferResponse{ModelName: modelName, Id: req.Id}}, nil
+}
+
+type inferTestStream struct {
+	protoGrpc.ServerStream
+	ctx       context.Context
+	requests  chan *inference.ModelInferRequest
+	mu        sync.Mutex
+	responses []*inference.ModelStreamInferResponse
+}
+
+func (s *inferTestStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *inferTestStream) SetHeader(md metadata.MD) error {
+	return nil
+}
+
+func (s *inferTestStream) Recv() (*inference.ModelInferRequest, error) {
+	req, ok := <-s.requests
+	if !ok {
+		return nil, io.EOF
+	}
+	return req, nil
+}
+
+func (s *inferTestStream) Send(res *inference.ModelStreamInferResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, res)
+	return nil
+}
+
+func createTestServer() *GrpcKFServingServer {
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.GRPC,
+			},
+		},
+	}
+	url, _ := url.Parse("http://localhost")
+	return NewGrpcKFServingServer(&p, &inferTestClient{}, url, "default")
+}
+
+func TestModelStreamInfer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := createTestServer()
+	puids := &sync.Map{}
+	server.Client = &inferTestClient{puids: puids}
+
+	stream := &inferTestStream{ctx: context.Background(), requests: make(chan *inference.ModelInferRequest)}
+	done := make(chan error)
+	go func() {
+		done <- server.ModelStreamInfer(stream)
+	}()
+	// The first request is the slowest but is still answered first
+	for _, id := range []string{"50", "fail", "0", "10"} {
+		stream.requests <- &inference.ModelInferRequest{ModelName: "model", Id: id}
+	}
+	close(stream.requests)
+	g.Expect(<-done).To(BeNil())
+
+	g.Expect(stream.responses).To(HaveLen(4))
+	g.Expect(stream.responses[0].InferResponse.Id).To(Equal("50"))
+	g.Expect(stream.responses[1].ErrorMessage).ToNot(BeEmpty())
+	g.Expect(stream.responses[1].InferResponse).To(BeNil())
+	g.Expect(stream.responses[2].InferResponse.Id).To(Equal("0"))
+	g.Expect(stream.responses[3].InferResponse.Id).To(Equal("10"))
+
+	// Each request on the stream has its own PUID
+	seen := map[interface{}]bool{}
+	for _, id := range []string{"50", "0", "10"} {
+		puid, ok := puids.Load(id)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(puid).ToNot(BeEmpty())
+		seen[puid] = true
+	}
+	g.Expect(seen).To(HaveLen(3))
+}
+
+func TestUnimplemented(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := createTestServer()
+
+	_, err := server.ModelConfig(context.Background(), &inference.ModelConfigRequest{Name: "model"})
+	g.Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+	_, err = server.RepositoryIndex(context.Background(), &inference.RepositoryIndexRequest{})
+	g.Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+
+	live, err := server.ServerLive(context.Background(), &inference.ServerLiveRequest{})
+	g.Expect(err).To(BeNil())
+	g.Expect(live.Live).To(BeTrue())
+}
+
+func TestModelStatistics(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := createTestServer()
+	// Statistics are kept for the lifetime of the executor so only the change is checked
+	before := &inference.ModelStatistics{InferenceStats: &inference.InferStatistics{Success: &inference.StatisticDuration{}, Fail: &inference.StatisticDuration{}}}
+	if res, err := server.ModelStatistics(context.Background(), &inference.ModelStatisticsRequest{Name: "statsmodel"}); err == nil {
+		before = res.ModelStats[0]
+	}
+
+	_, err := server.ModelInfer(context.Background(), &inference.ModelInferRequest{ModelName: "statsmodel", Id: "0"})
+	g.Expect(err).To(BeNil())
+	_, err = server.ModelInfer(context.Background(), &inference.ModelInferRequest{ModelName: "statsmodel", Id: "fail"})
+	g.Expect(err).ToNot(BeNil())
+
+	res, err := server.ModelStatistics(context.Background(), &inference.ModelStatisticsRequest{Name: "statsmodel"})
+	g.Expect(err).To(BeNil())
+	g.Expect(res.ModelStats).To(HaveLen(1))
+	stats := res.ModelStats[0]
+	g.Expect(stats.Name).To(Equal("statsmodel"))
+	g.Expect(stats.InferenceCount - before.InferenceCount).To(Equal(uint64(1)))
+	g.Expect(stats.ExecutionCount - before.ExecutionCount).To(Equal(uint64(2)))
+	g.Expect(stats.InferenceStats.Success.Count - before.InferenceStats.Success.Count).To(Equal(uint64(1)))
+	g.Expect(stats.InferenceStats.Fail.Count - before.InferenceStats.Fail.Count).To(Equal(uint64(1)))
+	g.Expect(stats.LastInference).ToNot(BeZero())
+
+	_, err = server.ModelStatistics(context.Background(), &inference.ModelStatisticsRequest{Name: "unknown"})
+	g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+}
@@ -8,12 +8,12 @@ import (
 	"github.com/go-logr/logr"
 	guuid "github.com/google/uuid"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
-	"github.com/opentracing/opentracing-go"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
@@ -54,10 +54,12 @@ func CreateGrpcServer(spec *v1.PredictorSpec, deploymentName string, annotations
 		grpc.MaxSendMsgSize(maxMsgSize),
 	}
 
-	interceptors := []grpc.UnaryServerInterceptor{metric.NewServerMetrics(spec, deploymentName).UnaryServerInterceptor()}
-	if opentracing.IsGlobalTracerRegistered() {
-		interceptors = append(interceptors, grpc_opentracing.UnaryServerInterceptor())
+	// The server span is started first so metric exemplars can refer to its trace
+	var interceptors []grpc.UnaryServerInterceptor
+	if tracing.IsEnabled() {
+		interceptors = append(interceptors, otelgrpc.UnaryServerInterceptor())
 	}
+	interceptors = append(interceptors, metric.NewServerMetrics(spec, deploymentName).UnaryServerInterceptor())
 	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(interceptors...)))
 
 	grpcServer := grpc.NewServer(opts...)
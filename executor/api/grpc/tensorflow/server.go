@@ -13,6 +13,8 @@ import (
 	"github.com/seldonio/seldon-core/executor/predictor"
 	"github.com/seldonio/seldon-core/executor/proto/tensorflow/serving"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	protoGrpc "google.golang.org/grpc"
+	protoGrpcMetadata "google.golang.org/grpc/metadata"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -39,7 +41,11 @@ func (g *GrpcTensorflowServer) execute(ctx context.Context, req proto.Message, m
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName(method), g.ServerUrl, g.Namespace, md, modelName)
 	reqPayload := payload.ProtoPayload{Msg: req}
-	return seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Predict(&predictor.ActivePredictor(g.predictor).Graph, &reqPayload)
+	if trace, ok := seldonPredictorProcess.TraceHeader(); ok {
+		_ = protoGrpc.SetHeader(ctx, protoGrpcMetadata.Pairs(payload.SeldonTraceHeader, trace))
+	}
+	return resPayload, err
 }
 
 func (g *GrpcTensorflowServer) Classify(ctx context.Context, req *serving.ClassificationRequest) (*serving.ClassificationResponse, error) {
@@ -79,7 +85,7 @@ func (g *GrpcTensorflowServer) MultiInference(ctx context.Context, req *serving.
 func (g *GrpcTensorflowServer) GetModelMetadata(ctx context.Context, req *serving.GetModelMetadataRequest) (*serving.GetModelMetadataResponse, error) {
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("GrpcGetModelMetadata"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, req.ModelSpec.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Metadata(&predictor.ActivePredictor(g.predictor).Graph, req.ModelSpec.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +95,7 @@ func (g *GrpcTensorflowServer) GetModelMetadata(ctx context.Context, req *servin
 func (g *GrpcTensorflowServer) GetModelStatus(ctx context.Context, req *serving.GetModelStatusRequest) (*serving.GetModelStatusResponse, error) {
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("GrpcGetModelStatus"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Status(&g.predictor.Graph, req.ModelSpec.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Status(&predictor.ActivePredictor(g.predictor).Graph, req.ModelSpec.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -9,6 +9,7 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/proto/tensorflow/serving"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	status "google.golang.org/grpc/status"
@@ -151,6 +152,54 @@ func TestPredict(t *testing.T) {
 	g.Expect(res.Outputs["x"].FloatVal[1]).Should(Equal(float32(2.0)))
 }
 
+// headerStream records the headers a server sets.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "Predict" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func TestPredictTraceHeader(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.GRPC,
+			},
+		},
+	}
+	url, _ := url.Parse("http://localhost")
+	server := NewGrpcTensorflowServer(&p, NewTestTensorflowClient(t), url, "default")
+
+	var sm serving.PredictRequest
+	var data = `{"model_spec":{"name":"half_plus_two"},"inputs":{"x":{"dtype": 1, "tensor_shape": {"dim":[{"size": 3}]}, "floatVal" : [1.0, 2.0, 3.0]}}}`
+	g.Expect(jsonpb.UnmarshalString(data, &sm)).Should(BeNil())
+
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = metadata.NewIncomingContext(ctx, metadata.New(map[string]string{TestMetaDataKey: TestMetaDataVal, payload.SeldonTraceHeader: "true"}))
+	_, err := server.Predict(ctx, &sm)
+	g.Expect(err).To(BeNil())
+	g.Expect(stream.header.Get(payload.SeldonTraceHeader)).To(HaveLen(1))
+	g.Expect(stream.header.Get(payload.SeldonTraceHeader)[0]).To(ContainSubstring(`"node":"model"`))
+}
+
 func TestGetModelStatus(t *testing.T) {
 	t.Logf("Started")
 	g := NewGomegaWithT(t)
@@ -8,6 +8,8 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/rest"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	"go.opentelemetry.io/otel"
 	"os"
 	"os/signal"
 	"syscall"
@@ -130,6 +132,9 @@ func (kp *KafkaProxy) Consume() error {
 				ctx := context.Background()
 				// Add Seldon Puid to Context
 				ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
+				if tracing.IsEnabled() {
+					ctx = otel.GetTextMapPropagator().Extract(ctx, tracing.MetadataCarrier(headers))
+				}
 
 				// Assume JSON if no content type - should maybe be application/octet-stream?
 				contentType := rest.ContentTypeJSON
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
@@ -56,6 +57,7 @@ type SeldonKafkaServer struct {
 	Protocol        string
 	FullHealthCheck bool
 	AutoCommit      bool
+	consumerMutex   sync.RWMutex
 }
 
 func NewKafkaServer(
@@ -170,6 +172,16 @@ func getProto(messageType string, messageBytes []byte) (proto2.Message, error) {
 	return msg, err
 }
 
+// Assignment returns the partitions of the input topic currently assigned to the server's consumer.
+func (ks *SeldonKafkaServer) Assignment() ([]kafka.TopicPartition, error) {
+	ks.consumerMutex.RLock()
+	defer ks.consumerMutex.RUnlock()
+	if ks.Consumer == nil {
+		return nil, fmt.Errorf("kafka server is not consuming")
+	}
+	return ks.Consumer.Assignment()
+}
+
 func (ks *SeldonKafkaServer) Serve() error {
 	consumerConfig := util.GetKafkaConsumerConfig(ks.Broker, ks.AutoCommit, ks.getGroupName())
 	c, err := kafka.NewConsumer(consumerConfig)
@@ -177,7 +189,9 @@ func (ks *SeldonKafkaServer) Serve() error {
 		return err
 	}
 
+	ks.consumerMutex.Lock()
 	ks.Consumer = c
+	ks.consumerMutex.Unlock()
 	ks.Log.Info("Created", "consumer", c.String(), "consumer group", ks.getGroupName(), "topic", ks.TopicIn)
 
 	err = c.SubscribeTopics([]string{ks.TopicIn}, nil)
@@ -198,7 +212,7 @@ func (ks *SeldonKafkaServer) Serve() error {
 	//wait for graph to be ready
 	ready := false
 	for ready == false {
-		err := predictor.Ready(ks.Protocol, &ks.Predictor.Graph, ks.FullHealthCheck)
+		err := predictor.Ready(ks.Protocol, &predictor.ActivePredictor(ks.Predictor).Graph, ks.FullHealthCheck)
 		ready = err == nil
 		if !ready {
 			ks.Log.Info("Waiting for graph to be ready")
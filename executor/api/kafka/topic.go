@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"github.com/cloudevents/sdk-go/pkg/bindings/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
@@ -159,7 +160,7 @@ func (tp *KafkaRPC) start() {
 	}()
 }
 
-func (tp *KafkaRPC) call(msg []byte, puid string, method string) (payload.SeldonPayload, error) {
+func (tp *KafkaRPC) call(ctx context.Context, msg []byte, puid string, method string) (payload.SeldonPayload, error) {
 	//add to receivers
 	c := make(chan payload.SeldonPayload)
 	tp.Lock.Lock()
@@ -169,11 +170,11 @@ func (tp *KafkaRPC) call(msg []byte, puid string, method string) (payload.Seldon
 	err := tp.Producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &tp.TopicSend, Partition: kafka.PartitionAny},
 		Value:          msg,
-		Headers: []kafka.Header{
+		Headers: append([]kafka.Header{
 			{Key: payload.SeldonPUIDHeader, Value: []byte(puid)},
 			{Key: KeyTopicResponse, Value: []byte(tp.TopicReceive)},
 			{Key: KeyMethod, Value: []byte(method)},
-		}}, nil)
+		}, traceHeaders(ctx)...)}, nil)
 	if err != nil {
 		tp.Log.Error(err, "Failed to produce request", "topic", tp.TopicSend)
 		return nil, err
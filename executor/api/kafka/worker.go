@@ -4,10 +4,11 @@ import (
 	"context"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/predictor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -29,22 +30,38 @@ func (ks *SeldonKafkaServer) worker(jobChan <-chan *KafkaJob, cancelChan <-chan
 	}
 }
 
+// traceHeaders returns the Kafka headers carrying the trace context of ctx, if tracing is enabled.
+func traceHeaders(ctx context.Context) []kafka.Header {
+	headers := make([]kafka.Header, 0)
+	if !tracing.IsEnabled() {
+		return headers
+	}
+	carrier := tracing.MetadataCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for k, values := range carrier {
+		for _, v := range values {
+			headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+	}
+	return headers
+}
+
 func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 	ctx := context.Background()
 	// Add Seldon Puid to Context
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, job.headers[payload.SeldonPUIDHeader][0])
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		tracer := opentracing.GlobalTracer()
-		serverSpan := tracer.StartSpan("kafkaServer", ext.RPCServerOption(nil))
-		ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-		defer serverSpan.Finish()
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
+		ctx = otel.GetTextMapPropagator().Extract(ctx, tracing.MetadataCarrier(job.headers))
+		ctx, serverSpan = tracing.Tracer().Start(ctx, "kafkaServer", trace.WithSpanKind(trace.SpanKindConsumer))
+		defer serverSpan.End()
 	}
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, ks.Client, logf.Log.WithName("KafkaClient"), ks.ServerUrl, ks.Namespace, job.headers, "")
 
-	resPayload, err := seldonPredictorProcess.Predict(&ks.Predictor.Graph, job.reqPayload)
+	resPayload, err := seldonPredictorProcess.Predict(&predictor.ActivePredictor(ks.Predictor).Graph, job.reqPayload)
 	if err != nil {
 		ks.Log.Error(err, "Failed prediction")
 		return
@@ -55,7 +72,7 @@ func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 		return
 	}
 
-	kafkaHeaders := make([]kafka.Header, 0)
+	kafkaHeaders := traceHeaders(ctx)
 	// Could in the future add the proto message name. At present seems we need to know the class to cast to so would need to do
 	// an exhaustive check, e.g. check its a tensorflow_serving.predict_pb2.PredictResponse, etc
 	//if ks.Transport == api.TransportGrpc {
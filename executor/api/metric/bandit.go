@@ -0,0 +1,50 @@
+package metric
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BanditMetrics exposes the arm statistics of the built-in multi-armed bandit routers.
+type BanditMetrics struct {
+	ArmPulls   *prometheus.GaugeVec
+	ArmRewards *prometheus.GaugeVec
+	ArmValue   *prometheus.GaugeVec
+}
+
+func registerGaugeVec(opts prometheus.GaugeOpts, labelNames []string) *prometheus.GaugeVec {
+	gauge := prometheus.NewGaugeVec(opts, labelNames)
+	err := prometheus.Register(gauge)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			gauge = e.ExistingCollector.(*prometheus.GaugeVec)
+		}
+	}
+	return gauge
+}
+
+func NewBanditMetrics() *BanditMetrics {
+	labelNames := []string{ModelNameMetric, ArmMetric}
+	return &BanditMetrics{
+		ArmPulls: registerGaugeVec(prometheus.GaugeOpts{
+			Name: BanditArmPullsMetricName,
+			Help: "Number of requests routed to each arm of a bandit router",
+		}, labelNames),
+		ArmRewards: registerGaugeVec(prometheus.GaugeOpts{
+			Name: BanditArmRewardsMetricName,
+			Help: "Cumulative feedback reward received by each arm of a bandit router",
+		}, labelNames),
+		ArmValue: registerGaugeVec(prometheus.GaugeOpts{
+			Name: BanditArmValueMetricName,
+			Help: "Estimated reward of each arm of a bandit router",
+		}, labelNames),
+	}
+}
+
+func (m *BanditMetrics) SetArm(nodeName string, arm int, pulls float64, rewards float64, value float64) {
+	armLabel := strconv.Itoa(arm)
+	m.ArmPulls.WithLabelValues(nodeName, armLabel).Set(pulls)
+	m.ArmRewards.WithLabelValues(nodeName, armLabel).Set(rewards)
+	m.ArmValue.WithLabelValues(nodeName, armLabel).Set(value)
+}
@@ -0,0 +1,32 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheMetrics counts the lookups in the response cache of each graph node.
+type CacheMetrics struct {
+	Hits   *prometheus.CounterVec
+	Misses *prometheus.CounterVec
+}
+
+func NewCacheMetrics() *CacheMetrics {
+	return &CacheMetrics{
+		Hits: registerCounterVec(prometheus.CounterOpts{
+			Name: CacheHitsMetricName,
+			Help: "Number of requests answered from the executor response cache without calling the model",
+		}, []string{ModelNameMetric}),
+		Misses: registerCounterVec(prometheus.CounterOpts{
+			Name: CacheMissesMetricName,
+			Help: "Number of requests not found in the executor response cache",
+		}, []string{ModelNameMetric}),
+	}
+}
+
+func (m *CacheMetrics) Hit(nodeName string) {
+	m.Hits.WithLabelValues(nodeName).Inc()
+}
+
+func (m *CacheMetrics) Miss(nodeName string) {
+	m.Misses.WithLabelValues(nodeName).Inc()
+}
@@ -0,0 +1,49 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons a client call is rejected before reaching a model.
+const (
+	RejectedCircuitOpen = "circuit_open"
+	RejectedMaxInFlight = "max_in_flight"
+)
+
+// CircuitBreakerMetrics exposes the circuit breaker state and early rejections of each graph node.
+type CircuitBreakerMetrics struct {
+	State    *prometheus.GaugeVec
+	Rejected *prometheus.CounterVec
+}
+
+func registerCounterVec(opts prometheus.CounterOpts, labelNames []string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(opts, labelNames)
+	err := prometheus.Register(counter)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			counter = e.ExistingCollector.(*prometheus.CounterVec)
+		}
+	}
+	return counter
+}
+
+func NewCircuitBreakerMetrics() *CircuitBreakerMetrics {
+	return &CircuitBreakerMetrics{
+		State: registerGaugeVec(prometheus.GaugeOpts{
+			Name: ClientCircuitBreakerStateMetricName,
+			Help: "Circuit breaker state of client calls from executor: 0 closed, 1 open, 2 half-open",
+		}, []string{ModelNameMetric}),
+		Rejected: registerCounterVec(prometheus.CounterOpts{
+			Name: ClientRejectedRequestsMetricName,
+			Help: "Number of client calls from executor rejected by a circuit breaker or concurrency limit",
+		}, []string{ModelNameMetric, ReasonMetric}),
+	}
+}
+
+func (m *CircuitBreakerMetrics) SetState(nodeName string, state int) {
+	m.State.WithLabelValues(nodeName).Set(float64(state))
+}
+
+func (m *CircuitBreakerMetrics) Reject(nodeName string, reason string) {
+	m.Rejected.WithLabelValues(nodeName, reason).Inc()
+}
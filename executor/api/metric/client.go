@@ -97,7 +97,7 @@ func (m *ClientMetrics) UnaryClientInterceptor() func(ctx context.Context, metho
 		err := invoker(ctx, method, req, reply, cc, opts...)
 		st, _ := status.FromError(err)
 		elapsedTime := time.Since(startTime).Seconds()
-		m.ClientHandledHistogram.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], method, m.ModelName, m.ImageName, m.ImageVersion, "unary", st.Code().String()).Observe(elapsedTime)
+		observe(ctx, m.ClientHandledHistogram.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], method, m.ModelName, m.ImageName, m.ImageVersion, "unary", st.Code().String()), elapsedTime)
 		m.ClientHandledSummary.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], method, m.ModelName, m.ImageName, m.ImageVersion, "unary", st.Code().String()).Observe(elapsedTime)
 		return err
 	}
@@ -1,5 +1,9 @@
 package metric
 
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
 const (
 	CodeMetric             = "code"    // 2xx, 5xx etc
 	HTTPMethodMetric       = "method"  // Http Method (Post, Get etc)
@@ -10,17 +14,49 @@ const (
 	ModelNameMetric        = "model_name"
 	ModelImageMetric       = "model_image"
 	ModelVersionMetric     = "model_version"
+	ArmMetric              = "arm"
+	ReasonMetric           = "reason"
+	ResultMetric           = "result"
+	NodeMethodMetric       = "node_method"
+	RouteMetric            = "route"
+
+	ServerRequestsMetricName        = "seldon_api_executor_server_requests_seconds"
+	ServerTimeToFirstByteMetricName = "seldon_api_executor_server_time_to_first_byte_seconds"
+	ClientRequestsMetricName        = "seldon_api_executor_client_requests_seconds"
+	ServerInFlightMetricName        = "seldon_api_executor_server_in_flight_requests"
+
+	ClientCircuitBreakerStateMetricName = "seldon_api_executor_client_circuit_breaker_state"
+	ClientRejectedRequestsMetricName    = "seldon_api_executor_client_rejected_requests_total"
+	ClientHedgesMetricName              = "seldon_api_executor_client_hedges_total"
+	ClientHedgeWinsMetricName           = "seldon_api_executor_client_hedge_wins_total"
+
+	CacheHitsMetricName   = "seldon_api_executor_cache_hits_total"
+	CacheMissesMetricName = "seldon_api_executor_cache_misses_total"
+
+	BanditArmPullsMetricName   = "seldon_api_executor_bandit_arm_pulls"
+	BanditArmRewardsMetricName = "seldon_api_executor_bandit_arm_rewards"
+	BanditArmValueMetricName   = "seldon_api_executor_bandit_arm_value"
+
+	GraphReloadsMetricName = "seldon_api_executor_graph_reloads_total"
 
-	ServerRequestsMetricName = "seldon_api_executor_server_requests_seconds"
-	ClientRequestsMetricName = "seldon_api_executor_client_requests_seconds"
+	NodeRequestBytesMetricName  = "seldon_api_executor_node_request_bytes"
+	NodeResponseBytesMetricName = "seldon_api_executor_node_response_bytes"
+	NodeErrorsMetricName        = "seldon_api_executor_node_errors_total"
+	NodeInFlightMetricName      = "seldon_api_executor_node_in_flight_requests"
+	RouterRoutesMetricName      = "seldon_api_executor_router_routes_total"
 
-	PredictionHttpServiceName = "predictions"
-	StatusHttpServiceName     = "status"
-	MetadataHttpServiceName   = "metadata"
-	FeedbackHttpServiceName   = "feedback"
+	PredictionHttpServiceName      = "predictions"
+	StatusHttpServiceName          = "status"
+	MetadataHttpServiceName        = "metadata"
+	FeedbackHttpServiceName        = "feedback"
+	StatisticsHttpServiceName      = "statistics"
+	AsyncPredictionHttpServiceName = "async-predictions"
+	AsyncResultHttpServiceName     = "async-results"
 )
 
 var (
 	DefBuckets    = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
 	DefObjectives = map[float64]float64{0.5: 0.05, 0.75: 0.025, 0.9: 0.01, 0.98: 0.002, 0.99: 0.001, 1.0: 0}
+	// Payload sizes in bytes from 64B to 16MB
+	PayloadBuckets = prometheus.ExponentialBuckets(64, 4, 10)
 )
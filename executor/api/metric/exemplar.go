@@ -0,0 +1,46 @@
+package metric
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIdExemplarLabel is the exemplar label holding the id of the trace an observation was made in.
+const TraceIdExemplarLabel = "trace_id"
+
+var exemplarsEnabled = false
+
+// SetExemplarsEnabled sets whether histogram observations made within a sampled trace carry its trace id as an
+// exemplar. Exemplars are only exposed in the OpenMetrics format.
+func SetExemplarsEnabled(enabled bool) {
+	exemplarsEnabled = enabled
+}
+
+func ExemplarsEnabled() bool {
+	return exemplarsEnabled
+}
+
+// ExemplarFromContext returns the exemplar labels for an observation made within the span of the context, or nil if
+// exemplars are disabled or the span is not sampled.
+func ExemplarFromContext(ctx context.Context) prometheus.Labels {
+	if !exemplarsEnabled {
+		return nil
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{TraceIdExemplarLabel: sc.TraceID().String()}
+}
+
+func observe(ctx context.Context, obs prometheus.Observer, value float64) {
+	if labels := ExemplarFromContext(ctx); labels != nil {
+		if eo, ok := obs.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(value, labels)
+			return
+		}
+	}
+	obs.Observe(value)
+}
@@ -0,0 +1,32 @@
+package metric
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExemplarFromContext(t *testing.T) {
+	g := NewGomegaWithT(t)
+	traceId, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanId, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	notSampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+	}))
+
+	g.Expect(ExemplarFromContext(sampled)).To(BeNil())
+
+	SetExemplarsEnabled(true)
+	defer SetExemplarsEnabled(false)
+	g.Expect(ExemplarFromContext(sampled)).To(HaveKeyWithValue(TraceIdExemplarLabel, "0af7651916cd43dd8448eb211c80319c"))
+	g.Expect(ExemplarFromContext(notSampled)).To(BeNil())
+	g.Expect(ExemplarFromContext(context.Background())).To(BeNil())
+}
@@ -0,0 +1,32 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HedgingMetrics counts the duplicate calls sent to slow graph nodes and how often one of them replied first.
+type HedgingMetrics struct {
+	Hedges *prometheus.CounterVec
+	Wins   *prometheus.CounterVec
+}
+
+func NewHedgingMetrics() *HedgingMetrics {
+	return &HedgingMetrics{
+		Hedges: registerCounterVec(prometheus.CounterOpts{
+			Name: ClientHedgesMetricName,
+			Help: "Number of duplicate client calls sent by executor to slow models",
+		}, []string{ModelNameMetric}),
+		Wins: registerCounterVec(prometheus.CounterOpts{
+			Name: ClientHedgeWinsMetricName,
+			Help: "Number of duplicate client calls from executor that replied before the original call",
+		}, []string{ModelNameMetric}),
+	}
+}
+
+func (m *HedgingMetrics) Hedge(nodeName string) {
+	m.Hedges.WithLabelValues(nodeName).Inc()
+}
+
+func (m *HedgingMetrics) Win(nodeName string) {
+	m.Wins.WithLabelValues(nodeName).Inc()
+}
@@ -0,0 +1,88 @@
+package metric
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// NodeMetrics exposes the payload sizes, errors and in-flight calls of each graph node and the routes chosen by
+// routers. The metrics are labelled with the deployment and predictor like the server and client metrics.
+type NodeMetrics struct {
+	RequestBytes  prometheus.ObserverVec
+	ResponseBytes prometheus.ObserverVec
+	Errors        *prometheus.CounterVec
+	InFlight      *prometheus.GaugeVec
+	Routes        *prometheus.CounterVec
+}
+
+func registerHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(opts, labelNames)
+	err := prometheus.Register(histogram)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			histogram = e.ExistingCollector.(*prometheus.HistogramVec)
+		}
+	}
+	return histogram
+}
+
+func NewNodeMetrics(spec *v1.PredictorSpec, deploymentName string) *NodeMetrics {
+	deploymentLabels := []string{DeploymentNameMetric, PredictorNameMetric, PredictorVersionMetric}
+	labels := prometheus.Labels{
+		DeploymentNameMetric:   deploymentName,
+		PredictorNameMetric:    spec.Name,
+		PredictorVersionMetric: spec.Annotations["version"],
+	}
+	labelNames := append(deploymentLabels, ModelNameMetric, NodeMethodMetric)
+	return &NodeMetrics{
+		RequestBytes: registerHistogramVec(prometheus.HistogramOpts{
+			Name:    NodeRequestBytesMetricName,
+			Help:    "A histogram of the payload sizes in bytes sent to each graph node by executor",
+			Buckets: PayloadBuckets,
+		}, labelNames).MustCurryWith(labels),
+		ResponseBytes: registerHistogramVec(prometheus.HistogramOpts{
+			Name:    NodeResponseBytesMetricName,
+			Help:    "A histogram of the payload sizes in bytes returned by each graph node to executor",
+			Buckets: PayloadBuckets,
+		}, labelNames).MustCurryWith(labels),
+		Errors: registerCounterVec(prometheus.CounterOpts{
+			Name: NodeErrorsMetricName,
+			Help: "Number of failed calls from executor to each graph node by status code",
+		}, append(deploymentLabels, ModelNameMetric, NodeMethodMetric, CodeMetric)).MustCurryWith(labels),
+		InFlight: registerGaugeVec(prometheus.GaugeOpts{
+			Name: NodeInFlightMetricName,
+			Help: "Number of client calls from executor to each graph node in flight",
+		}, append(deploymentLabels, ModelNameMetric)).MustCurryWith(labels),
+		Routes: registerCounterVec(prometheus.CounterOpts{
+			Name: RouterRoutesMetricName,
+			Help: "Number of requests sent by each router to each of its children: -1 all children, -2 none",
+		}, append(deploymentLabels, ModelNameMetric, RouteMetric)).MustCurryWith(labels),
+	}
+}
+
+func (m *NodeMetrics) ObserveRequest(ctx context.Context, nodeName string, method string, bytes int) {
+	observe(ctx, m.RequestBytes.WithLabelValues(nodeName, method), float64(bytes))
+}
+
+func (m *NodeMetrics) ObserveResponse(ctx context.Context, nodeName string, method string, bytes int) {
+	observe(ctx, m.ResponseBytes.WithLabelValues(nodeName, method), float64(bytes))
+}
+
+func (m *NodeMetrics) Error(nodeName string, method string, code string) {
+	m.Errors.WithLabelValues(nodeName, method, code).Inc()
+}
+
+func (m *NodeMetrics) Started(nodeName string) {
+	m.InFlight.WithLabelValues(nodeName).Inc()
+}
+
+func (m *NodeMetrics) Finished(nodeName string) {
+	m.InFlight.WithLabelValues(nodeName).Dec()
+}
+
+func (m *NodeMetrics) Route(nodeName string, route int) {
+	m.Routes.WithLabelValues(nodeName, strconv.Itoa(route)).Inc()
+}
@@ -0,0 +1,33 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Results of a graph reload.
+const (
+	ReloadAppliedResult  = "applied"
+	ReloadRejectedResult = "rejected"
+)
+
+// ReloadMetrics counts the attempts to reload the predictor graph from a watched file and whether each one was applied.
+type ReloadMetrics struct {
+	Reloads *prometheus.CounterVec
+}
+
+func NewReloadMetrics() *ReloadMetrics {
+	return &ReloadMetrics{
+		Reloads: registerCounterVec(prometheus.CounterOpts{
+			Name: GraphReloadsMetricName,
+			Help: "Number of times executor reloaded its graph, by whether the new graph was applied or rejected",
+		}, []string{PredictorNameMetric, ResultMetric}),
+	}
+}
+
+func (m *ReloadMetrics) Applied(predictorName string) {
+	m.Reloads.WithLabelValues(predictorName, ReloadAppliedResult).Inc()
+}
+
+func (m *ReloadMetrics) Rejected(predictorName string) {
+	m.Reloads.WithLabelValues(predictorName, ReloadRejectedResult).Inc()
+}
@@ -16,8 +16,12 @@ var RecreateServerSummary = false
 type ServerMetrics struct {
 	ServerHandledHistogram *prometheus.HistogramVec
 	ServerHandledSummary   *prometheus.SummaryVec
-	Predictor              *v1.PredictorSpec
-	DeploymentName         string
+	// Time until the first chunk of a streamed response is written.
+	ServerTimeToFirstByteHistogram *prometheus.HistogramVec
+	// Requests being served.
+	ServerInFlight *prometheus.GaugeVec
+	Predictor      *v1.PredictorSpec
+	DeploymentName string
 }
 
 func NewServerMetrics(spec *v1.PredictorSpec, deploymentName string) *ServerMetrics {
@@ -63,22 +67,47 @@ func NewServerMetrics(spec *v1.PredictorSpec, deploymentName string) *ServerMetr
 		}
 	}
 
+	ttfb := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    ServerTimeToFirstByteMetricName,
+			Help:    "A histogram of the time to first byte of streamed responses from executor server",
+			Buckets: DefBuckets,
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, PredictorVersionMetric, ServiceMetric},
+	)
+	err = prometheus.Register(ttfb)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			ttfb = e.ExistingCollector.(*prometheus.HistogramVec)
+		}
+	}
+
+	inFlight := registerGaugeVec(prometheus.GaugeOpts{
+		Name: ServerInFlightMetricName,
+		Help: "Number of requests to executor server in flight",
+	}, []string{DeploymentNameMetric, PredictorNameMetric, PredictorVersionMetric, ServiceMetric})
+
 	return &ServerMetrics{
-		ServerHandledHistogram: histogram,
-		ServerHandledSummary:   summary,
-		Predictor:              spec,
-		DeploymentName:         deploymentName,
+		ServerHandledHistogram:         histogram,
+		ServerHandledSummary:           summary,
+		ServerTimeToFirstByteHistogram: ttfb,
+		ServerInFlight:                 inFlight,
+		Predictor:                      spec,
+		DeploymentName:                 deploymentName,
 	}
 }
 
 // UnaryServerInterceptor is a gRPC server-side interceptor that provides Prometheus monitoring for Unary RPCs.
 func (m *ServerMetrics) UnaryServerInterceptor() func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		inFlight := m.ServerInFlight.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], info.FullMethod)
+		inFlight.Inc()
+		defer inFlight.Dec()
 		startTime := time.Now()
 		resp, err := handler(ctx, req)
 		st, _ := status.FromError(err)
 		elapsedTime := time.Since(startTime).Seconds()
-		m.ServerHandledHistogram.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], info.FullMethod, "unary", st.Code().String()).Observe(elapsedTime)
+		observe(ctx, m.ServerHandledHistogram.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], info.FullMethod, "unary", st.Code().String()), elapsedTime)
 		m.ServerHandledSummary.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], info.FullMethod, "unary", st.Code().String()).Observe(elapsedTime)
 		return resp, err
 	}
@@ -7,6 +7,8 @@ import (
 const (
 	SeldonPUIDHeader        = "Seldon-Puid"
 	SeldonSkipLoggingHeader = "Seldon-Skip-Logging"
+	SeldonCacheBypassHeader = "Seldon-Cache-Bypass"
+	SeldonTraceHeader       = "Seldon-Trace"
 )
 
 type MetaData struct {
@@ -0,0 +1,130 @@
+package payload
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+const (
+	TEXT_EVENT_STREAM = "text/event-stream"
+
+	streamChunkSize = 32 * 1024
+)
+
+// StreamPayload is a response whose body is read from a model as it is produced. The REST server writes the body to
+// the caller chunk by chunk with Stream. Anything else, such as the next node in the graph, reads the whole body with
+// GetPayload or GetBytes so the payload can be used like a BytesPayload.
+type StreamPayload struct {
+	ContentType     string
+	ContentEncoding string
+
+	mu   sync.Mutex
+	body io.ReadCloser
+	// The body read so far.
+	buf        bytes.Buffer
+	done       bool
+	err        error
+	onComplete []func(data []byte)
+}
+
+func NewStreamPayload(body io.ReadCloser, contentType string, contentEncoding string) *StreamPayload {
+	return &StreamPayload{ContentType: contentType, ContentEncoding: contentEncoding, body: body}
+}
+
+func (s *StreamPayload) GetPayload() interface{} {
+	data, _ := s.GetBytes()
+	return data
+}
+
+func (s *StreamPayload) GetContentType() string {
+	return s.ContentType
+}
+
+func (s *StreamPayload) GetContentEncoding() string {
+	return s.ContentEncoding
+}
+
+// GetBytes reads the rest of the body and returns the whole of it.
+func (s *StreamPayload) GetBytes() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.done {
+		s.read()
+	}
+	return s.buf.Bytes(), s.err
+}
+
+// OnComplete registers fn to be called with the whole body once it has been read to the end. It is called straight
+// away if it already has been.
+func (s *StreamPayload) OnComplete(fn func(data []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		if s.err == nil {
+			fn(s.buf.Bytes())
+		}
+		return
+	}
+	s.onComplete = append(s.onComplete, fn)
+}
+
+// Stream writes the body to w as it is read, calling flush after each chunk. The body is closed when it has been read
+// to the end or writing fails.
+func (s *StreamPayload) Stream(w io.Writer, flush func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Write out anything already read
+	written := 0
+	for {
+		if pending := s.buf.Bytes()[written:]; len(pending) > 0 {
+			n, err := w.Write(pending)
+			written += n
+			if err != nil {
+				s.close(err)
+				return err
+			}
+			flush()
+		}
+		if s.done {
+			return s.err
+		}
+		s.read()
+	}
+}
+
+// Close stops reading the body, interrupting a Stream waiting for the next chunk. Completion callbacks are not called
+// for a body that was not read to the end.
+func (s *StreamPayload) Close() error {
+	// The body is closed before taking the lock, which is held while waiting for a chunk
+	err := s.body.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.close(io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// read reads the next chunk of the body. It must be called with the lock held.
+func (s *StreamPayload) read() {
+	chunk := make([]byte, streamChunkSize)
+	n, err := s.body.Read(chunk)
+	s.buf.Write(chunk[:n])
+	if err == io.EOF {
+		onComplete := s.onComplete
+		s.close(nil)
+		for _, fn := range onComplete {
+			fn(s.buf.Bytes())
+		}
+	} else if err != nil {
+		s.close(err)
+	}
+}
+
+func (s *StreamPayload) close(err error) {
+	s.done = true
+	s.err = err
+	s.onComplete = nil
+	s.body.Close()
+}
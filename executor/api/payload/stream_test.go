@@ -0,0 +1,54 @@
+package payload
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestStreamPayload(t *testing.T) {
+	sp := NewStreamPayload(ioutil.NopCloser(strings.NewReader("data: a\n\ndata: b\n\n")), TEXT_EVENT_STREAM, "")
+	var completed []byte
+	sp.OnComplete(func(data []byte) {
+		completed = data
+	})
+
+	var out bytes.Buffer
+	flushes := 0
+	err := sp.Stream(&out, func() { flushes++ })
+	assert.NilError(t, err)
+	assert.Equal(t, "data: a\n\ndata: b\n\n", out.String())
+	assert.Assert(t, flushes > 0)
+	assert.Equal(t, "data: a\n\ndata: b\n\n", string(completed))
+
+	// The body read by Stream is still available to later readers
+	data, err := sp.GetBytes()
+	assert.NilError(t, err)
+	assert.Equal(t, "data: a\n\ndata: b\n\n", string(data))
+}
+
+func TestStreamPayloadGetBytes(t *testing.T) {
+	sp := NewStreamPayload(ioutil.NopCloser(strings.NewReader("data: a\n\n")), TEXT_EVENT_STREAM, "")
+	assert.Equal(t, "data: a\n\n", string(sp.GetPayload().([]byte)))
+
+	var completed []byte
+	sp.OnComplete(func(data []byte) {
+		completed = data
+	})
+	assert.Equal(t, "data: a\n\n", string(completed))
+}
+
+func TestStreamPayloadClose(t *testing.T) {
+	sp := NewStreamPayload(ioutil.NopCloser(strings.NewReader("data: a\n\n")), TEXT_EVENT_STREAM, "")
+	called := false
+	sp.OnComplete(func(data []byte) {
+		called = true
+	})
+	assert.NilError(t, sp.Close())
+	_, err := sp.GetBytes()
+	assert.Assert(t, err != nil)
+	assert.Assert(t, !called)
+}
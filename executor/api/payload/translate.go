@@ -0,0 +1,521 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/golang/protobuf/jsonpb"
+	protoV1 "github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+)
+
+const (
+	applicationJson = "application/json"
+
+	// Names given to V2 tensors translated from Seldon messages with no single feature name.
+	DefaultV2InputName  = "input-0"
+	DefaultV2OutputName = "output-0"
+
+	v2Bool   = "BOOL"
+	v2Bytes  = "BYTES"
+	v2Fp64   = "FP64"
+	v2Fp32   = "FP32"
+	v2Int8   = "INT8"
+	v2Int16  = "INT16"
+	v2Int32  = "INT32"
+	v2Int64  = "INT64"
+	v2Uint8  = "UINT8"
+	v2Uint16 = "UINT16"
+	v2Uint32 = "UINT32"
+	v2Uint64 = "UINT64"
+)
+
+// tensor is a named n-dimensional array in row-major order, the form a payload takes while it is translated between
+// protocols. Values are float64, bool or string.
+type tensor struct {
+	name     string
+	datatype string
+	shape    []int64
+	values   []interface{}
+}
+
+// v2Tensor and v2Message are the JSON form of V2 protocol requests and responses.
+type v2Tensor struct {
+	Name       string                 `json:"name"`
+	Shape      []int64                `json:"shape"`
+	Datatype   string                 `json:"datatype"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Data       interface{}            `json:"data"`
+}
+
+type v2Message struct {
+	ModelName    string                 `json:"model_name,omitempty"`
+	ModelVersion string                 `json:"model_version,omitempty"`
+	Id           string                 `json:"id,omitempty"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Inputs       []v2Tensor             `json:"inputs,omitempty"`
+	Outputs      []v2Tensor             `json:"outputs,omitempty"`
+}
+
+// TranslateRequest converts a request from one protocol to another. Only translation between the Seldon and V2
+// protocols is supported. Protobuf payloads are translated to protobuf and JSON payloads to JSON.
+func TranslateRequest(msg SeldonPayload, from string, to string) (SeldonPayload, error) {
+	return translate(msg, from, to, false)
+}
+
+// TranslateResponse converts a response from one protocol to another in the same way as TranslateRequest.
+func TranslateResponse(msg SeldonPayload, from string, to string) (SeldonPayload, error) {
+	return translate(msg, from, to, true)
+}
+
+func isV2(protocol string) bool {
+	return protocol == api.ProtocolV2 || protocol == api.ProtocolKFServing
+}
+
+func translate(msg SeldonPayload, from string, to string, response bool) (SeldonPayload, error) {
+	if from == to || (isV2(from) && isV2(to)) {
+		return msg, nil
+	}
+	_, isProto := msg.GetPayload().(protoV1.Message)
+	switch {
+	case from == api.ProtocolSeldon && isV2(to):
+		var sm *proto.SeldonMessage
+		if isProto {
+			var ok bool
+			if sm, ok = msg.GetPayload().(*proto.SeldonMessage); !ok {
+				return nil, fmt.Errorf("can not translate %T from the seldon protocol", msg.GetPayload())
+			}
+		} else {
+			data, err := DecompressSeldonPayload(msg)
+			if err != nil {
+				return nil, err
+			}
+			sm = &proto.SeldonMessage{}
+			if err := jsonpb.Unmarshal(bytes.NewReader(data), sm); err != nil {
+				return nil, fmt.Errorf("can not translate invalid seldon message: %w", err)
+			}
+		}
+		defaultName := DefaultV2InputName
+		if response {
+			defaultName = DefaultV2OutputName
+		}
+		tensors, err := seldonToTensors(sm, defaultName)
+		if err != nil {
+			return nil, err
+		}
+		return tensorsToV2(tensors, sm.GetMeta().GetPuid(), response, isProto)
+	case isV2(from) && to == api.ProtocolSeldon:
+		tensors, id, err := v2ToTensors(msg, response, isProto)
+		if err != nil {
+			return nil, err
+		}
+		sm, err := tensorsToSeldon(tensors)
+		if err != nil {
+			return nil, err
+		}
+		if id != "" {
+			sm.Meta = &proto.Meta{Puid: id}
+		}
+		if isProto {
+			return &ProtoPayload{Msg: sm}, nil
+		}
+		ma := jsonpb.Marshaler{}
+		data, err := ma.MarshalToString(sm)
+		if err != nil {
+			return nil, err
+		}
+		return &BytesPayload{Msg: []byte(data), ContentType: applicationJson}, nil
+	}
+	return nil, fmt.Errorf("can not translate payloads from the %s protocol to the %s protocol", from, to)
+}
+
+func seldonToTensors(sm *proto.SeldonMessage, defaultName string) ([]*tensor, error) {
+	switch {
+	case sm.GetData() != nil:
+		data := sm.GetData()
+		t := &tensor{name: defaultName}
+		if len(data.Names) == 1 {
+			t.name = data.Names[0]
+		}
+		if st := data.GetTensor(); st != nil {
+			t.datatype = v2Fp64
+			for _, d := range st.Shape {
+				t.shape = append(t.shape, int64(d))
+			}
+			for _, v := range st.Values {
+				t.values = append(t.values, v)
+			}
+		} else if ndarray := data.GetNdarray(); ndarray != nil {
+			if err := t.fromListValue(ndarray, 0); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("can not translate seldon tftensor data to the V2 protocol")
+		}
+		return []*tensor{t}, nil
+	case sm.GetStrData() != "":
+		return []*tensor{{name: defaultName, datatype: v2Bytes, shape: []int64{1}, values: []interface{}{sm.GetStrData()}}}, nil
+	case sm.GetBinData() != nil:
+		return []*tensor{{name: defaultName, datatype: v2Bytes, shape: []int64{1}, values: []interface{}{string(sm.GetBinData())}}}, nil
+	}
+	return nil, fmt.Errorf("can only translate seldon messages with data, strData or binData to the V2 protocol")
+}
+
+// fromListValue flattens an ndarray, which must be rectangular and hold values of one kind, into the tensor.
+func (t *tensor) fromListValue(list *structpb.ListValue, depth int) error {
+	if depth == len(t.shape) {
+		t.shape = append(t.shape, int64(len(list.Values)))
+	} else if t.shape[depth] != int64(len(list.Values)) {
+		return fmt.Errorf("can not translate ragged ndarray to the V2 protocol")
+	}
+	sawList, sawValue := false, false
+	for _, v := range list.Values {
+		var datatype string
+		switch kind := v.Kind.(type) {
+		case *structpb.Value_ListValue:
+			sawList = true
+			if sawValue {
+				return fmt.Errorf("can not translate ragged ndarray to the V2 protocol")
+			}
+			if err := t.fromListValue(kind.ListValue, depth+1); err != nil {
+				return err
+			}
+			continue
+		case *structpb.Value_NumberValue:
+			datatype = v2Fp64
+			t.values = append(t.values, kind.NumberValue)
+		case *structpb.Value_BoolValue:
+			datatype = v2Bool
+			t.values = append(t.values, kind.BoolValue)
+		case *structpb.Value_StringValue:
+			datatype = v2Bytes
+			t.values = append(t.values, kind.StringValue)
+		default:
+			return fmt.Errorf("can not translate ndarray value %v to the V2 protocol", v)
+		}
+		sawValue = true
+		if sawList || depth != len(t.shape)-1 {
+			return fmt.Errorf("can not translate ragged ndarray to the V2 protocol")
+		}
+		if t.datatype != "" && t.datatype != datatype {
+			return fmt.Errorf("can not translate ndarray with mixed types to the V2 protocol")
+		}
+		t.datatype = datatype
+	}
+	if t.datatype == "" && depth == 0 {
+		t.datatype = v2Fp64
+	}
+	return nil
+}
+
+func tensorsToV2(tensors []*tensor, id string, response bool, isProto bool) (SeldonPayload, error) {
+	if !isProto {
+		msg := v2Message{Id: id}
+		for _, t := range tensors {
+			v2t := v2Tensor{Name: t.name, Shape: t.shape, Datatype: t.datatype, Data: t.values}
+			if t.values == nil {
+				v2t.Data = []interface{}{}
+			}
+			if response {
+				msg.Outputs = append(msg.Outputs, v2t)
+			} else {
+				msg.Inputs = append(msg.Inputs, v2t)
+			}
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		return &BytesPayload{Msg: data, ContentType: applicationJson}, nil
+	}
+
+	var contents []*inference.InferTensorContents
+	for _, t := range tensors {
+		c, err := t.toContents()
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, c)
+	}
+	if response {
+		res := &inference.ModelInferResponse{Id: id}
+		for i, t := range tensors {
+			res.Outputs = append(res.Outputs, &inference.ModelInferResponse_InferOutputTensor{Name: t.name, Datatype: t.datatype, Shape: t.shape, Contents: contents[i]})
+		}
+		return &ProtoPayload{Msg: res}, nil
+	}
+	req := &inference.ModelInferRequest{Id: id}
+	for i, t := range tensors {
+		req.Inputs = append(req.Inputs, &inference.ModelInferRequest_InferInputTensor{Name: t.name, Datatype: t.datatype, Shape: t.shape, Contents: contents[i]})
+	}
+	return &ProtoPayload{Msg: req}, nil
+}
+
+func (t *tensor) toContents() (*inference.InferTensorContents, error) {
+	c := &inference.InferTensorContents{}
+	for _, v := range t.values {
+		switch t.datatype {
+		case v2Bool:
+			c.BoolContents = append(c.BoolContents, v.(bool))
+		case v2Fp64:
+			c.Fp64Contents = append(c.Fp64Contents, v.(float64))
+		case v2Fp32:
+			c.Fp32Contents = append(c.Fp32Contents, float32(v.(float64)))
+		case v2Int8, v2Int16, v2Int32:
+			c.IntContents = append(c.IntContents, int32(v.(float64)))
+		case v2Int64:
+			c.Int64Contents = append(c.Int64Contents, int64(v.(float64)))
+		case v2Uint8, v2Uint16, v2Uint32:
+			c.UintContents = append(c.UintContents, uint32(v.(float64)))
+		case v2Uint64:
+			c.Uint64Contents = append(c.Uint64Contents, uint64(v.(float64)))
+		default:
+			return nil, fmt.Errorf("can not translate %s tensor %s to the V2 gRPC protocol", t.datatype, t.name)
+		}
+	}
+	return c, nil
+}
+
+func v2ToTensors(msg SeldonPayload, response bool, isProto bool) ([]*tensor, string, error) {
+	if isProto {
+		switch v := msg.GetPayload().(type) {
+		case *inference.ModelInferRequest:
+			tensors, err := v2ProtoToTensors(len(v.Inputs), func(i int) (string, string, []int64, *inference.InferTensorContents) {
+				return v.Inputs[i].Name, v.Inputs[i].Datatype, v.Inputs[i].Shape, v.Inputs[i].Contents
+			}, v.RawInputContents)
+			return tensors, v.Id, err
+		case *inference.ModelInferResponse:
+			tensors, err := v2ProtoToTensors(len(v.Outputs), func(i int) (string, string, []int64, *inference.InferTensorContents) {
+				return v.Outputs[i].Name, v.Outputs[i].Datatype, v.Outputs[i].Shape, v.Outputs[i].Contents
+			}, v.RawOutputContents)
+			return tensors, v.Id, err
+		}
+		return nil, "", fmt.Errorf("can not translate %T from the V2 protocol", msg.GetPayload())
+	}
+
+	data, err := DecompressSeldonPayload(msg)
+	if err != nil {
+		return nil, "", err
+	}
+	var v2msg v2Message
+	if err := json.Unmarshal(data, &v2msg); err != nil {
+		return nil, "", fmt.Errorf("can not translate invalid V2 message: %w", err)
+	}
+	v2tensors := v2msg.Inputs
+	if response {
+		v2tensors = v2msg.Outputs
+	}
+	var tensors []*tensor
+	for _, v2t := range v2tensors {
+		t := &tensor{name: v2t.Name, datatype: v2t.Datatype, shape: v2t.Shape}
+		if err := t.appendJsonData(v2t.Data); err != nil {
+			return nil, "", err
+		}
+		tensors = append(tensors, t)
+	}
+	return tensors, v2msg.Id, nil
+}
+
+// appendJsonData flattens V2 JSON tensor data, which may be nested or flat.
+func (t *tensor) appendJsonData(data interface{}) error {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := t.appendJsonData(item); err != nil {
+				return err
+			}
+		}
+	case float64, bool, string:
+		t.values = append(t.values, v)
+	default:
+		return fmt.Errorf("can not translate V2 tensor %s with data %v", t.name, data)
+	}
+	return nil
+}
+
+func v2ProtoToTensors(n int, get func(i int) (string, string, []int64, *inference.InferTensorContents), raw [][]byte) ([]*tensor, error) {
+	var tensors []*tensor
+	for i := 0; i < n; i++ {
+		name, datatype, shape, contents := get(i)
+		t := &tensor{name: name, datatype: datatype, shape: shape}
+		var err error
+		if len(raw) > i {
+			err = t.fromRaw(raw[i])
+		} else {
+			t.fromContents(contents)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tensors = append(tensors, t)
+	}
+	return tensors, nil
+}
+
+func (t *tensor) fromContents(c *inference.InferTensorContents) {
+	for _, v := range c.GetBoolContents() {
+		t.values = append(t.values, v)
+	}
+	for _, v := range c.GetIntContents() {
+		t.values = append(t.values, float64(v))
+	}
+	for _, v := range c.GetInt64Contents() {
+		t.values = append(t.values, float64(v))
+	}
+	for _, v := range c.GetUintContents() {
+		t.values = append(t.values, float64(v))
+	}
+	for _, v := range c.GetUint64Contents() {
+		t.values = append(t.values, float64(v))
+	}
+	for _, v := range c.GetFp32Contents() {
+		t.values = append(t.values, float64(v))
+	}
+	for _, v := range c.GetFp64Contents() {
+		t.values = append(t.values, v)
+	}
+}
+
+// fromRaw decodes raw tensor contents, which hold little-endian values or, for BYTES, values each preceded by
+// their 4 byte length.
+func (t *tensor) fromRaw(raw []byte) error {
+	if t.datatype == v2Bytes {
+		for len(raw) >= 4 {
+			n := int(binary.LittleEndian.Uint32(raw))
+			if len(raw) < 4+n {
+				break
+			}
+			t.values = append(t.values, string(raw[4:4+n]))
+			raw = raw[4+n:]
+		}
+		if len(raw) != 0 {
+			return fmt.Errorf("invalid raw contents for BYTES tensor %s", t.name)
+		}
+		return nil
+	}
+	sizes := map[string]int{v2Bool: 1, v2Int8: 1, v2Uint8: 1, v2Int16: 2, v2Uint16: 2, v2Int32: 4, v2Uint32: 4,
+		v2Fp32: 4, v2Int64: 8, v2Uint64: 8, v2Fp64: 8}
+	size, ok := sizes[t.datatype]
+	if !ok || len(raw)%size != 0 {
+		return fmt.Errorf("can not translate raw contents of %s tensor %s", t.datatype, t.name)
+	}
+	for i := 0; i < len(raw); i += size {
+		b := raw[i : i+size]
+		var v interface{}
+		switch t.datatype {
+		case v2Bool:
+			v = b[0] != 0
+		case v2Int8:
+			v = float64(int8(b[0]))
+		case v2Uint8:
+			v = float64(b[0])
+		case v2Int16:
+			v = float64(int16(binary.LittleEndian.Uint16(b)))
+		case v2Uint16:
+			v = float64(binary.LittleEndian.Uint16(b))
+		case v2Int32:
+			v = float64(int32(binary.LittleEndian.Uint32(b)))
+		case v2Uint32:
+			v = float64(binary.LittleEndian.Uint32(b))
+		case v2Fp32:
+			v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case v2Int64:
+			v = float64(int64(binary.LittleEndian.Uint64(b)))
+		case v2Uint64:
+			v = float64(binary.LittleEndian.Uint64(b))
+		case v2Fp64:
+			v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		t.values = append(t.values, v)
+	}
+	return nil
+}
+
+// tensorsToSeldon returns a Seldon message with an ndarray named after the tensor, or, for several tensors of one
+// column each, an ndarray with a column and name for each tensor.
+func tensorsToSeldon(tensors []*tensor) (*proto.SeldonMessage, error) {
+	if len(tensors) == 0 {
+		return nil, fmt.Errorf("can not translate V2 message with no tensors to the seldon protocol")
+	}
+	for _, t := range tensors {
+		if int64(len(t.values)) != product(t.shape) {
+			return nil, fmt.Errorf("V2 tensor %s has shape %v but %d values", t.name, t.shape, len(t.values))
+		}
+	}
+
+	var names []string
+	var ndarray *structpb.ListValue
+	if len(tensors) == 1 {
+		names = []string{tensors[0].name}
+		ndarray = toListValue(tensors[0].values, tensors[0].shape)
+	} else {
+		rows := tensors[0].shape
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("can not translate V2 message with several scalar tensors to the seldon protocol")
+		}
+		ndarray = &structpb.ListValue{}
+		for r := 0; r < int(rows[0]); r++ {
+			ndarray.Values = append(ndarray.Values, &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: &structpb.ListValue{}}})
+		}
+		for _, t := range tensors {
+			if len(t.shape) == 0 || t.shape[0] != rows[0] || product(t.shape[1:]) != 1 {
+				return nil, fmt.Errorf("can only translate V2 messages with several tensors to the seldon protocol if each tensor is a single column of the same length")
+			}
+			names = append(names, t.name)
+			for r, v := range t.values {
+				row := ndarray.Values[r].GetListValue()
+				row.Values = append(row.Values, toValue(v))
+			}
+		}
+	}
+	return &proto.SeldonMessage{
+		Meta: &proto.Meta{},
+		DataOneof: &proto.SeldonMessage_Data{
+			Data: &proto.DefaultData{
+				Names:     names,
+				DataOneof: &proto.DefaultData_Ndarray{Ndarray: ndarray},
+			},
+		},
+	}, nil
+}
+
+func toValue(v interface{}) *structpb.Value {
+	switch v := v.(type) {
+	case bool:
+		return &structpb.Value{Kind: &structpb.Value_BoolValue{BoolValue: v}}
+	case string:
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}}
+	default:
+		return &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: v.(float64)}}
+	}
+}
+
+// toListValue nests the flattened values of a tensor to match its shape.
+func toListValue(values []interface{}, shape []int64) *structpb.ListValue {
+	list := &structpb.ListValue{}
+	if len(shape) <= 1 {
+		for _, v := range values {
+			list.Values = append(list.Values, toValue(v))
+		}
+		return list
+	}
+	size := int(product(shape[1:]))
+	for i := 0; i < int(shape[0]); i++ {
+		list.Values = append(list.Values, &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: toListValue(values[i*size:(i+1)*size], shape[1:])}})
+	}
+	return list
+}
+
+func product(shape []int64) int64 {
+	n := int64(1)
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package payload
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	seldon "github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+)
+
+func TestTranslateSeldonJsonToV2(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &BytesPayload{Msg: []byte(`{"meta":{"puid":"abc"},"data":{"ndarray":[[1,2],[3,4]]}}`), ContentType: applicationJson}
+	res, err := TranslateRequest(msg, api.ProtocolSeldon, api.ProtocolV2)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"id":"abc","inputs":[{"name":"input-0","shape":[2,2],"datatype":"FP64","data":[1,2,3,4]}]}`))
+
+	msg = &BytesPayload{Msg: []byte(`{"data":{"names":["text"],"ndarray":["a","b"]}}`), ContentType: applicationJson}
+	res, err = TranslateRequest(msg, api.ProtocolSeldon, api.ProtocolV2)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"inputs":[{"name":"text","shape":[2],"datatype":"BYTES","data":["a","b"]}]}`))
+
+	msg = &BytesPayload{Msg: []byte(`{"data":{"tensor":{"shape":[1,3],"values":[1,2,3]}}}`), ContentType: applicationJson}
+	res, err = TranslateResponse(msg, api.ProtocolSeldon, api.ProtocolKFServing)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"outputs":[{"name":"output-0","shape":[1,3],"datatype":"FP64","data":[1,2,3]}]}`))
+}
+
+func TestTranslateSeldonJsonToV2Errors(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, data := range []string{
+		`{"data":{"ndarray":[[1,2],[3]]}}`,
+		`{"data":{"ndarray":[1,[2]]}}`,
+		`{"data":{"ndarray":[1,"a"]}}`,
+		`{"jsonData":{"a":1}}`,
+		`not json`,
+	} {
+		msg := &BytesPayload{Msg: []byte(data), ContentType: applicationJson}
+		_, err := TranslateRequest(msg, api.ProtocolSeldon, api.ProtocolV2)
+		g.Expect(err).ToNot(BeNil(), data)
+	}
+}
+
+func TestTranslateV2JsonToSeldon(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &BytesPayload{Msg: []byte(`{"id":"abc","outputs":[{"name":"predict","shape":[2,2],"datatype":"INT64","data":[[1,2],[3,4]]}]}`), ContentType: applicationJson}
+	res, err := TranslateResponse(msg, api.ProtocolV2, api.ProtocolSeldon)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"meta":{"puid":"abc"},"data":{"names":["predict"],"ndarray":[[1,2],[3,4]]}}`))
+
+	// Single column tensors are combined into the columns of one ndarray
+	msg = &BytesPayload{Msg: []byte(`{"outputs":[{"name":"a","shape":[2],"datatype":"FP32","data":[1,2]},{"name":"b","shape":[2,1],"datatype":"BOOL","data":[true,false]}]}`), ContentType: applicationJson}
+	res, err = TranslateResponse(msg, api.ProtocolV2, api.ProtocolSeldon)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"meta":{},"data":{"names":["a","b"],"ndarray":[[1,true],[2,false]]}}`))
+
+	msg = &BytesPayload{Msg: []byte(`{"outputs":[{"name":"a","shape":[2,2],"datatype":"FP32","data":[1,2,3,4]},{"name":"b","shape":[2],"datatype":"FP32","data":[1,2]}]}`), ContentType: applicationJson}
+	_, err = TranslateResponse(msg, api.ProtocolV2, api.ProtocolSeldon)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestTranslateProto(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var sm seldon.SeldonMessage
+	err := jsonpb.UnmarshalString(`{"data":{"names":["x"],"ndarray":[[1.5],[2.5]]}}`, &sm)
+	g.Expect(err).Should(BeNil())
+	res, err := TranslateRequest(&ProtoPayload{Msg: &sm}, api.ProtocolSeldon, api.ProtocolV2)
+	g.Expect(err).Should(BeNil())
+	req := res.GetPayload().(*inference.ModelInferRequest)
+	g.Expect(len(req.Inputs)).To(Equal(1))
+	g.Expect(req.Inputs[0].Name).To(Equal("x"))
+	g.Expect(req.Inputs[0].Datatype).To(Equal("FP64"))
+	g.Expect(req.Inputs[0].Shape).To(Equal([]int64{2, 1}))
+	g.Expect(req.Inputs[0].Contents.Fp64Contents).To(Equal([]float64{1.5, 2.5}))
+
+	// Raw contents are decoded using the tensor's datatype
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint32(raw, math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(raw[4:], math.Float32bits(0.25))
+	resp := &inference.ModelInferResponse{
+		Outputs:           []*inference.ModelInferResponse_InferOutputTensor{{Name: "y", Datatype: "FP32", Shape: []int64{2}}},
+		RawOutputContents: [][]byte{raw},
+	}
+	res, err = TranslateResponse(&ProtoPayload{Msg: resp}, api.ProtocolV2, api.ProtocolSeldon)
+	g.Expect(err).Should(BeNil())
+	var expected seldon.SeldonMessage
+	err = jsonpb.UnmarshalString(`{"meta":{},"data":{"names":["y"],"ndarray":[0.5,0.25]}}`, &expected)
+	g.Expect(err).Should(BeNil())
+	g.Expect(proto.Equal(res.GetPayload().(*seldon.SeldonMessage), &expected)).To(BeTrue())
+}
+
+func TestTranslateSameProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &BytesPayload{Msg: []byte(`{"inputs":[]}`), ContentType: applicationJson}
+	res, err := TranslateRequest(msg, api.ProtocolKFServing, api.ProtocolV2)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).To(BeIdenticalTo(msg))
+
+	_, err = TranslateRequest(msg, api.ProtocolTensorflow, api.ProtocolV2)
+	g.Expect(err).ToNot(BeNil())
+}
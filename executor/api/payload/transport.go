@@ -0,0 +1,69 @@
+package payload
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	protoV1 "github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+)
+
+// ConvertRequest converts a request between its JSON form, used over REST, and its protobuf form, used over gRPC.
+// Payloads already in the form for the transport are returned unchanged. Only Seldon and V2 protocol payloads can be
+// converted.
+func ConvertRequest(msg SeldonPayload, protocol string, grpc bool) (SeldonPayload, error) {
+	return convert(msg, protocol, grpc, false)
+}
+
+// ConvertResponse converts a response between transports in the same way as ConvertRequest.
+func ConvertResponse(msg SeldonPayload, protocol string, grpc bool) (SeldonPayload, error) {
+	return convert(msg, protocol, grpc, true)
+}
+
+// ConvertFeedback converts Seldon protocol feedback between transports.
+func ConvertFeedback(msg SeldonPayload, grpc bool) (SeldonPayload, error) {
+	return convertSeldon(msg, grpc, func() protoV1.Message { return &proto.Feedback{} })
+}
+
+func convert(msg SeldonPayload, protocol string, grpc bool, response bool) (SeldonPayload, error) {
+	if _, isProto := msg.GetPayload().(protoV1.Message); isProto == grpc {
+		return msg, nil
+	}
+	switch {
+	case protocol == api.ProtocolSeldon:
+		return convertSeldon(msg, grpc, func() protoV1.Message { return &proto.SeldonMessage{} })
+	case isV2(protocol):
+		tensors, id, err := v2ToTensors(msg, response, !grpc)
+		if err != nil {
+			return nil, err
+		}
+		return tensorsToV2(tensors, id, response, grpc)
+	}
+	return nil, fmt.Errorf("can not convert %s protocol payloads between REST and gRPC", protocol)
+}
+
+func convertSeldon(msg SeldonPayload, grpc bool, newMessage func() protoV1.Message) (SeldonPayload, error) {
+	pm, isProto := msg.GetPayload().(protoV1.Message)
+	if isProto == grpc {
+		return msg, nil
+	}
+	if isProto {
+		ma := jsonpb.Marshaler{}
+		data, err := ma.MarshalToString(pm)
+		if err != nil {
+			return nil, err
+		}
+		return &BytesPayload{Msg: []byte(data), ContentType: applicationJson}, nil
+	}
+	data, err := DecompressSeldonPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	m := newMessage()
+	if err := jsonpb.Unmarshal(bytes.NewReader(data), m); err != nil {
+		return nil, fmt.Errorf("can not convert invalid seldon payload to protobuf: %w", err)
+	}
+	return &ProtoPayload{Msg: m}, nil
+}
@@ -0,0 +1,61 @@
+package payload
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+)
+
+func TestConvertSeldon(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &BytesPayload{Msg: []byte(`{"meta":{"puid":"abc"},"strData":"hello"}`), ContentType: applicationJson}
+	res, err := ConvertRequest(msg, api.ProtocolSeldon, true)
+	g.Expect(err).Should(BeNil())
+	sm := res.GetPayload().(*proto.SeldonMessage)
+	g.Expect(sm.GetStrData()).To(Equal("hello"))
+	g.Expect(sm.GetMeta().GetPuid()).To(Equal("abc"))
+
+	res, err = ConvertResponse(res, api.ProtocolSeldon, false)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"meta":{"puid":"abc"},"strData":"hello"}`))
+
+	// Payloads already in the form for the transport are unchanged
+	res, err = ConvertRequest(msg, api.ProtocolSeldon, false)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).To(BeIdenticalTo(msg))
+
+	fb := &BytesPayload{Msg: []byte(`{"reward":1}`), ContentType: applicationJson}
+	res, err = ConvertFeedback(fb, true)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res.GetPayload().(*proto.Feedback).GetReward()).To(Equal(float32(1)))
+}
+
+func TestConvertV2(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &BytesPayload{Msg: []byte(`{"id":"abc","inputs":[{"name":"x","shape":[2],"datatype":"INT32","data":[1,2]}]}`), ContentType: applicationJson}
+	res, err := ConvertRequest(msg, api.ProtocolV2, true)
+	g.Expect(err).Should(BeNil())
+	req := res.GetPayload().(*inference.ModelInferRequest)
+	g.Expect(req.Id).To(Equal("abc"))
+	g.Expect(req.Inputs[0].Contents.IntContents).To(Equal([]int32{1, 2}))
+
+	resp := &ProtoPayload{Msg: &inference.ModelInferResponse{
+		Outputs: []*inference.ModelInferResponse_InferOutputTensor{{Name: "y", Datatype: "FP32", Shape: []int64{1}, Contents: &inference.InferTensorContents{Fp32Contents: []float32{0.5}}}},
+	}}
+	res, err = ConvertResponse(resp, api.ProtocolKFServing, false)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"outputs":[{"name":"y","shape":[1],"datatype":"FP32","data":[0.5]}]}`))
+}
+
+func TestConvertTensorflow(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &BytesPayload{Msg: []byte(`{"instances":[1]}`), ContentType: applicationJson}
+	_, err := ConvertRequest(msg, api.ProtocolTensorflow, true)
+	g.Expect(err).ToNot(BeNil())
+}
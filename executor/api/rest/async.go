@@ -0,0 +1,329 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	http2 "github.com/cloudevents/sdk-go/pkg/bindings/http"
+	guuid "github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	asyncWorkersEnvVar           = "SELDON_ASYNC_WORKERS"
+	asyncWorkersDefault          = 4
+	asyncQueueSizeEnvVar         = "SELDON_ASYNC_QUEUE_SIZE"
+	asyncQueueSizeDefault        = 100
+	asyncResultTtlSecondsEnvVar  = "SELDON_ASYNC_RESULT_TTL_SECONDS"
+	asyncResultTtlDefault        = 600
+	asyncJobTimeoutSecondsEnvVar = "SELDON_ASYNC_JOB_TIMEOUT_SECONDS"
+	asyncJobTimeoutDefault       = 300
+
+	SeldonCallbackUrlHeader  = "Seldon-Callback-Url"
+	AsyncJobHttpPathVariable = "job"
+)
+
+// Callbacks are only sent over HTTP to the hosts set with SetAsyncCallbackHosts, so a caller can not have the executor
+// send requests to arbitrary services inside the cluster. Callbacks are refused if no hosts are set.
+var (
+	asyncCallbackHosts      = make(map[string]bool)
+	asyncCallbackHostsMutex sync.RWMutex
+)
+
+// SetAsyncCallbackHosts sets the hosts the results of asynchronous predictions may be sent to.
+func SetAsyncCallbackHosts(hosts []string) {
+	asyncCallbackHostsMutex.Lock()
+	defer asyncCallbackHostsMutex.Unlock()
+	asyncCallbackHosts = make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			asyncCallbackHosts[host] = true
+		}
+	}
+}
+
+func asyncCallbackAllowed(callback *url.URL) bool {
+	if callback.Scheme != "http" && callback.Scheme != "https" {
+		return false
+	}
+	asyncCallbackHostsMutex.RLock()
+	defer asyncCallbackHostsMutex.RUnlock()
+	return asyncCallbackHosts[strings.ToLower(callback.Hostname())]
+}
+
+type asyncJobStatus string
+
+const (
+	asyncJobQueued    asyncJobStatus = "queued"
+	asyncJobRunning   asyncJobStatus = "running"
+	asyncJobSucceeded asyncJobStatus = "succeeded"
+	asyncJobFailed    asyncJobStatus = "failed"
+)
+
+// asyncJob is a prediction run in the background while the caller polls for its result.
+type asyncJob struct {
+	id     string
+	mu     sync.Mutex
+	status asyncJobStatus
+	result payload.SeldonPayload
+	err    error
+}
+
+func (j *asyncJob) state() (asyncJobStatus, payload.SeldonPayload, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status, j.result, j.err
+}
+
+type asyncJobStatusResponse struct {
+	Id     string         `json:"id"`
+	Status asyncJobStatus `json:"status"`
+}
+
+// asyncJobError is returned with its status code when a job can not be submitted or found.
+type asyncJobError struct {
+	code int
+	msg  string
+}
+
+func (e *asyncJobError) Error() string {
+	return e.msg
+}
+
+func (e *asyncJobError) HttpStatusCode() int {
+	return e.code
+}
+
+// asyncJobs runs asynchronous predictions in a bounded pool of workers and keeps each result for a TTL after the job
+// finishes. Jobs that run for longer than the timeout are cancelled and fail. Jobs are only kept in memory so they are
+// lost if the executor restarts.
+type asyncJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*asyncJob
+	queue   chan func()
+	workers int
+	ttl     time.Duration
+	timeout time.Duration
+	start   sync.Once
+}
+
+func newAsyncJobs() *asyncJobs {
+	return &asyncJobs{
+		jobs:    make(map[string]*asyncJob),
+		queue:   make(chan func(), util.GetEnvAsInt(asyncQueueSizeEnvVar, asyncQueueSizeDefault)),
+		workers: util.GetEnvAsInt(asyncWorkersEnvVar, asyncWorkersDefault),
+		ttl:     time.Duration(util.GetEnvAsInt(asyncResultTtlSecondsEnvVar, asyncResultTtlDefault)) * time.Second,
+		timeout: time.Duration(util.GetEnvAsInt(asyncJobTimeoutSecondsEnvVar, asyncJobTimeoutDefault)) * time.Second,
+	}
+}
+
+// submit queues a job to run predict, with a context that expires after the job timeout, and then done. Jobs are rejected if one with the same id is still known or the
+// queue is full.
+func (a *asyncJobs) submit(id string, predict func(ctx context.Context) (payload.SeldonPayload, error), done func(job *asyncJob)) error {
+	// Workers are only started once the first job arrives
+	a.start.Do(func() {
+		for i := 0; i < a.workers; i++ {
+			go func() {
+				for run := range a.queue {
+					run()
+				}
+			}()
+		}
+	})
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.jobs[id]; ok {
+		return &asyncJobError{code: http.StatusConflict, msg: fmt.Sprintf("job %s already exists", id)}
+	}
+	job := &asyncJob{id: id, status: asyncJobQueued}
+	select {
+	case a.queue <- func() { a.run(job, predict, done) }:
+	default:
+		return &asyncJobError{code: http.StatusServiceUnavailable, msg: "too many asynchronous predictions queued"}
+	}
+	a.jobs[id] = job
+	return nil
+}
+
+func (a *asyncJobs) run(job *asyncJob, predict func(ctx context.Context) (payload.SeldonPayload, error), done func(job *asyncJob)) {
+	job.mu.Lock()
+	job.status = asyncJobRunning
+	job.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	res, err := predict(ctx)
+	if ctx.Err() == context.DeadlineExceeded {
+		res, err = nil, &asyncJobError{code: http.StatusGatewayTimeout, msg: fmt.Sprintf("job %s timed out after %s", job.id, a.timeout)}
+	}
+	cancel()
+
+	job.mu.Lock()
+	job.result = res
+	job.err = err
+	if err != nil {
+		job.status = asyncJobFailed
+	} else {
+		job.status = asyncJobSucceeded
+	}
+	job.mu.Unlock()
+
+	done(job)
+	time.AfterFunc(a.ttl, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		delete(a.jobs, job.id)
+	})
+}
+
+func (a *asyncJobs) get(id string) (*asyncJob, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	job, ok := a.jobs[id]
+	return job, ok
+}
+
+// predictionsAsync submits a prediction to run in the background and returns its id, which is the request's PUID,
+// straight away.
+func (r *SeldonRestApi) predictionsAsync(w http.ResponseWriter, req *http.Request) {
+	r.Log.V(1).Info("Async predictions called")
+	puid := req.Header.Get(payload.SeldonPUIDHeader)
+
+	var callback *url.URL
+	if rawUrl := req.Header.Get(SeldonCallbackUrlHeader); rawUrl != "" {
+		var err error
+		callback, err = url.Parse(rawUrl)
+		if err != nil || !callback.IsAbs() {
+			r.respondWithError(w, nil, &asyncJobError{code: http.StatusBadRequest, msg: fmt.Sprintf("invalid callback url %s", rawUrl)})
+			return
+		}
+		if !asyncCallbackAllowed(callback) {
+			r.respondWithError(w, nil, &asyncJobError{code: http.StatusBadRequest, msg: fmt.Sprintf("callback url %s is not allowed", rawUrl)})
+			return
+		}
+	}
+
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+
+	vars := mux.Vars(req)
+	modelName := vars[ModelHttpPathVariable]
+
+	// The job outlives the request so does not use its context, or read the request once it is queued
+	ctx := context.WithValue(context.Background(), payload.SeldonPUIDHeader, puid)
+	remoteSpan := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header)))
+	spec := predictor.ActivePredictor(r.predictor)
+	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header.Clone(), modelName)
+	reqPayload, err := seldonPredictorProcess.Client.Unmarshall(bodyBytes, req.Header.Get(http2.ContentType))
+	if err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+
+	predict := func(jobCtx context.Context) (payload.SeldonPayload, error) {
+		seldonPredictorProcess.Ctx = context.WithValue(jobCtx, payload.SeldonPUIDHeader, puid)
+		// Apply tracing if active
+		if tracing.IsEnabled() {
+			var serverSpan trace.Span
+			if remoteSpan.IsValid() {
+				seldonPredictorProcess.Ctx = trace.ContextWithRemoteSpanContext(seldonPredictorProcess.Ctx, remoteSpan)
+			}
+			seldonPredictorProcess.Ctx, serverSpan = tracing.Tracer().Start(seldonPredictorProcess.Ctx, TracingPredictionsName, trace.WithSpanKind(trace.SpanKindServer))
+			defer serverSpan.End()
+		}
+		return seldonPredictorProcess.Predict(&spec.Graph, reqPayload)
+	}
+	done := func(job *asyncJob) {
+		if callback != nil {
+			r.sendCallback(callback, modelName, job)
+		}
+	}
+	if err := r.asyncJobs.submit(puid, predict, done); err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+
+	w.Header().Set("Location", req.URL.Path+"/"+puid)
+	r.respondWithAsyncStatus(w, http.StatusAccepted, puid, asyncJobQueued)
+}
+
+// asyncResult returns the status of a job until it finishes and then the same response the prediction would have
+// had if it was made synchronously.
+func (r *SeldonRestApi) asyncResult(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	id := vars[AsyncJobHttpPathVariable]
+
+	job, ok := r.asyncJobs.get(id)
+	if !ok {
+		r.respondWithError(w, nil, &asyncJobError{code: http.StatusNotFound, msg: fmt.Sprintf("job %s not found", id)})
+		return
+	}
+	status, res, err := job.state()
+	switch status {
+	case asyncJobSucceeded:
+		r.respondWithSuccess(w, http.StatusOK, res)
+	case asyncJobFailed:
+		r.respondWithError(w, res, err)
+	default:
+		r.respondWithAsyncStatus(w, http.StatusAccepted, id, status)
+	}
+}
+
+func (r *SeldonRestApi) respondWithAsyncStatus(w http.ResponseWriter, code int, id string, status asyncJobStatus) {
+	data, _ := json.Marshal(asyncJobStatusResponse{Id: id, Status: status})
+	r.respondWithSuccess(w, code, &payload.BytesPayload{Msg: data, ContentType: ContentTypeJSON})
+}
+
+// sendCallback sends the result of a finished job, or the error it failed with, to the callback URL as a CloudEvent
+// through the request logger's workers.
+func (r *SeldonRestApi) sendCallback(callback *url.URL, modelName string, job *asyncJob) {
+	_, msg, err := job.state()
+	if err != nil && (msg == nil || msg.GetPayload() == nil) {
+		msg = r.Client.CreateErrorPayload(err)
+	}
+	data, err := msg.GetBytes()
+	if err != nil {
+		r.Log.Error(err, "Failed to create callback", "job", job.id)
+		return
+	}
+	contentType := msg.GetContentType()
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	if modelName == "" {
+		modelName = r.predictor.Graph.Name
+	}
+	err = payloadLogger.QueueLogRequest(payloadLogger.LogRequest{
+		Url:             callback,
+		Bytes:           &data,
+		ContentType:     contentType,
+		ContentEncoding: msg.GetContentEncoding(),
+		ReqType:         payloadLogger.InferenceCallback,
+		Id:              guuid.New().String(),
+		SourceUri:       r.ServerUrl,
+		ModelId:         modelName,
+		RequestId:       job.id,
+	})
+	if err != nil {
+		r.Log.Error(err, "Failed to queue callback", "job", job.id)
+	}
+}
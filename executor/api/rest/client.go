@@ -16,7 +16,6 @@ import (
 	http2 "github.com/cloudevents/sdk-go/pkg/bindings/http"
 	"github.com/go-logr/logr"
 	"github.com/golang/protobuf/jsonpb"
-	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -25,9 +24,13 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -158,7 +161,7 @@ func (smc *JSONRestClient) getMetricsRoundTripper(modelName string, service stri
 		metric.ModelNameMetric:        modelName,
 		metric.ModelImageMetric:       imageName,
 		metric.ModelVersionMetric:     imageVersion,
-	}), http.DefaultTransport)
+	}), http.DefaultTransport, promhttp.WithExemplarFromContext(metric.ExemplarFromContext))
 
 	return promhttp.InstrumentRoundTripperDuration(smc.metrics.ClientHandledSummary.MustCurryWith(prometheus.Labels{
 		metric.DeploymentNameMetric:   smc.DeploymentName,
@@ -181,69 +184,78 @@ func (smc *JSONRestClient) addHeaders(req *http.Request, m map[string][]string)
 	}
 }
 
-func (smc *JSONRestClient) doHttp(ctx context.Context, modelName string, method string, url *url.URL, msg []byte, meta map[string][]string, contentType string, contentEncoding string) ([]byte, string, string, error) {
+func (smc *JSONRestClient) doHttp(ctx context.Context, modelName string, method string, url *url.URL, msg []byte, meta map[string][]string, contentType string, contentEncoding string) (payload.SeldonPayload, error) {
 	smc.Log.V(1).Info("Calling HTTP", "URL", url)
 
+	reqCtx := ctx
+	received, cancel := func() {}, func() {}
+	if isStreaming(ctx) {
+		reqCtx, received, cancel = streamRequestContext(ctx)
+	}
+
 	var req *http.Request
 	var err error
 	if msg != nil {
-		req, err = http.NewRequest("POST", url.String(), bytes.NewBuffer(msg))
+		req, err = http.NewRequestWithContext(reqCtx, "POST", url.String(), bytes.NewBuffer(msg))
 		if err != nil {
-			return nil, "", "", err
+			cancel()
+			return nil, err
 		}
 		req.Header.Set(http2.ContentType, contentType)
 		if contentEncoding != "" {
 			req.Header.Set("Content-Encoding", contentEncoding)
 		}
 	} else {
-		req, err = http.NewRequest("GET", url.String(), nil)
+		req, err = http.NewRequestWithContext(reqCtx, "GET", url.String(), nil)
 		if err != nil {
-			return nil, "", "", err
+			cancel()
+			return nil, err
 		}
 	}
 
 	// Add metadata passed in
 	smc.addHeaders(req, meta)
 
-	if opentracing.IsGlobalTracerRegistered() {
-		tracer := opentracing.GlobalTracer()
-
-		startSpanOptions := make([]opentracing.StartSpanOption, 0)
-		parentSpan := opentracing.SpanFromContext(ctx)
-		if parentSpan != nil {
-			startSpanOptions = append(startSpanOptions, opentracing.ChildOf(parentSpan.Context()))
-		}
-		clientSpan := opentracing.StartSpan(
-			method,
-			startSpanOptions...)
-		defer clientSpan.Finish()
-		tracer.Inject(clientSpan.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+	if tracing.IsEnabled() {
+		spanCtx, clientSpan := tracing.Tracer().Start(ctx, method, trace.WithSpanKind(trace.SpanKindClient))
+		defer clientSpan.End()
+		otel.GetTextMapPropagator().Inject(spanCtx, propagation.HeaderCarrier(req.Header))
 	}
 
-	client := smc.httpClient
+	// The client is shared by concurrent calls so each call instruments its own copy
+	client := *smc.httpClient
 	client.Transport = smc.getMetricsRoundTripper(modelName, method)
 
 	response, err := client.Do(req)
+	received()
 	if err != nil {
-		return nil, "", "", err
+		cancel()
+		return nil, err
+	}
+
+	contentTypeResponse := response.Header.Get(http2.ContentType)
+	contentEncodingResponse := response.Header.Get("Content-Encoding")
+
+	// A streamed response is returned unread and the request is cancelled once the stream is closed
+	if isStreaming(ctx) && response.StatusCode == http.StatusOK && isEventStream(contentTypeResponse) {
+		body := &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+		return payload.NewStreamPayload(body, contentTypeResponse, contentEncodingResponse), nil
 	}
+	defer cancel()
 
 	//Read response
 	b, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return nil, "", "", err
+		return nil, err
 	}
 	defer response.Body.Close()
 
-	contentTypeResponse := response.Header.Get(http2.ContentType)
-	contentEncodingResponse := response.Header.Get("Content-Encoding")
-
 	if response.StatusCode != http.StatusOK {
 		smc.Log.Info("httpPost failed", "response code", response.StatusCode)
 		err = &httpStatusError{StatusCode: response.StatusCode, Url: url}
 	}
 
-	return b, contentTypeResponse, contentEncodingResponse, err
+	return &payload.BytesPayload{Msg: b, ContentType: contentTypeResponse, ContentEncoding: contentEncodingResponse}, err
 }
 
 func (smc *JSONRestClient) modifyMethod(method string, modelName string) string {
@@ -299,7 +311,7 @@ func (smc *JSONRestClient) call(ctx context.Context, modelName string, method st
 		contentEncoding = req.GetContentEncoding()
 	}
 
-	sm, contentType, contentEncoding, err := smc.doHttp(ctx, modelName, method, &url, bytes, meta, contentType, contentEncoding)
+	res, err := smc.doHttp(ctx, modelName, method, &url, bytes, meta, contentType, contentEncoding)
 
 	// Check if a httpStatusError was returned.
 	if err != nil {
@@ -308,8 +320,7 @@ func (smc *JSONRestClient) call(ctx context.Context, modelName string, method st
 		}
 	}
 
-	res := payload.BytesPayload{Msg: sm, ContentType: contentType, ContentEncoding: contentEncoding}
-	return &res, err
+	return res, err
 }
 
 func (smc *JSONRestClient) Status(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
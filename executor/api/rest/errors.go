@@ -14,6 +14,11 @@ func (e *httpStatusError) Error() string {
 	return fmt.Sprintf("Internal service call from executor failed calling %s status code %d", e.Url, e.StatusCode)
 }
 
+// HttpStatusCode returns the status code of the failed call so callers outside this package can inspect it.
+func (e *httpStatusError) HttpStatusCode() int {
+	return e.StatusCode
+}
+
 func invalidPayload(msg string) error {
 	return fmt.Errorf("invalid payload: %s", msg)
 }
@@ -3,6 +3,7 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -13,16 +14,18 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/gorilla/mux"
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/seldonio/seldon-core/executor/api"
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -39,6 +42,7 @@ type SeldonRestApi struct {
 	metrics         *metric.ServerMetrics
 	prometheusPath  string
 	fullHealthCheck bool
+	asyncJobs       *asyncJobs
 }
 
 func NewServerRestApi(predictor *v1.PredictorSpec, client client.SeldonApiClient, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthCheck bool) *SeldonRestApi {
@@ -59,6 +63,7 @@ func NewServerRestApi(predictor *v1.PredictorSpec, client client.SeldonApiClient
 		serverMetrics,
 		prometheusPath,
 		fullHealthCheck,
+		newAsyncJobs(),
 	}
 }
 
@@ -93,8 +98,10 @@ func (r *SeldonRestApi) respondWithSuccess(w http.ResponseWriter, code int, payl
 
 func (r *SeldonRestApi) respondWithError(w http.ResponseWriter, payload payload.SeldonPayload, err error) {
 
-	if serr, ok := err.(*httpStatusError); ok {
-		w.WriteHeader(serr.StatusCode)
+	// Errors from model calls and requests rejected by the executor carry the status to return
+	var serr interface{ HttpStatusCode() int }
+	if errors.As(err, &serr) {
+		w.WriteHeader(serr.HttpStatusCode())
 	} else {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -124,6 +131,7 @@ func (r *SeldonRestApi) wrapMetrics(service string, baseHandler http.HandlerFunc
 			metric.PredictorVersionMetric: r.predictor.Annotations["version"],
 			metric.ServiceMetric:          service}),
 		baseHandler,
+		promhttp.WithExemplarFromContext(metric.ExemplarFromContext),
 	)
 
 	handler = promhttp.InstrumentHandlerDuration(
@@ -135,13 +143,29 @@ func (r *SeldonRestApi) wrapMetrics(service string, baseHandler http.HandlerFunc
 		handler,
 	)
 
-	return handler
+	inFlight := promhttp.InstrumentHandlerInFlight(
+		r.metrics.ServerInFlight.WithLabelValues(r.DeploymentName, r.predictor.Name, r.predictor.Annotations["version"], service),
+		handler,
+	)
+
+	if metric.ExemplarsEnabled() {
+		// The server span is only started by the handler, so exemplars refer to the trace of the caller
+		return func(w http.ResponseWriter, req *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			inFlight.ServeHTTP(w, req.WithContext(ctx))
+		}
+	}
+	return inFlight.ServeHTTP
 }
 
 func (r *SeldonRestApi) Initialise() {
 	r.Router.HandleFunc("/ready", r.checkReady)
 	r.Router.HandleFunc("/live", r.alive)
-	r.Router.Handle(r.prometheusPath, promhttp.Handler())
+	r.Router.NewRoute().Path("/graph/hash").Methods("GET").HandlerFunc(r.graphHash)
+	r.Router.Handle(r.prometheusPath, promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: metric.ExemplarsEnabled()}),
+	))
 	if !r.ProbesOnly {
 		cloudeventHeaderMiddleware := CloudeventHeaderMiddleware{deploymentName: r.DeploymentName, namespace: r.Namespace}
 		r.Router.Use(puidHeader)
@@ -164,6 +188,8 @@ func (r *SeldonRestApi) Initialise() {
 			api10 := r.Router.PathPrefix("/api/v1.0").Methods("OPTIONS", "POST").Subrouter()
 			api10.Handle("/predictions", r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
 			api10.Handle("/feedback", r.wrapMetrics(metric.FeedbackHttpServiceName, r.feedback))
+			api10.Handle("/predictions:async", r.wrapMetrics(metric.AsyncPredictionHttpServiceName, r.predictionsAsync))
+			r.Router.NewRoute().Path("/api/v1.0/predictions:async/{"+AsyncJobHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.AsyncResultHttpServiceName, r.asyncResult))
 			r.Router.NewRoute().Path("/api/v1.0/status/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.status))
 			r.Router.NewRoute().Path("/api/v1.0/status").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.checkReady))
 			r.Router.NewRoute().Path("/api/v1.0/metadata").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.MetadataHttpServiceName, r.graphMetadata))
@@ -185,7 +211,12 @@ func (r *SeldonRestApi) Initialise() {
 			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/infer").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
 			r.Router.NewRoute().Path("/v2/models/infer").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions)) // Nonstandard path - Seldon extension
 			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/ready").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.status))
+			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/stats").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatisticsHttpServiceName, r.statistics))
+			// Statistics of every model as in Triton, which hides the metadata of a model named stats
+			r.Router.NewRoute().Path("/v2/models/stats").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatisticsHttpServiceName, r.statistics))
 			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.MetadataHttpServiceName, r.metadata))
+			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/infer:async").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.AsyncPredictionHttpServiceName, r.predictionsAsync))                      // Nonstandard path - Seldon extension
+			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/infer:async/{"+AsyncJobHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.AsyncResultHttpServiceName, r.asyncResult)) // Nonstandard path - Seldon extension
 			r.Router.NewRoute().PathPrefix("/v2/docs/").Handler(http.StripPrefix("/v2/docs/", http.FileServer(http.Dir("./openapi/open-inference/"))))
 			// Health
 			r.Router.NewRoute().Path("/v2/health/ready").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.checkReady))
@@ -195,7 +226,12 @@ func (r *SeldonRestApi) Initialise() {
 }
 
 func (r *SeldonRestApi) checkReady(w http.ResponseWriter, req *http.Request) {
-	err := predictor.Ready(r.Protocol, &r.predictor.Graph, r.fullHealthCheck)
+	spec := predictor.ActivePredictor(r.predictor)
+	err := predictor.Ready(r.Protocol, &spec.Graph, r.fullHealthCheck)
+	if err == nil {
+		seldonPredictorProcess := predictor.NewPredictorProcess(req.Context(), r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, "")
+		err = seldonPredictorProcess.ReadyEdges(spec)
+	}
 	if err != nil {
 		r.Log.Error(err, "Ready check failed")
 		w.WriteHeader(http.StatusServiceUnavailable)
@@ -204,33 +240,40 @@ func (r *SeldonRestApi) checkReady(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// graphHash reports the hash of the graph new requests run on, which changes when the graph is reloaded.
+func (r *SeldonRestApi) graphHash(w http.ResponseWriter, req *http.Request) {
+	data, err := json.Marshal(predictor.GetActiveGraph(r.predictor))
+	if err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+	r.respondWithSuccess(w, http.StatusOK, &payload.BytesPayload{Msg: data, ContentType: ContentTypeJSON})
+}
+
 func (r *SeldonRestApi) alive(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func setupTracing(ctx context.Context, req *http.Request, spanName string) (context.Context, opentracing.Span) {
-	tracer := opentracing.GlobalTracer()
-	spanCtx, _ := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
-	serverSpan := tracer.StartSpan(spanName, ext.RPCServerOption(spanCtx))
-	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-	return ctx, serverSpan
+func setupTracing(ctx context.Context, req *http.Request, spanName string) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+	return tracing.Tracer().Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindServer))
 }
 
 func (r *SeldonRestApi) metadata(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
 		ctx, serverSpan = setupTracing(ctx, req, TracingMetadataName)
-		defer serverSpan.Finish()
+		defer serverSpan.End()
 	}
 
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
-	resPayload, err := seldonPredictorProcess.Metadata(&r.predictor.Graph, modelName, nil)
+	resPayload, err := seldonPredictorProcess.Metadata(&predictor.ActivePredictor(r.predictor).Graph, modelName, nil)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -242,17 +285,17 @@ func (r *SeldonRestApi) status(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
 		ctx, serverSpan = setupTracing(ctx, req, TracingStatusName)
-		defer serverSpan.Finish()
+		defer serverSpan.End()
 	}
 
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
-	resPayload, err := seldonPredictorProcess.Status(&r.predictor.Graph, modelName, nil)
+	resPayload, err := seldonPredictorProcess.Status(&predictor.ActivePredictor(r.predictor).Graph, modelName, nil)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -260,15 +303,34 @@ func (r *SeldonRestApi) status(w http.ResponseWriter, req *http.Request) {
 	r.respondWithSuccess(w, http.StatusOK, resPayload)
 }
 
+// statistics returns the V2 protocol statistics of a model, or of all models if none is given. Counters are written
+// as JSON numbers, as Triton does, rather than the strings of the protobuf JSON mapping.
+func (r *SeldonRestApi) statistics(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	modelName := vars[ModelHttpPathVariable]
+
+	stats, err := predictor.ModelStatistics(modelName)
+	if err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+	r.respondWithSuccess(w, http.StatusOK, &payload.BytesPayload{Msg: data, ContentType: ContentTypeJSON})
+}
+
 func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, req.Header.Get(payload.SeldonPUIDHeader))
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
 		ctx, serverSpan = setupTracing(ctx, req, TracingStatusName)
-		defer serverSpan.Finish()
+		defer serverSpan.End()
 	}
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
@@ -284,7 +346,7 @@ func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resPayload, err := seldonPredictorProcess.Feedback(&r.predictor.Graph, reqPayload)
+	resPayload, err := seldonPredictorProcess.Feedback(&predictor.ActivePredictor(r.predictor).Graph, reqPayload)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -294,16 +356,20 @@ func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 
 func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 	r.Log.V(1).Info("Predictions called")
+	start := time.Now()
 
 	ctx := req.Context()
 	// Add Seldon Puid to Context
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, req.Header.Get(payload.SeldonPUIDHeader))
+	if acceptsEventStream(req) {
+		ctx = withStreaming(ctx)
+	}
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
 		ctx, serverSpan = setupTracing(ctx, req, TracingPredictionsName)
-		defer serverSpan.Finish()
+		defer serverSpan.End()
 	}
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
@@ -315,6 +381,8 @@ func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
+	// The request runs on the graph active when it arrived even if the graph is reloaded meanwhile
+	spec := predictor.ActivePredictor(r.predictor)
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
 
 	reqPayload, err := seldonPredictorProcess.Client.Unmarshall(bodyBytes, req.Header.Get(http2.ContentType))
@@ -323,11 +391,24 @@ func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resPayload, err := seldonPredictorProcess.Predict(&r.predictor.Graph, reqPayload)
+	reqPayload, err = seldonPredictorProcess.ValidateRequest(&spec.Graph, reqPayload)
+	if err != nil {
+		r.respondWithError(w, nil, err)
+		return
+	}
+
+	resPayload, err := seldonPredictorProcess.Predict(&spec.Graph, reqPayload)
+	if trace, ok := seldonPredictorProcess.TraceHeader(); ok {
+		w.Header().Set(payload.SeldonTraceHeader, trace)
+	}
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
 	}
+	if stream, ok := resPayload.(*payload.StreamPayload); ok {
+		r.respondWithStream(ctx, w, metric.PredictionHttpServiceName, start, stream)
+		return
+	}
 	r.respondWithSuccess(w, http.StatusOK, resPayload)
 }
 
@@ -337,15 +418,15 @@ func (r *SeldonRestApi) graphMetadata(w http.ResponseWriter, req *http.Request)
 	ctx := req.Context()
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
+	if tracing.IsEnabled() {
+		var serverSpan trace.Span
 		ctx, serverSpan = setupTracing(ctx, req, TracingMetadataName)
-		defer serverSpan.Finish()
+		defer serverSpan.End()
 	}
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, "")
 
-	graphMetadata, err := seldonPredictorProcess.GraphMetadata(r.predictor)
+	graphMetadata, err := seldonPredictorProcess.GraphMetadata(predictor.ActivePredictor(r.predictor))
 
 	if err != nil {
 		r.respondWithError(w, nil, err)
@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	guuid "github.com/google/uuid"
 	. "github.com/onsi/gomega"
@@ -16,7 +19,10 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/test"
+	"github.com/seldonio/seldon-core/executor/logger"
+	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -708,3 +714,271 @@ func TestTensorflowModelBadModelName(t *testing.T) {
 	r.Router.ServeHTTP(res, req)
 	g.Expect(res.Code).To(Equal(200))
 }
+
+func TestV2ModelStatistics(t *testing.T) {
+	t.Logf("Started")
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "statsmodel",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		},
+	}
+
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolV2, "test", "/metrics", true)
+	r.Initialise()
+	// Statistics are kept for the lifetime of the executor so only the change is checked
+	var inferences, executions uint64
+	if before, err := predictor.ModelStatistics("statsmodel"); err == nil {
+		inferences, executions = before.ModelStats[0].InferenceCount, before.ModelStats[0].ExecutionCount
+	}
+
+	var data = `{"inputs":[{"name":"input","datatype":"FP32","shape":[1],"data":[1.0]}]}`
+	req, _ := http.NewRequest("POST", "/v2/models/statsmodel/infer", strings.NewReader(data))
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(200))
+
+	req, _ = http.NewRequest("GET", "/v2/models/statsmodel/stats", nil)
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(200))
+	var stats map[string][]map[string]interface{}
+	err := json.Unmarshal(res.Body.Bytes(), &stats)
+	g.Expect(err).To(BeNil())
+	g.Expect(stats["model_stats"]).To(HaveLen(1))
+	g.Expect(stats["model_stats"][0]["name"]).To(Equal("statsmodel"))
+	g.Expect(stats["model_stats"][0]["inference_count"]).To(Equal(float64(inferences + 1)))
+	g.Expect(stats["model_stats"][0]["execution_count"]).To(Equal(float64(executions + 1)))
+
+	req, _ = http.NewRequest("GET", "/v2/models/unknown/stats", nil)
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(404))
+}
+
+func TestPredictionsEventStream(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	next := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Header.Get("Accept")).To(Equal("text/event-stream"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"token\":\"a\"}\n\n"))
+		w.(http.Flusher).Flush()
+		// The second chunk is only sent once the caller has received the first
+		<-next
+		w.Write([]byte("data: {\"token\":\"b\"}\n\n"))
+	})
+	model := httptest.NewServer(handler)
+	defer model.Close()
+	modelUrl, err := url.Parse(model.URL)
+	g.Expect(err).Should(BeNil())
+	port, err := strconv.Atoi(modelUrl.Port())
+	g.Expect(err).Should(BeNil())
+
+	modelType := v1.MODEL
+	timeout := int32(100)
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "stream",
+			Type: &modelType,
+			// The node timeout applies until the response headers arrive
+			TimeoutMs: &timeout,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: modelUrl.Hostname(),
+				ServicePort: int32(port),
+				Type:        v1.REST,
+				HttpPort:    int32(port),
+			},
+		},
+	}
+	client, err := NewJSONRestClient(api.ProtocolSeldon, "dep", &p, nil)
+	g.Expect(err).To(BeNil())
+	r := NewServerRestApi(&p, client, false, modelUrl, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.Initialise()
+	executor := httptest.NewServer(r.Router)
+	defer executor.Close()
+
+	req, _ := http.NewRequest("POST", executor.URL+"/api/v1.0/predictions", strings.NewReader(`{"data":{"ndarray":[1]}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	res, err := http.DefaultClient.Do(req)
+	g.Expect(err).To(BeNil())
+	defer res.Body.Close()
+	g.Expect(res.StatusCode).To(Equal(200))
+	g.Expect(res.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+	chunk := make([]byte, 1024)
+	n, err := res.Body.Read(chunk)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(chunk[:n])).To(Equal("data: {\"token\":\"a\"}\n\n"))
+	time.Sleep(200 * time.Millisecond)
+	close(next)
+	rest, err := ioutil.ReadAll(res.Body)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(rest)).To(Equal("data: {\"token\":\"b\"}\n\n"))
+
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	metricsRes := httptest.NewRecorder()
+	r.Router.ServeHTTP(metricsRes, req)
+	tp := expfmt.TextParser{}
+	metrics, err := tp.TextToMetricFamilies(metricsRes.Body)
+	g.Expect(err).Should(BeNil())
+	g.Expect(metrics[metric.ServerTimeToFirstByteMetricName]).ShouldNot(BeNil())
+}
+
+func TestPredictionsAsync(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	callbacks := make(chan *http.Request, 1)
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbacks <- r
+	}))
+	defer callbackServer.Close()
+	callbackUrl, _ := url.Parse(callbackServer.URL)
+	SetAsyncCallbackHosts([]string{callbackUrl.Hostname()})
+	defer SetAsyncCallbackHosts(nil)
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, logf.Log.WithName("test"), "", "", "", "", "", api.ProtocolSeldon)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		},
+	}
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.Initialise()
+
+	var data = `{"data":{"ndarray":[1.1,2]}}`
+	req, _ := http.NewRequest("POST", "/api/v1.0/predictions:async", strings.NewReader(data))
+	req.Header = map[string][]string{
+		"Content-Type":           {"application/json"},
+		payload.SeldonPUIDHeader: {"async-1"},
+		SeldonCallbackUrlHeader:  {callbackServer.URL},
+	}
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusAccepted))
+	g.Expect(res.Header().Get("Location")).To(Equal("/api/v1.0/predictions:async/async-1"))
+	var status asyncJobStatusResponse
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &status)).To(BeNil())
+	g.Expect(status.Id).To(Equal("async-1"))
+
+	// A job id can only be used once
+	req, _ = http.NewRequest("POST", "/api/v1.0/predictions:async", strings.NewReader(data))
+	req.Header = map[string][]string{"Content-Type": {"application/json"}, payload.SeldonPUIDHeader: {"async-1"}}
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusConflict))
+
+	g.Eventually(func() int {
+		req, _ := http.NewRequest("GET", "/api/v1.0/predictions:async/async-1", nil)
+		res = httptest.NewRecorder()
+		r.Router.ServeHTTP(res, req)
+		return res.Code
+	}).Should(Equal(http.StatusOK))
+	g.Expect(res.Body.String()).To(Equal(data))
+
+	var callback *http.Request
+	g.Eventually(callbacks, 5*time.Second).Should(Receive(&callback))
+	g.Expect(callback.Header.Get("Ce-Type")).To(Equal(logger.CEInferenceCallback))
+	g.Expect(callback.Header.Get("Ce-Requestid")).To(Equal("async-1"))
+
+	req, _ = http.NewRequest("GET", "/api/v1.0/predictions:async/unknown", nil)
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusNotFound))
+}
+
+func TestAsyncJobTimeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+	jobs := newAsyncJobs()
+	jobs.timeout = 50 * time.Millisecond
+
+	finished := make(chan *asyncJob, 1)
+	err := jobs.submit("slow", func(ctx context.Context) (payload.SeldonPayload, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, func(job *asyncJob) { finished <- job })
+	g.Expect(err).To(BeNil())
+
+	var job *asyncJob
+	g.Eventually(finished, 5*time.Second).Should(Receive(&job))
+	status, _, err := job.state()
+	g.Expect(status).To(Equal(asyncJobFailed))
+	g.Expect(err).To(MatchError("job slow timed out after 50ms"))
+	g.Expect(err.(*asyncJobError).HttpStatusCode()).To(Equal(http.StatusGatewayTimeout))
+}
+
+func TestPredictionsAsyncCallbackHosts(t *testing.T) {
+	g := NewGomegaWithT(t)
+	SetAsyncCallbackHosts([]string{"callbacks.example.com"})
+	defer SetAsyncCallbackHosts(nil)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		},
+	}
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.Initialise()
+
+	for callbackUrl, expected := range map[string]int{
+		"http://callbacks.example.com/results":  http.StatusAccepted,
+		"https://CALLBACKS.example.com:8443/r":  http.StatusAccepted,
+		"http://169.254.169.254/latest":         http.StatusBadRequest,
+		"file:///etc/passwd":                    http.StatusBadRequest,
+		"ftp://callbacks.example.com/results":   http.StatusBadRequest,
+		"http://callbacks.example.com.evil/foo": http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest("POST", "/api/v1.0/predictions:async", strings.NewReader(`{"data":{"ndarray":[1]}}`))
+		req.Header = map[string][]string{
+			"Content-Type":           {"application/json"},
+			payload.SeldonPUIDHeader: {"callback-" + callbackUrl},
+			SeldonCallbackUrlHeader:  {callbackUrl},
+		}
+		res := httptest.NewRecorder()
+		r.Router.ServeHTTP(res, req)
+		g.Expect(res.Code).To(Equal(expected), callbackUrl)
+	}
+
+	// Callbacks are refused when no hosts are allowed
+	SetAsyncCallbackHosts(nil)
+	req, _ := http.NewRequest("POST", "/api/v1.0/predictions:async", strings.NewReader(`{"data":{"ndarray":[1]}}`))
+	req.Header = map[string][]string{
+		"Content-Type":           {"application/json"},
+		payload.SeldonPUIDHeader: {"callback-none"},
+		SeldonCallbackUrlHeader:  {"http://callbacks.example.com/results"},
+	}
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusBadRequest))
+}
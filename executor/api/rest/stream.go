@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+type streamContextKey struct{}
+
+// withStreaming marks a request whose caller accepts a streamed response.
+func withStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamContextKey{}, true)
+}
+
+func isStreaming(ctx context.Context) bool {
+	streaming, _ := ctx.Value(streamContextKey{}).(bool)
+	return streaming
+}
+
+func isEventStream(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), payload.TEXT_EVENT_STREAM)
+}
+
+func acceptsEventStream(req *http.Request) bool {
+	for _, accept := range req.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if isEventStream(mediaType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamRequestContext returns the context for a call whose response may be streamed. It is cancelled with ctx until
+// received is called once the response headers have arrived. After that only cancel stops the call, so the body can
+// still be read when the call to the node has returned and ctx, which may carry the node's timeout, is cancelled.
+func streamRequestContext(ctx context.Context) (reqCtx context.Context, received func(), cancel context.CancelFunc) {
+	reqCtx, cancel = context.WithCancel(context.Background())
+	receivedCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			// ctx is only cancelled after received when the call has returned
+			select {
+			case <-receivedCh:
+			default:
+				cancel()
+			}
+		case <-receivedCh:
+		case <-reqCtx.Done():
+		}
+	}()
+	var once sync.Once
+	return reqCtx, func() { once.Do(func() { close(receivedCh) }) }, cancel
+}
+
+// cancelOnClose cancels the request of a streamed response when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// respondWithStream writes a streamed response, flushing each chunk to the caller as it arrives from the model.
+func (r *SeldonRestApi) respondWithStream(ctx context.Context, w http.ResponseWriter, service string, start time.Time, stream *payload.StreamPayload) {
+	w.Header().Set("Content-Type", stream.GetContentType())
+	contentEncoding := stream.GetContentEncoding()
+	if contentEncoding != "" {
+		w.Header().Set("Content-Encoding", contentEncoding)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Stop reading from the model if the caller goes away while waiting for the next chunk
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	firstChunk := true
+	err := stream.Stream(w, func() {
+		if firstChunk {
+			firstChunk = false
+			r.metrics.ServerTimeToFirstByteHistogram.WithLabelValues(r.DeploymentName, r.predictor.Name, r.predictor.Annotations["version"], service).Observe(time.Since(start).Seconds())
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+	if err != nil {
+		r.Log.Error(err, "Failed to stream response")
+	}
+}
@@ -1,52 +1,173 @@
 package tracing
 
 import (
+	"context"
 	"io"
 	"os"
+	"strings"
 
-	"github.com/opentracing/opentracing-go"
-	jaegercfg "github.com/uber/jaeger-client-go/config"
-	"github.com/uber/jaeger-client-go/zipkin"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// TracerName is the instrumentation name of the executor's spans.
+	TracerName = "github.com/seldonio/seldon-core/executor"
+
+	ENV_TRACING                 = "TRACING"
+	ENV_OTLP_ENDPOINT           = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	ENV_OTLP_TRACES_ENDPOINT    = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	ENV_OTLP_PROTOCOL           = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	ENV_OTLP_TRACES_PROTOCOL    = "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"
+	ENV_PROPAGATORS             = "OTEL_PROPAGATORS"
+	ENV_LEGACY_PROPAGATION_TYPE = "JAEGER_TRACE_PROPAGATION_TYPE"
+	ENV_LEGACY_SERVICE_NAME     = "JAEGER_SERVICE_NAME"
+
+	defaultServiceName = "executor"
+	protocolHTTP       = "http/protobuf"
+)
+
+var enabled bool
+
+// IsEnabled reports whether spans are exported. Without an exporter the global tracer provider creates spans that
+// record nothing, so callers may skip tracing altogether.
+func IsEnabled() bool {
+	return enabled
+}
+
+// Tracer returns the tracer the executor's spans are created with.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// SetTracerProvider exports spans with the given tracer provider rather than one configured from the environment.
+func SetTracerProvider(tp trace.TracerProvider) {
+	otel.SetTracerProvider(tp)
+	enabled = true
+}
+
+type shutdownCloser func(ctx context.Context) error
+
+func (c shutdownCloser) Close() error {
+	return c(context.Background())
+}
+
+// InitTracing sets the global tracer provider and propagator from the environment. Spans are exported over OTLP when
+// TRACING is set or an OTLP endpoint is given, using gRPC unless OTEL_EXPORTER_OTLP_PROTOCOL is http/protobuf. The
+// exporter is otherwise configured through the standard OTEL_EXPORTER_OTLP_* variables. Trace context is propagated
+// with W3C traceparent headers unless OTEL_PROPAGATORS or JAEGER_TRACE_PROPAGATION_TYPE asks for B3.
 func InitTracing() (io.Closer, error) {
-	//Initialise tracing
-	cfg, err := jaegercfg.FromEnv()
+	otel.SetTextMapPropagator(newPropagator())
+	if !tracingRequested() {
+		return shutdownCloser(func(ctx context.Context) error { return nil }), nil
+	}
+
+	ctx := context.Background()
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName())),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
 	if err != nil {
-		// parsing errors might happen here, such as when we get a string where we expect a number
 		return nil, err
 	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	SetTracerProvider(tp)
+	return shutdownCloser(tp.Shutdown), nil
+}
 
-	if cfg.ServiceName == "" {
-		cfg.ServiceName = "executor"
+func tracingRequested() bool {
+	switch strings.ToLower(os.Getenv(ENV_TRACING)) {
+	case "1", "true":
+		return true
 	}
+	return os.Getenv(ENV_OTLP_ENDPOINT) != "" || os.Getenv(ENV_OTLP_TRACES_ENDPOINT) != ""
+}
 
-	propagation := os.Getenv("JAEGER_TRACE_PROPAGATION_TYPE")
-	closer, err := newTraceByPropagation(propagation, cfg)
-	if err != nil {
-		return nil, err
+func serviceName() string {
+	if name := os.Getenv(ENV_LEGACY_SERVICE_NAME); name != "" {
+		return name
 	}
-	return closer, nil
+	return defaultServiceName
 }
 
-func newTraceByPropagation(propagation string, cfg *jaegercfg.Configuration) (closer io.Closer, err error) {
-	switch propagation {
-	case "b3":
-		return newB3Tracer(cfg)
+func newExporter(ctx context.Context) (*otlptrace.Exporter, error) {
+	protocol := os.Getenv(ENV_OTLP_TRACES_PROTOCOL)
+	if protocol == "" {
+		protocol = os.Getenv(ENV_OTLP_PROTOCOL)
+	}
+	if protocol == protocolHTTP {
+		return otlptracehttp.New(ctx)
 	}
-	return newDefaultTracer(cfg)
+	return otlptracegrpc.New(ctx)
 }
 
-func newB3Tracer(cfg *jaegercfg.Configuration) (io.Closer, error) {
-	zipkinPropagator := zipkin.NewZipkinB3HTTPHeaderPropagator()
-	closer, err := cfg.InitGlobalTracer(cfg.ServiceName,
-		jaegercfg.Injector(opentracing.HTTPHeaders, zipkinPropagator),
-		jaegercfg.Extractor(opentracing.HTTPHeaders, zipkinPropagator),
-		jaegercfg.ZipkinSharedRPCSpan(true))
+// newPropagator returns the propagators named in OTEL_PROPAGATORS, or B3 if the Jaeger propagation type asks for it,
+// or otherwise W3C trace context and baggage. B3 is injected as multiple headers as it was with the Jaeger tracer.
+func newPropagator() propagation.TextMapPropagator {
+	names := os.Getenv(ENV_PROPAGATORS)
+	if names == "" {
+		if os.Getenv(ENV_LEGACY_PROPAGATION_TYPE) == "b3" {
+			names = "b3multi"
+		} else {
+			names = "tracecontext,baggage"
+		}
+	}
+	var propagators []propagation.TextMapPropagator
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New())
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...)
+}
+
+// MetadataCarrier carries trace context in request metadata such as Kafka headers, whose keys are matched without
+// regard to case.
+type MetadataCarrier map[string][]string
+
+func (c MetadataCarrier) Get(key string) string {
+	if values, ok := c[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	for k, values := range c {
+		if strings.EqualFold(k, key) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
 
-	return closer, err
+func (c MetadataCarrier) Set(key string, value string) {
+	c[key] = []string{value}
 }
-func newDefaultTracer(cfg *jaegercfg.Configuration) (io.Closer, error) {
-	closer, err := cfg.InitGlobalTracer(cfg.ServiceName)
-	return closer, err
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
 }
@@ -1,44 +1,80 @@
 package tracing
 
 import (
-	"github.com/opentracing/opentracing-go"
-	"github.com/uber/jaeger-client-go"
-	"os"
+	"context"
+	"net/http"
 	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	nonRootNonSampledHeader = opentracing.TextMapCarrier{
-		"x-b3-traceid":      "1",
-		"x-b3-spanid":       "2",
-		"x-b3-parentspanid": "1",
-		"x-b3-sampled":      "0",
+	nonRootNonSampledHeader = http.Header{
+		"X-B3-Traceid":      []string{"00000000000000010000000000000001"},
+		"X-B3-Spanid":       []string{"0000000000000002"},
+		"X-B3-Parentspanid": []string{"0000000000000001"},
+		"X-B3-Sampled":      []string{"0"},
+	}
+	traceparentHeader = http.Header{
+		"Traceparent": []string{"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
 	}
 )
 
-func newSpanContext(traceID, spanID, parentID uint64, sampled bool, baggage map[string]string) jaeger.SpanContext {
-	return jaeger.NewSpanContext(
-		jaeger.TraceID{Low: traceID},
-		jaeger.SpanID(spanID),
-		jaeger.SpanID(parentID),
-		sampled,
-		baggage,
-	)
+func TestInitTracingB3(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Setenv(ENV_LEGACY_PROPAGATION_TYPE, "b3")
+	closer, err := InitTracing()
+	g.Expect(err).Should(BeNil())
+	defer closer.Close()
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(nonRootNonSampledHeader))
+	sc := trace.SpanContextFromContext(ctx)
+	g.Expect(sc.IsValid()).To(BeTrue())
+	g.Expect(sc.SpanID().String()).To(Equal("0000000000000002"))
+	g.Expect(sc.IsSampled()).To(BeFalse())
+
+	// B3 is injected as multiple headers
+	header := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	g.Expect(header.Get("X-B3-Traceid")).To(Equal("00000000000000010000000000000001"))
 }
 
-func TestInitTracing(t *testing.T) {
-	os.Setenv("JAEGER_TRACE_PROPAGATION_TYPE", "b3")
+func TestInitTracingTraceContext(t *testing.T) {
+	g := NewGomegaWithT(t)
 	closer, err := InitTracing()
-	if err != nil {
-		t.Error("init trace err", err.Error())
-	}
+	g.Expect(err).Should(BeNil())
 	defer closer.Close()
-	if !opentracing.IsGlobalTracerRegistered() {
-		t.Error("trace is not registered")
-	}
-	tracer := opentracing.GlobalTracer()
-	_, err = tracer.Extract(opentracing.HTTPHeaders, nonRootNonSampledHeader)
-	if err != nil {
-		t.Error("trace extract error", err.Error())
-	}
+	g.Expect(IsEnabled()).To(BeFalse())
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(traceparentHeader))
+	sc := trace.SpanContextFromContext(ctx)
+	g.Expect(sc.TraceID().String()).To(Equal("0af7651916cd43dd8448eb211c80319c"))
+	g.Expect(sc.IsSampled()).To(BeTrue())
+}
+
+func TestInitTracingOTLP(t *testing.T) {
+	g := NewGomegaWithT(t)
+	t.Setenv(ENV_OTLP_ENDPOINT, "http://localhost:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "100")
+	closer, err := InitTracing()
+	g.Expect(err).Should(BeNil())
+	defer func() {
+		closer.Close()
+		enabled = false
+	}()
+	g.Expect(IsEnabled()).To(BeTrue())
+
+	_, span := Tracer().Start(context.Background(), "test")
+	defer span.End()
+	g.Expect(span.SpanContext().IsValid()).To(BeTrue())
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	g := NewGomegaWithT(t)
+	carrier := MetadataCarrier{"traceparent": []string{traceparentHeader.Get("Traceparent")}}
+	g.Expect(carrier.Get("Traceparent")).To(Equal(traceparentHeader.Get("Traceparent")))
+	g.Expect(carrier.Get("tracestate")).To(Equal(""))
 }
@@ -141,3 +141,15 @@ func GetEnvAsBool(key string, fallback bool) bool {
 
 	return fallback
 }
+
+// Get an environment variable given by key or return the fallback.
+func GetEnvAsInt(key string, fallback int) int {
+	if raw, ok := os.LookupEnv(key); ok {
+		val, err := strconv.Atoi(raw)
+		if err == nil {
+			return val
+		}
+	}
+
+	return fallback
+}
@@ -18,9 +18,12 @@ import (
 	"time"
 
 	"strconv"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/admin"
+	"github.com/seldonio/seldon-core/executor/api/batch"
 	seldonclient "github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
 	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving"
@@ -29,6 +32,7 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/grpc/tensorflow"
 	"github.com/seldonio/seldon-core/executor/api/kafka"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/rest"
 	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
@@ -54,7 +58,7 @@ const (
 )
 
 var (
-	serverType = flag.String("server_type", "rpc", "Server type: rpc or kafka")
+	serverType = flag.String("server_type", "rpc", "Server type: rpc, kafka or batch")
 
 	debugDefault = false
 
@@ -83,6 +87,11 @@ var (
 	logKafkaBroker    = flag.String("log_kafka_broker", "", "The kafka log broker")
 	logKafkaTopic     = flag.String("log_kafka_topic", "", "The kafka log topic")
 	fullHealthChecks  = flag.Bool("full_health_checks", false, "Full health checks via chosen protocol API")
+	batchInput        = flag.String("batch_input_path", "", "The batch input file or directory of JSONL and CSV files")
+	batchOutput       = flag.String("batch_output_path", "", "The batch output file")
+	batchCheckpoint   = flag.String("batch_checkpoint_path", "", "The batch checkpoint file, defaults to the output file with a .checkpoint suffix")
+	batchWorkers      = flag.Int("batch_workers", 4, "Number of batch workers")
+	batchSize         = flag.Int("batch_size", 1, "Number of batch rows merged into each request where possible")
 	debug             = flag.Bool(
 		"debug",
 		util.GetEnvAsBool(debugEnvVar, debugDefault),
@@ -103,6 +112,23 @@ func getServerUrl(hostname string, port int) (*url.URL, error) {
 	return url.Parse(fmt.Sprintf("http://%s:%d/", hostname, port))
 }
 
+// createClients creates a rest and grpc client for the protocol.
+func createClients(protocol string, deploymentName string, predictor *v1.PredictorSpec, annotations map[string]string) (seldonclient.SeldonApiClient, seldonclient.SeldonApiClient, error) {
+	clientRest, err := rest.NewJSONRestClient(protocol, deploymentName, predictor, annotations)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch protocol {
+	case api.ProtocolSeldon:
+		return clientRest, seldon.NewSeldonGrpcClient(predictor, deploymentName, annotations), nil
+	case api.ProtocolTensorflow:
+		return clientRest, tensorflow.NewTensorflowGrpcClient(predictor, deploymentName, annotations), nil
+	case api.ProtocolV2, api.ProtocolKFServing:
+		return clientRest, kfserving.NewKFServingGrpcClient(predictor, deploymentName, annotations), nil
+	}
+	return nil, nil, fmt.Errorf("unknown protocol %s", protocol)
+}
+
 func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictor *v1.PredictorSpec, client seldonclient.SeldonApiClient, port int, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthChecks bool) {
 	wg.Add(1)
 	defer wg.Done()
@@ -309,6 +335,26 @@ func main() {
 		}
 	}
 
+	if *serverType == "batch" {
+		if *batchInput == "" {
+			*batchInput = os.Getenv(batch.ENV_BATCH_INPUT_PATH)
+			if *batchInput == "" {
+				log.Fatal("Required argument batch_input_path missing")
+			}
+		}
+
+		if *batchOutput == "" {
+			*batchOutput = os.Getenv(batch.ENV_BATCH_OUTPUT_PATH)
+			if *batchOutput == "" {
+				log.Fatal("Required argument batch_output_path missing")
+			}
+		}
+
+		if *batchCheckpoint == "" {
+			*batchCheckpoint = os.Getenv(batch.ENV_BATCH_CHECKPOINT_PATH)
+		}
+	}
+
 	if !(*transport == "rest" || *transport == "grpc") {
 		log.Fatal("Only rest and grpc supported")
 	}
@@ -370,16 +416,56 @@ func main() {
 	//Init Tracing
 	closer, err := tracing.InitTracing()
 	if err != nil {
-		log.Fatal("Could not initialize jaeger tracer", err.Error())
+		log.Fatal("Could not initialize tracer", err.Error())
 	}
 	defer closer.Close()
 
+	// Label the metrics of graph nodes with the deployment and predictor
+	predictor2.SetNodeMetricsPredictor(predictor, *sdepName)
+
+	// Link latency and payload size histograms to traces if asked to
+	metric.SetExemplarsEnabled(annotations[k8s.ANNOTATION_METRICS_EXEMPLARS] == "true")
+
+	// Check requests against the graph's input metadata if asked to
+	validate := annotations[k8s.ANNOTATION_VALIDATE_REQUESTS] == "true"
+	cast := annotations[k8s.ANNOTATION_CAST_REQUESTS] == "true"
+	predictor2.SetRequestValidation(validate, validate && cast)
+
+	// Hold back readiness while the graph's edges are incompatible if asked to
+	predictor2.SetEdgeReadinessGate(annotations[k8s.ANNOTATION_EDGE_READINESS_GATE] == "true")
+
+	// Only send the results of asynchronous predictions to the allowed callback hosts
+	if hosts := annotations[k8s.ANNOTATION_ASYNC_CALLBACK_HOSTS]; hosts != "" {
+		rest.SetAsyncCallbackHosts(strings.Split(hosts, ","))
+	}
+
+	clientRest, clientGrpc, err := createClients(*protocol, *sdepName, predictor, annotations)
+	if err != nil {
+		log.Fatalf("Failed to create clients: %v", err)
+	}
+
+	// Register clients for graph nodes called over another transport or with another protocol
+	predictor2.SetDeploymentProtocol(*protocol)
+	predictor2.RegisterProtocolClient(*protocol, clientRest)
+	predictor2.RegisterProtocolClient(*protocol, clientGrpc)
+	for _, nodeProtocol := range predictor2.NodeProtocols(&predictor.Graph, *protocol) {
+		logger.Info("Translating payloads for nodes", "protocol", nodeProtocol)
+		nodeRest, nodeGrpc, err := createClients(nodeProtocol, *sdepName, predictor, annotations)
+		if err != nil {
+			log.Fatalf("Failed to create clients for protocol %s: %v", nodeProtocol, err)
+		}
+		predictor2.RegisterProtocolClient(nodeProtocol, nodeRest)
+		predictor2.RegisterProtocolClient(nodeProtocol, nodeGrpc)
+	}
+
+	var kafkaConsumer admin.KafkaConsumer
 	if *serverType == "kafka" {
 		logger.Info("Starting kafka server")
 		kafkaServer, err := kafka.NewKafkaServer(*kafkaFullGraph, *kafkaWorkers, *sdepName, *namespace, *protocol, *transport, annotations, serverUrl, predictor, *kafkaBroker, *kafkaTopicIn, *kafkaTopicOut, logger, *fullHealthChecks, *kafkaAutoCommit)
 		if err != nil {
 			log.Fatalf("Failed to create kafka server: %v", err)
 		}
+		kafkaConsumer = kafkaServer
 		go func() {
 			err = kafkaServer.Serve()
 			if err != nil {
@@ -388,21 +474,34 @@ func main() {
 		}()
 	}
 
-	clientRest, err := rest.NewJSONRestClient(*protocol, *sdepName, predictor, annotations)
-	if err != nil {
-		log.Fatalf("Failed to create http client: %v", err)
+	if *serverType == "batch" {
+		logger.Info("Starting batch server")
+		batchServer, err := batch.NewBatchServer(*batchWorkers, *batchSize, *sdepName, *namespace, *protocol, *transport, annotations, serverUrl, predictor, *batchInput, *batchOutput, *batchCheckpoint, logger, *fullHealthChecks)
+		if err != nil {
+			log.Fatalf("Failed to create batch server: %v", err)
+		}
+		// The executor exits once the batch is complete rather than serving requests
+		if err = batchServer.Serve(); err != nil {
+			log.Fatal("Failed to serve batch", err)
+		}
+		return
 	}
 
-	var clientGrpc seldonclient.SeldonApiClient
-	switch *protocol {
-	case api.ProtocolSeldon:
-		clientGrpc = seldon.NewSeldonGrpcClient(predictor, *sdepName, annotations)
-	case api.ProtocolTensorflow:
-		clientGrpc = tensorflow.NewTensorflowGrpcClient(predictor, *sdepName, annotations)
-	case api.ProtocolV2, api.ProtocolKFServing:
-		clientGrpc = kfserving.NewKFServingGrpcClient(predictor, *sdepName, annotations)
-	default:
-		log.Fatalf("Failed to create grpc client. Unknown protocol %s: %v", *protocol, err)
+	// Serve the admin API on its own port if asked to
+	if adminPort := annotations[k8s.ANNOTATION_ADMIN_PORT]; adminPort != "" {
+		runAdminServer(adminPort, logger, predictor, kafkaConsumer)
+	}
+
+	// Reload the graph when the file it is mounted from changes if asked to
+	// The Kafka topics of the graph's nodes are only created at startup when the whole graph runs over Kafka
+	if reloadFile := annotations[k8s.ANNOTATION_GRAPH_RELOAD_FILE]; reloadFile != "" && *serverType == "kafka" && *kafkaFullGraph {
+		logger.Info("Not watching for graph changes as the graph runs over kafka", "file", reloadFile)
+	} else if reloadFile != "" {
+		logger.Info("Watching for graph changes", "file", reloadFile)
+		predictor2.SetActivePredictor(predictor)
+		reloadStop := make(chan struct{})
+		defer close(reloadStop)
+		go predictor2.NewGraphWatcher(predictor.Name, reloadFile, predictor2.DefaultGraphReloadInterval, logger).Watch(reloadStop)
 	}
 
 	wg := sync.WaitGroup{}
@@ -416,6 +515,30 @@ func main() {
 	waitForShutdown(logger, &wg, httpStop, grpcStop)
 }
 
+// runAdminServer starts the admin API in the background. It is not started without a token to authenticate requests.
+func runAdminServer(portAnnotation string, logger logr.Logger, predictor *v1.PredictorSpec, kafkaConsumer admin.KafkaConsumer) {
+	port, err := strconv.Atoi(portAnnotation)
+	if err != nil {
+		logger.Error(err, "Invalid admin port", "port", portAnnotation)
+		return
+	}
+	token := os.Getenv(admin.ENV_ADMIN_TOKEN)
+	if token == "" {
+		logger.Info("Admin API not started as no token is set", "env", admin.ENV_ADMIN_TOKEN)
+		return
+	}
+	adminApi := admin.NewAdminApi(predictor, token, kafkaConsumer)
+	adminApi.Initialise()
+	srv := adminApi.CreateHttpServer(port)
+	lis := createListener(port, logger)
+	go func() {
+		logger.Info("Running admin server", "port", port)
+		if err := srv.Serve(lis); err != nil {
+			logger.Error(err, "admin server error")
+		}
+	}()
+}
+
 func createListener(port int, logger logr.Logger) net.Listener {
 	// Create a listener at the desired port.
 	var lis net.Listener
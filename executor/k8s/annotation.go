@@ -12,6 +12,13 @@ const (
 	ANNOTATION_GRPC_MAX_MESSAGE_SIZE = "seldon.io/grpc-max-message-size"
 	ANNOTATION_GRPC_TIMEOUT          = "seldon.io/grpc-timeout"
 	ANNOTATION_REST_TIMEOUT          = "seldon.io/rest-timeout"
+	ANNOTATION_VALIDATE_REQUESTS     = "seldon.io/validate-requests"
+	ANNOTATION_CAST_REQUESTS         = "seldon.io/cast-requests"
+	ANNOTATION_EDGE_READINESS_GATE   = "seldon.io/edge-readiness-gate"
+	ANNOTATION_GRAPH_RELOAD_FILE     = "seldon.io/graph-reload-file"
+	ANNOTATION_ADMIN_PORT            = "seldon.io/admin-port"
+	ANNOTATION_METRICS_EXEMPLARS     = "seldon.io/metrics-exemplars"
+	ANNOTATION_ASYNC_CALLBACK_HOSTS  = "seldon.io/async-callback-hosts"
 )
 
 func trimQuotes(v string) string {
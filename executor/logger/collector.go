@@ -2,6 +2,7 @@ package logger
 
 import (
 	"errors"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,8 +17,26 @@ var (
 	workQueue = make(chan LogRequest, DefaultWorkQueueSize)
 	// writeTimeoutMilliseconds is the timeout for waiting for work to be written to the queue. If 0, will not wait if buffer is full.
 	writeTimeoutMilliseconds = DefaultWriteTimeoutMilliseconds
+	// droppedLogs counts the log requests not queued as the buffer stayed full until the timeout.
+	droppedLogs int64
 )
 
+// QueueStats describes the log requests waiting to be sent and those dropped since the executor started.
+type QueueStats struct {
+	Depth    int   `json:"depth"`
+	Capacity int   `json:"capacity"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// GetQueueStats returns the current depth of the log request queue and the number of log requests dropped.
+func GetQueueStats() QueueStats {
+	return QueueStats{
+		Depth:    len(workQueue),
+		Capacity: cap(workQueue),
+		Dropped:  atomic.LoadInt64(&droppedLogs),
+	}
+}
+
 func QueueLogRequest(req LogRequest) error {
 	timer := time.NewTimer(time.Duration(writeTimeoutMilliseconds) * time.Millisecond)
 	defer timer.Stop()
@@ -25,6 +44,7 @@ func QueueLogRequest(req LogRequest) error {
 	case workQueue <- req:
 		return nil
 	case <-timer.C:
+		atomic.AddInt64(&droppedLogs, 1)
 		return errors.New("timed out waiting to queue log request: buffer is full")
 	}
 }
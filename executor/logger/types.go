@@ -10,6 +10,8 @@ const (
 	InferenceRequest  LogRequestType = "Request"
 	InferenceResponse LogRequestType = "Response"
 	InferenceFeedback LogRequestType = "Feedback"
+	// The result of an asynchronous prediction sent to the callback URL given with the request.
+	InferenceCallback LogRequestType = "Callback"
 )
 
 type LogRequest struct {
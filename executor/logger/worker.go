@@ -19,6 +19,7 @@ const (
 	CEInferenceRequest  = "io.seldon.serving.inference.request"
 	CEInferenceResponse = "io.seldon.serving.inference.response"
 	CEFeedback          = "io.seldon.serving.feedback"
+	CEInferenceCallback = "io.seldon.serving.inference.callback"
 	// cloud events extension attributes have to be lowercase alphanumeric
 	RequestIdAttr            = "requestid"
 	ModelIdAttr              = "modelid"
@@ -100,6 +101,8 @@ func getCEType(logReq LogRequest) (string, error) {
 		return CEInferenceResponse, nil
 	case InferenceFeedback:
 		return CEFeedback, nil
+	case InferenceCallback:
+		return CEInferenceCallback, nil
 	default:
 		return "", fmt.Errorf("Incorrect log request type: %s", errors.New("Incorrect log request type"))
 	}
@@ -205,7 +208,8 @@ func (w *Worker) Start() {
 			case work := <-w.Work:
 				// Receive a work request.
 
-				if w.KafkaTopic != "" {
+				// Callbacks always go to the URL given with the request
+				if w.KafkaTopic != "" && work.ReqType != InferenceCallback {
 					if err := w.sendKafkaEvent(work); err != nil {
 						w.Log.Error(err, "Failed to send kafka log", "Topic", w.KafkaTopic)
 					}
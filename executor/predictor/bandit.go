@@ -0,0 +1,232 @@
+package predictor
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const (
+	banditEpsilonParameter = "epsilon"
+	banditDefaultEpsilon   = 0.1
+	// Number of PUID to route decisions remembered per bandit so feedback can be attributed to the right arm.
+	banditRouteHistorySize = 10000
+)
+
+// bandit holds the arm statistics of a multi-armed bandit router.
+type bandit struct {
+	mu      sync.Mutex
+	pulls   []float64
+	counts  []float64
+	rewards []float64
+	// Route history keyed by PUID with oldest first eviction.
+	routes     map[string]int
+	routeOrder []string
+	routeNext  int
+	metrics    *metric.BanditMetrics
+}
+
+var bandits = newNodeStates[bandit]()
+
+func isBandit(node *v1.PredictiveUnit) bool {
+	return hasImplementation(node, v1.EPSILON_GREEDY) || hasImplementation(node, v1.THOMPSON_SAMPLING)
+}
+
+func getBandit(node *v1.PredictiveUnit) *bandit {
+	arms := len(node.Children)
+	// The statistics start again if the number of children changes
+	return bandits.getValid(node.Name, func(b *bandit) bool {
+		return len(b.pulls) == arms
+	}, func() *bandit {
+		return &bandit{
+			pulls:      make([]float64, arms),
+			counts:     make([]float64, arms),
+			rewards:    make([]float64, arms),
+			routes:     make(map[string]int),
+			routeOrder: make([]string, banditRouteHistorySize),
+			metrics:    metric.NewBanditMetrics(),
+		}
+	})
+}
+
+// value is the estimated reward of an arm, the mean reward for epsilon greedy and the Beta posterior mean for
+// Thompson sampling.
+func (b *bandit) value(implementation v1.PredictiveUnitImplementation, arm int) float64 {
+	if implementation == v1.THOMPSON_SAMPLING {
+		return (1 + b.rewards[arm]) / (2 + b.counts[arm])
+	}
+	if b.counts[arm] == 0 {
+		return 0
+	}
+	return b.rewards[arm] / b.counts[arm]
+}
+
+func (b *bandit) updateMetrics(nodeName string, implementation v1.PredictiveUnitImplementation, arm int) {
+	b.metrics.SetArm(nodeName, arm, b.pulls[arm], b.rewards[arm], b.value(implementation, arm))
+}
+
+func (b *bandit) recordRoute(puid string, arm int) {
+	if puid == "" {
+		return
+	}
+	if _, ok := b.routes[puid]; !ok {
+		if old := b.routeOrder[b.routeNext]; old != "" {
+			delete(b.routes, old)
+		}
+		b.routeOrder[b.routeNext] = puid
+		b.routeNext = (b.routeNext + 1) % len(b.routeOrder)
+	}
+	b.routes[puid] = arm
+}
+
+func (b *bandit) chooseEpsilonGreedy(epsilon float64) int {
+	if rand.Float64() < epsilon {
+		return rand.Intn(len(b.pulls))
+	}
+	best := 0
+	for arm := range b.pulls {
+		if b.value(v1.EPSILON_GREEDY, arm) > b.value(v1.EPSILON_GREEDY, best) {
+			best = arm
+		}
+	}
+	return best
+}
+
+func (b *bandit) chooseThompsonSampling() int {
+	best := 0
+	bestSample := -1.0
+	for arm := range b.pulls {
+		sample := sampleBeta(1+b.rewards[arm], 1+b.counts[arm]-b.rewards[arm])
+		if sample > bestSample {
+			best = arm
+			bestSample = sample
+		}
+	}
+	return best
+}
+
+func (p *PredictorProcess) banditRouter(node *v1.PredictiveUnit) (int, error) {
+	if len(node.Children) == 0 {
+		return 0, fmt.Errorf("%s router %s has no children", *node.Implementation, node.Name)
+	}
+	epsilon := banditDefaultEpsilon
+	if val, ok := getParameter(node, banditEpsilonParameter); ok {
+		var err error
+		epsilon, err = strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, err
+		}
+		if epsilon < 0 || epsilon > 1 {
+			return 0, fmt.Errorf("%s router %s has %s %v outside [0,1]", *node.Implementation, node.Name, banditEpsilonParameter, epsilon)
+		}
+	}
+
+	b := getBandit(node)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var arm int
+	if *node.Implementation == v1.THOMPSON_SAMPLING {
+		arm = b.chooseThompsonSampling()
+	} else {
+		arm = b.chooseEpsilonGreedy(epsilon)
+	}
+	b.pulls[arm]++
+	puid, _ := p.getPUIDHeader()
+	b.recordRoute(puid, arm)
+	b.updateMetrics(node.Name, *node.Implementation, arm)
+	return arm, nil
+}
+
+// banditRouteFeedback returns the route a bandit took for the request being given feedback, or -1 if unknown. The
+// request is found by the PUID in the meta of the feedback's response, as feedback requests usually get a PUID of
+// their own, and then by the PUID of the feedback request.
+func (p *PredictorProcess) banditRouteFeedback(node *v1.PredictiveUnit, fb *proto.Feedback) int {
+	puid := fb.GetResponse().GetMeta().GetPuid()
+	if puid == "" {
+		puid, _ = p.getPUIDHeader()
+	}
+	b := getBandit(node)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if arm, ok := b.routes[puid]; ok {
+		return arm
+	}
+	if route, ok := fb.GetResponse().GetMeta().GetRouting()[node.Name]; ok && int(route) < len(b.pulls) {
+		return int(route)
+	}
+	return -1
+}
+
+// banditFeedback updates the arm statistics with the reward of a feedback request.
+func (p *PredictorProcess) banditFeedback(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	fb, err := feedbackFromPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	arm := p.banditRouteFeedback(node, fb)
+	if arm < 0 {
+		p.Log.Info("Ignoring feedback with unknown route", "node", node.Name)
+		return msg, nil
+	}
+	reward := math.Min(math.Max(float64(fb.GetReward()), 0), 1)
+
+	b := getBandit(node)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.counts[arm]++
+	b.rewards[arm] += reward
+	b.updateMetrics(node.Name, *node.Implementation, arm)
+	return msg, nil
+}
+
+func feedbackFromPayload(msg payload.SeldonPayload) (*proto.Feedback, error) {
+	if fb, ok := msg.GetPayload().(*proto.Feedback); ok {
+		return fb, nil
+	}
+	data, err := payload.DecompressSeldonPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	var fb proto.Feedback
+	err = jsonpb.UnmarshalString(string(data), &fb)
+	if err != nil {
+		return nil, fmt.Errorf("payload is not a Feedback message: %w", err)
+	}
+	return &fb, nil
+}
+
+// sampleBeta draws from Beta(alpha, beta) using two Gamma samples.
+func sampleBeta(alpha float64, beta float64) float64 {
+	x := sampleGamma(alpha)
+	y := sampleGamma(beta)
+	return x / (x + y)
+}
+
+// sampleGamma draws from Gamma(shape, 1) with the Marsaglia and Tsang method.
+func sampleGamma(shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(shape+1) * math.Pow(rand.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rand.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rand.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
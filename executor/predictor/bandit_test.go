@@ -0,0 +1,145 @@
+package predictor
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createPredictorProcessWithPUID(t *testing.T, puid string) *PredictorProcess {
+	url, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, puid)
+	pp := NewPredictorProcess(ctx, &test.SeldonMessageTestClient{}, logf.Log.WithName("SeldonMessageRestClient"), url, "default", map[string][]string{}, "")
+	return &pp
+}
+
+func resetBandit(name string) {
+	bandits.delete(name)
+}
+
+func createBanditGraph(name string, implementation v1.PredictiveUnitImplementation, params ...v1.Parameter) *v1.PredictiveUnit {
+	model := v1.MODEL
+	return &v1.PredictiveUnit{
+		Name:           name,
+		Implementation: &implementation,
+		Parameters:     params,
+		Children: []v1.PredictiveUnit{
+			{Name: "a", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000, Type: v1.REST}},
+			{Name: "b", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo2", ServicePort: 9001, Type: v1.REST}},
+			{Name: "c", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo3", ServicePort: 9002, Type: v1.REST}},
+		},
+	}
+}
+
+func createBanditFeedbackPayload(g *GomegaWithT, data string) payload.SeldonPayload {
+	var fb proto.Feedback
+	err := jsonpb.UnmarshalString(data, &fb)
+	g.Expect(err).Should(BeNil())
+	return &payload.ProtoPayload{Msg: &fb}
+}
+
+func TestEpsilonGreedyRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandit("eg-router")
+	graph := createBanditGraph("eg-router", v1.EPSILON_GREEDY, v1.Parameter{Name: "epsilon", Value: "0", Type: v1.FLOAT})
+
+	// All arms are equal so the first is chosen and remembered for the PUID
+	pp := createPredictorProcessWithPUID(t, "puid-1")
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing["eg-router"]).Should(Equal(int32(0)))
+
+	// Feedback for the PUID is attributed to the remembered route
+	_, err = pp.Feedback(graph, createBanditFeedbackPayload(g, `{"reward":0.0}`))
+	g.Expect(err).Should(BeNil())
+
+	// Feedback with a PUID of its own is attributed by the PUID in the response meta
+	pp = createPredictorProcessWithPUID(t, "puid-feedback")
+	_, err = pp.Feedback(graph, createBanditFeedbackPayload(g, `{"reward":0.0,"response":{"meta":{"puid":"puid-1","routing":{"eg-router":1}}}}`))
+	g.Expect(err).Should(BeNil())
+
+	// Unknown PUID falls back to the routing in the response meta
+	pp = createPredictorProcessWithPUID(t, "puid-2")
+	_, err = pp.Feedback(graph, createBanditFeedbackPayload(g, `{"reward":1.0,"response":{"meta":{"routing":{"eg-router":2}}}}`))
+	g.Expect(err).Should(BeNil())
+
+	pp = createPredictorProcessWithPUID(t, "puid-3")
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing["eg-router"]).Should(Equal(int32(2)))
+
+	b := getBandit(graph)
+	g.Expect(b.counts).Should(Equal([]float64{2, 0, 1}))
+	g.Expect(b.rewards).Should(Equal([]float64{0, 0, 1}))
+	g.Expect(testutil.ToFloat64(b.metrics.ArmPulls.WithLabelValues("eg-router", "2"))).Should(Equal(1.0))
+	g.Expect(testutil.ToFloat64(b.metrics.ArmValue.WithLabelValues("eg-router", "2"))).Should(Equal(1.0))
+}
+
+func TestEpsilonGreedyRouterInvalidEpsilon(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandit("eg-invalid")
+	graph := createBanditGraph("eg-invalid", v1.EPSILON_GREEDY, v1.Parameter{Name: "epsilon", Value: "1.5", Type: v1.FLOAT})
+
+	pp := createPredictorProcessWithPUID(t, "puid-invalid")
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).To(MatchError("EPSILON_GREEDY router eg-invalid has epsilon 1.5 outside [0,1]"))
+}
+
+func TestThompsonSamplingRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandit("ts-router")
+	graph := createBanditGraph("ts-router", v1.THOMPSON_SAMPLING)
+
+	pp := createPredictorProcessWithPUID(t, "")
+	for i := 0; i < 50; i++ {
+		_, err := pp.Feedback(graph, createBanditFeedbackPayload(g, `{"reward":1.0,"response":{"meta":{"routing":{"ts-router":1}}}}`))
+		g.Expect(err).Should(BeNil())
+		_, err = pp.Feedback(graph, createBanditFeedbackPayload(g, `{"reward":0.0,"response":{"meta":{"routing":{"ts-router":0}}}}`))
+		g.Expect(err).Should(BeNil())
+		_, err = pp.Feedback(graph, createBanditFeedbackPayload(g, `{"reward":0.0,"response":{"meta":{"routing":{"ts-router":2}}}}`))
+		g.Expect(err).Should(BeNil())
+	}
+
+	chosen := 0
+	for i := 0; i < 100; i++ {
+		route, err := pp.banditRouter(graph)
+		g.Expect(err).Should(BeNil())
+		if route == 1 {
+			chosen++
+		}
+	}
+	g.Expect(chosen).Should(BeNumerically(">", 90))
+}
+
+func TestBanditRouteHistory(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetBandit("history-router")
+	b := getBandit(createBanditGraph("history-router", v1.EPSILON_GREEDY))
+	for i := 0; i < banditRouteHistorySize+10; i++ {
+		b.recordRoute(string(rune(i)), i%3)
+	}
+	g.Expect(b.routes).Should(HaveLen(banditRouteHistorySize))
+	_, ok := b.routes[string(rune(0))]
+	g.Expect(ok).Should(BeFalse())
+}
+
+func TestSampleBeta(t *testing.T) {
+	g := NewGomegaWithT(t)
+	total := 0.0
+	for i := 0; i < 10000; i++ {
+		sample := sampleBeta(2, 6)
+		g.Expect(sample).Should(BeNumerically(">=", 0))
+		g.Expect(sample).Should(BeNumerically("<=", 1))
+		total += sample
+	}
+	g.Expect(total / 10000).Should(BeNumerically("~", 0.25, 0.02))
+}
@@ -0,0 +1,344 @@
+package predictor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	protoV1 "github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// batchItem is a prediction request that can be merged with other requests along its first dimension. Only requests
+// with the same signature, which covers the data type and the shape after the first dimension, are merged.
+type batchItem struct {
+	signature string
+	rows      int
+	seldon    *proto.SeldonMessage
+	v2        *inference.ModelInferRequest
+	// Content type of Seldon protocol requests sent as JSON over REST.
+	jsonContentType string
+}
+
+// newBatchItem returns false for requests that can not be batched, such as string, binary or JSON data.
+func newBatchItem(msg payload.SeldonPayload) (*batchItem, bool) {
+	switch v := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		return newSeldonBatchItem(v, "")
+	case *inference.ModelInferRequest:
+		return newV2BatchItem(v)
+	case []byte:
+		if msg.GetContentEncoding() != "" {
+			return nil, false
+		}
+		var sm proto.SeldonMessage
+		if err := jsonpb.UnmarshalString(string(v), &sm); err != nil {
+			return nil, false
+		}
+		return newSeldonBatchItem(&sm, msg.GetContentType())
+	}
+	return nil, false
+}
+
+func newSeldonBatchItem(sm *proto.SeldonMessage, jsonContentType string) (*batchItem, bool) {
+	// Tags and routing can not be merged across requests
+	if meta := sm.GetMeta(); meta != nil && (len(meta.Tags) > 0 || len(meta.Routing) > 0 || len(meta.RequestPath) > 0) {
+		return nil, false
+	}
+	data := sm.GetData()
+	if data == nil {
+		return nil, false
+	}
+	item := &batchItem{seldon: sm, jsonContentType: jsonContentType}
+	names := strings.Join(data.Names, ",")
+	if ndarray := data.GetNdarray(); ndarray != nil {
+		item.rows = len(ndarray.Values)
+		item.signature = fmt.Sprintf("seldon:%s:ndarray:%s:%v", jsonContentType, names, ndarrayShape(ndarray)[1:])
+	} else if tensor := data.GetTensor(); tensor != nil {
+		if len(tensor.Shape) == 0 || int(product32(tensor.Shape)) != len(tensor.Values) {
+			return nil, false
+		}
+		item.rows = int(tensor.Shape[0])
+		item.signature = fmt.Sprintf("seldon:%s:tensor:%s:%v", jsonContentType, names, tensor.Shape[1:])
+	} else {
+		return nil, false
+	}
+	return item, item.rows > 0
+}
+
+func newV2BatchItem(req *inference.ModelInferRequest) (*batchItem, bool) {
+	if len(req.Inputs) == 0 || len(req.Parameters) > 0 {
+		return nil, false
+	}
+	item := &batchItem{v2: req}
+	var sig strings.Builder
+	sig.WriteString("v2:")
+	for _, input := range req.Inputs {
+		if len(input.Shape) == 0 || input.Contents == nil || len(input.Parameters) > 0 {
+			return nil, false
+		}
+		if int64(contentsLen(input.Contents)) != product64(input.Shape) {
+			return nil, false
+		}
+		if input == req.Inputs[0] {
+			item.rows = int(input.Shape[0])
+		} else if int(input.Shape[0]) != item.rows {
+			return nil, false
+		}
+		fmt.Fprintf(&sig, "%s:%s:%v;", input.Name, input.Datatype, input.Shape[1:])
+	}
+	for _, output := range req.Outputs {
+		fmt.Fprintf(&sig, "%s;", output.Name)
+	}
+	item.signature = sig.String()
+	return item, item.rows > 0
+}
+
+// MergePayloads merges requests into one request along their first dimension, for callers that collect their own
+// batches rather than relying on a node's batching policy. split divides the response to the merged request into a
+// response for each request. It returns false if the requests can not be merged.
+func MergePayloads(msgs []payload.SeldonPayload) (merged payload.SeldonPayload, split func(res payload.SeldonPayload) ([]payload.SeldonPayload, error), ok bool) {
+	if len(msgs) == 0 {
+		return nil, nil, false
+	}
+	items := make([]*batchItem, len(msgs))
+	for i, msg := range msgs {
+		item, ok := newBatchItem(msg)
+		if !ok || (i > 0 && item.signature != items[0].signature) {
+			return nil, nil, false
+		}
+		items[i] = item
+	}
+	merged, err := mergeBatch(items)
+	if err != nil {
+		return nil, nil, false
+	}
+	return merged, func(res payload.SeldonPayload) ([]payload.SeldonPayload, error) {
+		return splitBatch(res, items)
+	}, true
+}
+
+// mergeBatch merges requests with the same signature into one request.
+func mergeBatch(items []*batchItem) (payload.SeldonPayload, error) {
+	if items[0].v2 != nil {
+		return mergeV2Batch(items), nil
+	}
+
+	first := items[0].seldon
+	data := &proto.DefaultData{Names: first.GetData().Names}
+	if first.GetData().GetNdarray() != nil {
+		ndarray := &structpb.ListValue{}
+		for _, item := range items {
+			ndarray.Values = append(ndarray.Values, item.seldon.GetData().GetNdarray().Values...)
+		}
+		data.DataOneof = &proto.DefaultData_Ndarray{Ndarray: ndarray}
+	} else {
+		tensor := &proto.Tensor{Shape: append([]int32{0}, first.GetData().GetTensor().Shape[1:]...)}
+		for _, item := range items {
+			tensor.Shape[0] += int32(item.rows)
+			tensor.Values = append(tensor.Values, item.seldon.GetData().GetTensor().Values...)
+		}
+		data.DataOneof = &proto.DefaultData_Tensor{Tensor: tensor}
+	}
+	// The meta of each request, such as its PUID, is restored on its part of the response
+	merged := &proto.SeldonMessage{DataOneof: &proto.SeldonMessage_Data{Data: data}}
+	return seldonBatchPayload(merged, items[0].jsonContentType)
+}
+
+func mergeV2Batch(items []*batchItem) payload.SeldonPayload {
+	first := items[0].v2
+	merged := &inference.ModelInferRequest{
+		ModelName:    first.ModelName,
+		ModelVersion: first.ModelVersion,
+		Id:           first.Id,
+		Outputs:      first.Outputs,
+	}
+	for i, input := range first.Inputs {
+		mergedInput := &inference.ModelInferRequest_InferInputTensor{
+			Name:     input.Name,
+			Datatype: input.Datatype,
+			Shape:    append([]int64{0}, input.Shape[1:]...),
+			Contents: &inference.InferTensorContents{},
+		}
+		for _, item := range items {
+			itemInput := item.v2.Inputs[i]
+			mergedInput.Shape[0] += itemInput.Shape[0]
+			appendContents(mergedInput.Contents, itemInput.Contents)
+		}
+		merged.Inputs = append(merged.Inputs, mergedInput)
+	}
+	return &payload.ProtoPayload{Msg: merged}
+}
+
+// splitBatch splits the response to a merged request into a response for each request in the batch.
+func splitBatch(msg payload.SeldonPayload, items []*batchItem) ([]payload.SeldonPayload, error) {
+	rows := make([]int, len(items))
+	total := 0
+	for i, item := range items {
+		rows[i] = item.rows
+		total += item.rows
+	}
+
+	switch v := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		return splitSeldonBatch(v, items, rows, total, "")
+	case *inference.ModelInferResponse:
+		return splitV2Batch(v, items, rows, total)
+	case []byte:
+		data, err := payload.DecompressSeldonPayload(msg)
+		if err != nil {
+			return nil, err
+		}
+		var sm proto.SeldonMessage
+		if err := jsonpb.UnmarshalString(string(data), &sm); err != nil {
+			return nil, fmt.Errorf("can not split batched response: %w", err)
+		}
+		return splitSeldonBatch(&sm, items, rows, total, msg.GetContentType())
+	}
+	return nil, fmt.Errorf("can not split batched response of type %T", msg.GetPayload())
+}
+
+func splitSeldonBatch(sm *proto.SeldonMessage, items []*batchItem, rows []int, total int, jsonContentType string) ([]payload.SeldonPayload, error) {
+	data := sm.GetData()
+	if data == nil {
+		return nil, fmt.Errorf("can not split batched response without ndarray or tensor data")
+	}
+	parts := make([]payload.SeldonPayload, len(rows))
+	start := 0
+	for i, n := range rows {
+		partData := &proto.DefaultData{Names: data.Names}
+		if ndarray := data.GetNdarray(); ndarray != nil {
+			if len(ndarray.Values) != total {
+				return nil, fmt.Errorf("batched response has %d rows but the batch has %d", len(ndarray.Values), total)
+			}
+			partData.DataOneof = &proto.DefaultData_Ndarray{Ndarray: &structpb.ListValue{Values: ndarray.Values[start : start+n]}}
+		} else if tensor := data.GetTensor(); tensor != nil {
+			if len(tensor.Shape) == 0 || int(tensor.Shape[0]) != total || int(product32(tensor.Shape)) != len(tensor.Values) {
+				return nil, fmt.Errorf("batched response has tensor shape %v but the batch has %d rows", tensor.Shape, total)
+			}
+			rowSize := len(tensor.Values) / total
+			partData.DataOneof = &proto.DefaultData_Tensor{Tensor: &proto.Tensor{
+				Shape:  append([]int32{int32(n)}, tensor.Shape[1:]...),
+				Values: tensor.Values[start*rowSize : (start+n)*rowSize],
+			}}
+		} else {
+			return nil, fmt.Errorf("can not split batched response without ndarray or tensor data")
+		}
+		part := &proto.SeldonMessage{Status: sm.Status, DataOneof: &proto.SeldonMessage_Data{Data: partData}}
+		if sm.Meta != nil {
+			part.Meta = protoV1.Clone(sm.Meta).(*proto.Meta)
+		}
+		if meta := items[i].seldon.GetMeta(); meta != nil {
+			if part.Meta == nil {
+				part.Meta = &proto.Meta{}
+			}
+			protoV1.Merge(part.Meta, meta)
+		}
+		var err error
+		parts[i], err = seldonBatchPayload(part, jsonContentType)
+		if err != nil {
+			return nil, err
+		}
+		start += n
+	}
+	return parts, nil
+}
+
+func splitV2Batch(resp *inference.ModelInferResponse, items []*batchItem, rows []int, total int) ([]payload.SeldonPayload, error) {
+	parts := make([]payload.SeldonPayload, len(rows))
+	for i := range parts {
+		parts[i] = &payload.ProtoPayload{Msg: &inference.ModelInferResponse{
+			ModelName:    resp.ModelName,
+			ModelVersion: resp.ModelVersion,
+			Id:           items[i].v2.Id,
+			Parameters:   resp.Parameters,
+		}}
+	}
+	for _, output := range resp.Outputs {
+		if len(output.Shape) == 0 || int(output.Shape[0]) != total || output.Contents == nil ||
+			int64(contentsLen(output.Contents)) != product64(output.Shape) {
+			return nil, fmt.Errorf("batched response output %s has shape %v but the batch has %d rows", output.Name, output.Shape, total)
+		}
+		rowSize := contentsLen(output.Contents) / total
+		start := 0
+		for i, n := range rows {
+			part := parts[i].GetPayload().(*inference.ModelInferResponse)
+			part.Outputs = append(part.Outputs, &inference.ModelInferResponse_InferOutputTensor{
+				Name:       output.Name,
+				Datatype:   output.Datatype,
+				Shape:      append([]int64{int64(n)}, output.Shape[1:]...),
+				Parameters: output.Parameters,
+				Contents:   sliceContents(output.Contents, start*rowSize, (start+n)*rowSize),
+			})
+			start += n
+		}
+	}
+	return parts, nil
+}
+
+// seldonBatchPayload wraps a message as protobuf or, for requests received as JSON, as JSON bytes.
+func seldonBatchPayload(sm *proto.SeldonMessage, jsonContentType string) (payload.SeldonPayload, error) {
+	if jsonContentType == "" {
+		return &payload.ProtoPayload{Msg: sm}, nil
+	}
+	ma := jsonpb.Marshaler{}
+	data, err := ma.MarshalToString(sm)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.BytesPayload{Msg: []byte(data), ContentType: jsonContentType}, nil
+}
+
+func appendContents(dst *inference.InferTensorContents, src *inference.InferTensorContents) {
+	dst.BoolContents = append(dst.BoolContents, src.BoolContents...)
+	dst.IntContents = append(dst.IntContents, src.IntContents...)
+	dst.Int64Contents = append(dst.Int64Contents, src.Int64Contents...)
+	dst.UintContents = append(dst.UintContents, src.UintContents...)
+	dst.Uint64Contents = append(dst.Uint64Contents, src.Uint64Contents...)
+	dst.Fp32Contents = append(dst.Fp32Contents, src.Fp32Contents...)
+	dst.Fp64Contents = append(dst.Fp64Contents, src.Fp64Contents...)
+}
+
+// sliceContents takes elements start to end of a tensor. Only one of the content fields of a tensor is set.
+func sliceContents(c *inference.InferTensorContents, start int, end int) *inference.InferTensorContents {
+	return &inference.InferTensorContents{
+		BoolContents:   sliceOrNil(c.BoolContents, start, end),
+		IntContents:    sliceOrNil(c.IntContents, start, end),
+		Int64Contents:  sliceOrNil(c.Int64Contents, start, end),
+		UintContents:   sliceOrNil(c.UintContents, start, end),
+		Uint64Contents: sliceOrNil(c.Uint64Contents, start, end),
+		Fp32Contents:   sliceOrNil(c.Fp32Contents, start, end),
+		Fp64Contents:   sliceOrNil(c.Fp64Contents, start, end),
+	}
+}
+
+func sliceOrNil[T any](s []T, start int, end int) []T {
+	if len(s) == 0 {
+		return nil
+	}
+	return s[start:end]
+}
+
+func contentsLen(c *inference.InferTensorContents) int {
+	return len(c.BoolContents) + len(c.IntContents) + len(c.Int64Contents) + len(c.UintContents) +
+		len(c.Uint64Contents) + len(c.Fp32Contents) + len(c.Fp64Contents)
+}
+
+func product32(shape []int32) int64 {
+	n := int64(1)
+	for _, d := range shape {
+		n *= int64(d)
+	}
+	return n
+}
+
+func product64(shape []int64) int64 {
+	n := int64(1)
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
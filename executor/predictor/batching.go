@@ -0,0 +1,177 @@
+package predictor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const batchingDefaultMaxLatencyMs = 10
+
+type batchResult struct {
+	msg payload.SeldonPayload
+	err error
+}
+
+// batchCall is a prediction request waiting for its batch to be sent.
+type batchCall struct {
+	p        *PredictorProcess
+	msg      payload.SeldonPayload
+	item     *batchItem
+	queuedAt time.Time
+	result   chan batchResult
+}
+
+// nodeBatcher collects concurrent prediction requests to a node until the batch is full or the oldest request has
+// waited for the maximum latency.
+type nodeBatcher struct {
+	mu      sync.Mutex
+	pending []*batchCall
+	rows    int
+	// Incremented for each batch sent so a timer does not send a later batch.
+	batchId int
+}
+
+var nodeBatchers = newNodeStates[nodeBatcher]()
+
+func getNodeBatcher(node *v1.PredictiveUnit) *nodeBatcher {
+	return nodeBatchers.get(node.Name, func() *nodeBatcher {
+		return &nodeBatcher{}
+	})
+}
+
+// batchedPredict calls the node's predict endpoint, merging the request with concurrent requests if the node has a
+// batching policy. Requests that can not be merged are sent on their own.
+func (p *PredictorProcess) batchedPredict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	if node.Batching == nil {
+		return p.predictNode(node, msg)
+	}
+	item, ok := newBatchItem(msg)
+	if !ok {
+		return p.predictNode(node, msg)
+	}
+
+	call := &batchCall{p: p, msg: msg, item: item, queuedAt: time.Now(), result: make(chan batchResult, 1)}
+	getNodeBatcher(node).add(node, call)
+	select {
+	case r := <-call.result:
+		return r.msg, r.err
+	case <-p.Ctx.Done():
+		return nil, p.Ctx.Err()
+	}
+}
+
+func (b *nodeBatcher) add(node *v1.PredictiveUnit, call *batchCall) {
+	maxBatchSize := int(node.Batching.MaxBatchSize)
+	maxLatency := time.Duration(node.Batching.MaxLatencyMs) * time.Millisecond
+	if node.Batching.MaxLatencyMs == 0 {
+		maxLatency = batchingDefaultMaxLatencyMs * time.Millisecond
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) > 0 && (b.pending[0].item.signature != call.item.signature || b.rows+call.item.rows > maxBatchSize) {
+		b.send(node)
+	}
+	b.pending = append(b.pending, call)
+	b.rows += call.item.rows
+	if b.rows >= maxBatchSize {
+		b.send(node)
+	} else if len(b.pending) == 1 {
+		batchId := b.batchId
+		time.AfterFunc(maxLatency, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.batchId == batchId {
+				b.send(node)
+			}
+		})
+	}
+}
+
+// send sends the pending batch. It must be called with the lock held.
+func (b *nodeBatcher) send(node *v1.PredictiveUnit) {
+	calls := b.pending
+	b.pending = nil
+	b.rows = 0
+	b.batchId++
+	go runBatch(node, calls)
+}
+
+func runBatch(node *v1.PredictiveUnit, calls []*batchCall) {
+	stats := getModelStatistics(calls[0].p.getModelName(node))
+	for _, call := range calls {
+		stats.recordQueue(time.Since(call.queuedAt))
+	}
+
+	if len(calls) == 1 {
+		res, err := calls[0].p.predictNode(node, calls[0].msg)
+		calls[0].result <- batchResult{msg: res, err: err}
+		return
+	}
+
+	items := make([]*batchItem, len(calls))
+	for i, call := range calls {
+		items[i] = call.item
+	}
+	merged, err := mergeBatch(items)
+	if err != nil {
+		failBatch(calls, nil, err)
+		return
+	}
+
+	// The batch is sent with the headers of its first request
+	ctx, cancel := batchContext(calls)
+	defer cancel()
+	p := *calls[0].p
+	p.Ctx = ctx
+	p.Log.V(1).Info("Sending batch", "node", node.Name, "requests", len(calls))
+	res, err := p.predictNode(node, merged)
+	if err != nil {
+		failBatch(calls, res, err)
+		return
+	}
+	parts, err := splitBatch(res, items)
+	if err != nil {
+		failBatch(calls, nil, err)
+		return
+	}
+	for i, call := range calls {
+		call.result <- batchResult{msg: parts[i]}
+	}
+}
+
+func failBatch(calls []*batchCall, msg payload.SeldonPayload, err error) {
+	for _, call := range calls {
+		call.result <- batchResult{msg: msg, err: err}
+	}
+}
+
+// batchContext is only cancelled once every request in the batch has been cancelled. It keeps the request id and
+// trace span of the first request.
+func batchContext(calls []*batchCall) (context.Context, context.CancelFunc) {
+	first := calls[0].p.Ctx
+	ctx := context.Background()
+	if puid := first.Value(payload.SeldonPUIDHeader); puid != nil {
+		ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
+	}
+	if span := trace.SpanFromContext(first); span.SpanContext().IsValid() {
+		ctx = trace.ContextWithSpan(ctx, span)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		for _, call := range calls {
+			select {
+			case <-call.p.Ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,201 @@
+package predictor
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func createSeldonPayload(g *GomegaWithT, data string) payload.SeldonPayload {
+	var sm proto.SeldonMessage
+	err := jsonpb.UnmarshalString(data, &sm)
+	g.Expect(err).Should(BeNil())
+	return &payload.ProtoPayload{Msg: &sm}
+}
+
+func TestBatching(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createResilientModel("batched-model")
+	graph.Batching = &v1.BatchingPolicy{MaxBatchSize: 3, MaxLatencyMs: 5000}
+
+	client := &flakyTestClient{}
+	pp := createPredictorProcessWithClient(t, client)
+	requests := []string{
+		`{"data":{"ndarray":[[1,2]]}}`,
+		`{"data":{"ndarray":[[3,4]]}}`,
+		`{"data":{"ndarray":[[5,6]]}}`,
+	}
+	responses := make([]payload.SeldonPayload, len(requests))
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req string) {
+			defer wg.Done()
+			var err error
+			responses[i], err = pp.Predict(graph, createSeldonPayload(g, req))
+			g.Expect(err).Should(BeNil())
+		}(i, req)
+	}
+	wg.Wait()
+
+	// The full batch is sent as one call and each request gets its own row back
+	g.Expect(client.calls["batched-model"]).To(Equal(1))
+	ma := jsonpb.Marshaler{}
+	for i, res := range responses {
+		data, err := ma.MarshalToString(res.GetPayload().(*proto.SeldonMessage))
+		g.Expect(err).Should(BeNil())
+		g.Expect(data).To(Equal(requests[i]))
+	}
+}
+
+func TestBatchingMaxLatency(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createResilientModel("batched-latency")
+	graph.Batching = &v1.BatchingPolicy{MaxBatchSize: 10, MaxLatencyMs: 5}
+
+	client := &flakyTestClient{}
+	res, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).ToNot(BeNil())
+	g.Expect(client.calls["batched-latency"]).To(Equal(1))
+}
+
+func TestBatchTensor(t *testing.T) {
+	g := NewGomegaWithT(t)
+	item1, ok := newBatchItem(createSeldonPayload(g, `{"data":{"names":["a","b"],"tensor":{"shape":[1,2],"values":[1,2]}}}`))
+	g.Expect(ok).To(BeTrue())
+	item2, ok := newBatchItem(createSeldonPayload(g, `{"data":{"names":["a","b"],"tensor":{"shape":[2,2],"values":[3,4,5,6]}}}`))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(item1.signature).To(Equal(item2.signature))
+
+	merged, err := mergeBatch([]*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	tensor := merged.GetPayload().(*proto.SeldonMessage).GetData().GetTensor()
+	g.Expect(tensor.Shape).To(Equal([]int32{3, 2}))
+	g.Expect(tensor.Values).To(Equal([]float64{1, 2, 3, 4, 5, 6}))
+
+	resp := createSeldonPayload(g, `{"data":{"names":["p"],"tensor":{"shape":[3,1],"values":[0.1,0.2,0.3]}}}`)
+	parts, err := splitBatch(resp, []*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	g.Expect(parts[0].GetPayload().(*proto.SeldonMessage).GetData().GetTensor().Values).To(Equal([]float64{0.1}))
+	g.Expect(parts[1].GetPayload().(*proto.SeldonMessage).GetData().GetTensor().Shape).To(Equal([]int32{2, 1}))
+	g.Expect(parts[1].GetPayload().(*proto.SeldonMessage).GetData().GetTensor().Values).To(Equal([]float64{0.2, 0.3}))
+
+	// A response with the wrong number of rows can not be split
+	resp = createSeldonPayload(g, `{"data":{"tensor":{"shape":[1,1],"values":[0.1]}}}`)
+	_, err = splitBatch(resp, []*batchItem{item1, item2})
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestBatchJson(t *testing.T) {
+	g := NewGomegaWithT(t)
+	item1, ok := newBatchItem(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`), ContentType: "application/json"})
+	g.Expect(ok).To(BeTrue())
+	item2, ok := newBatchItem(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[3,4]]}}`), ContentType: "application/json"})
+	g.Expect(ok).To(BeTrue())
+
+	merged, err := mergeBatch([]*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(merged.GetPayload().([]byte))).To(Equal(`{"data":{"ndarray":[[1,2],[3,4]]}}`))
+	g.Expect(merged.GetContentType()).To(Equal("application/json"))
+
+	parts, err := splitBatch(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[0.1,0.9]}}`), ContentType: "application/json"}, []*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(parts[0].GetPayload().([]byte))).To(Equal(`{"data":{"ndarray":[0.1]}}`))
+	g.Expect(string(parts[1].GetPayload().([]byte))).To(Equal(`{"data":{"ndarray":[0.9]}}`))
+}
+
+func TestBatchNdarrayShape(t *testing.T) {
+	g := NewGomegaWithT(t)
+	item1, ok := newBatchItem(createSeldonPayload(g, `{"data":{"ndarray":[[1,2]]}}`))
+	g.Expect(ok).To(BeTrue())
+	item2, ok := newBatchItem(createSeldonPayload(g, `{"data":{"ndarray":[[3,4],[5,6]]}}`))
+	g.Expect(ok).To(BeTrue())
+	item3, ok := newBatchItem(createSeldonPayload(g, `{"data":{"ndarray":[[1,2,3]]}}`))
+	g.Expect(ok).To(BeTrue())
+	g.Expect(item1.signature).To(Equal(item2.signature))
+	g.Expect(item1.signature).ToNot(Equal(item3.signature))
+}
+
+func TestBatchMeta(t *testing.T) {
+	g := NewGomegaWithT(t)
+	item1, ok := newBatchItem(createSeldonPayload(g, `{"meta":{"puid":"puid-1"},"data":{"ndarray":[[1,2]]}}`))
+	g.Expect(ok).To(BeTrue())
+	item2, ok := newBatchItem(createSeldonPayload(g, `{"meta":{"puid":"puid-2"},"data":{"ndarray":[[3,4]]}}`))
+	g.Expect(ok).To(BeTrue())
+
+	merged, err := mergeBatch([]*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	g.Expect(merged.GetPayload().(*proto.SeldonMessage).GetMeta()).To(BeNil())
+
+	resp := createSeldonPayload(g, `{"meta":{"puid":"puid-1","tags":{"model":"a"}},"data":{"ndarray":[0.1,0.9]}}`)
+	parts, err := splitBatch(resp, []*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	for i, puid := range []string{"puid-1", "puid-2"} {
+		meta := parts[i].GetPayload().(*proto.SeldonMessage).GetMeta()
+		g.Expect(meta.Puid).To(Equal(puid))
+		g.Expect(meta.Tags).To(HaveKey("model"))
+	}
+}
+
+func TestBatchV2(t *testing.T) {
+	g := NewGomegaWithT(t)
+	createRequest := func(id string, rows int64, values ...float32) payload.SeldonPayload {
+		return &payload.ProtoPayload{Msg: &inference.ModelInferRequest{
+			Id: id,
+			Inputs: []*inference.ModelInferRequest_InferInputTensor{{
+				Name:     "input",
+				Datatype: "FP32",
+				Shape:    []int64{rows, 2},
+				Contents: &inference.InferTensorContents{Fp32Contents: values},
+			}},
+		}}
+	}
+	item1, ok := newBatchItem(createRequest("1", 1, 1, 2))
+	g.Expect(ok).To(BeTrue())
+	item2, ok := newBatchItem(createRequest("2", 2, 3, 4, 5, 6))
+	g.Expect(ok).To(BeTrue())
+	_, ok = newBatchItem(createRequest("3", 2, 1, 2))
+	g.Expect(ok).To(BeFalse())
+
+	merged, err := mergeBatch([]*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	input := merged.GetPayload().(*inference.ModelInferRequest).Inputs[0]
+	g.Expect(input.Shape).To(Equal([]int64{3, 2}))
+	g.Expect(input.Contents.Fp32Contents).To(Equal([]float32{1, 2, 3, 4, 5, 6}))
+
+	resp := &payload.ProtoPayload{Msg: &inference.ModelInferResponse{
+		ModelName: "model",
+		Outputs: []*inference.ModelInferResponse_InferOutputTensor{{
+			Name:     "output",
+			Datatype: "INT64",
+			Shape:    []int64{3},
+			Contents: &inference.InferTensorContents{Int64Contents: []int64{7, 8, 9}},
+		}},
+	}}
+	parts, err := splitBatch(resp, []*batchItem{item1, item2})
+	g.Expect(err).Should(BeNil())
+	part1 := parts[0].GetPayload().(*inference.ModelInferResponse)
+	g.Expect(part1.Id).To(Equal("1"))
+	g.Expect(part1.Outputs[0].Contents.Int64Contents).To(Equal([]int64{7}))
+	part2 := parts[1].GetPayload().(*inference.ModelInferResponse)
+	g.Expect(part2.Id).To(Equal("2"))
+	g.Expect(part2.Outputs[0].Shape).To(Equal([]int64{2}))
+	g.Expect(part2.Outputs[0].Contents.Int64Contents).To(Equal([]int64{8, 9}))
+}
+
+func TestBatchNotBatchable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	_, ok := newBatchItem(createSeldonPayload(g, `{"strData":"hello"}`))
+	g.Expect(ok).To(BeFalse())
+	_, ok = newBatchItem(createSeldonPayload(g, `{"meta":{"tags":{"a":"b"}},"data":{"ndarray":[[1]]}}`))
+	g.Expect(ok).To(BeFalse())
+	_, ok = newBatchItem(&payload.BytesPayload{Msg: []byte(`{"inputs":[]}`), ContentType: "application/json"})
+	g.Expect(ok).To(BeFalse())
+}
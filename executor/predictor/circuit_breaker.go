@@ -0,0 +1,200 @@
+package predictor
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	circuitBreakerDefaultOpenMs           = 30000
+	circuitBreakerDefaultHalfOpenRequests = 1
+)
+
+type circuitState int
+
+// Values exported in the circuit breaker state metric.
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// Status codes and gRPC code names showing the node itself failed, as opposed to rejecting a bad request.
+var circuitFailureStatus = map[string]bool{
+	"UNKNOWN":          true,
+	"DEADLINEEXCEEDED": true,
+	"INTERNAL":         true,
+	"UNAVAILABLE":      true,
+	"DATALOSS":         true,
+}
+
+// nodeGuard holds the circuit breaker state and number of in flight calls of a node.
+type nodeGuard struct {
+	mu       sync.Mutex
+	state    circuitState
+	failures int32
+	// Trial calls started and succeeded while half-open.
+	trials    int32
+	successes int32
+	openedAt  time.Time
+	inFlight  int32
+	metrics   *metric.CircuitBreakerMetrics
+}
+
+var nodeGuards = newNodeStates[nodeGuard]()
+
+func getNodeGuard(node *v1.PredictiveUnit) *nodeGuard {
+	return nodeGuards.get(node.Name, func() *nodeGuard {
+		return &nodeGuard{metrics: metric.NewCircuitBreakerMetrics()}
+	})
+}
+
+// rejectedError is returned for calls not sent to a node because its circuit is open or it has too many calls in
+// flight. It is returned as a 503 over REST and with its gRPC code over gRPC.
+type rejectedError struct {
+	node   string
+	reason string
+	code   codes.Code
+}
+
+func (e *rejectedError) Error() string {
+	return fmt.Sprintf("call to %s rejected: %s", e.node, e.reason)
+}
+
+func (e *rejectedError) HttpStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+func (e *rejectedError) GRPCStatus() *status.Status {
+	return status.New(e.code, e.Error())
+}
+
+func (g *nodeGuard) setState(nodeName string, state circuitState) {
+	g.state = state
+	g.metrics.SetState(nodeName, int(state))
+}
+
+func (g *nodeGuard) reject(node *v1.PredictiveUnit, reason string, code codes.Code) error {
+	g.metrics.Reject(node.Name, reason)
+	return &rejectedError{node: node.Name, reason: reason, code: code}
+}
+
+// acquireNode checks the circuit breaker and concurrency limit of the node before a call. On success it returns a
+// function that must be called with the outcome of the call.
+func (p *PredictorProcess) acquireNode(node *v1.PredictiveUnit) (func(err error), error) {
+	if node.CircuitBreaker == nil && node.MaxInFlight == nil {
+		return func(err error) {}, nil
+	}
+	g := getNodeGuard(node)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if node.MaxInFlight != nil && g.inFlight >= *node.MaxInFlight {
+		return nil, g.reject(node, metric.RejectedMaxInFlight, codes.ResourceExhausted)
+	}
+	trial := false
+	if cb := node.CircuitBreaker; cb != nil {
+		if g.state == circuitOpen && time.Since(g.openedAt) >= circuitOpenDuration(cb) {
+			g.trials, g.successes = 0, 0
+			g.setState(node.Name, circuitHalfOpen)
+		}
+		switch g.state {
+		case circuitOpen:
+			return nil, g.reject(node, metric.RejectedCircuitOpen, codes.Unavailable)
+		case circuitHalfOpen:
+			if g.trials >= circuitHalfOpenRequests(cb) {
+				return nil, g.reject(node, metric.RejectedCircuitOpen, codes.Unavailable)
+			}
+			g.trials++
+			trial = true
+		}
+	}
+	g.inFlight++
+
+	return func(err error) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.inFlight--
+		if node.CircuitBreaker != nil {
+			g.record(node, trial, err, p.Ctx.Err() != nil)
+		}
+	}, nil
+}
+
+// record updates the circuit breaker with the outcome of a call. Calls cancelled by the caller say nothing about
+// the health of the node and only free their trial slot.
+func (g *nodeGuard) record(node *v1.PredictiveUnit, trial bool, err error, cancelled bool) {
+	cb := node.CircuitBreaker
+	switch {
+	case cancelled:
+		if trial && g.state == circuitHalfOpen {
+			g.trials--
+		}
+	case isCircuitFailure(err):
+		switch g.state {
+		case circuitClosed:
+			g.failures++
+			if g.failures >= cb.FailureThreshold {
+				g.open(node.Name)
+			}
+		case circuitHalfOpen:
+			g.open(node.Name)
+		}
+	default:
+		switch g.state {
+		case circuitClosed:
+			g.failures = 0
+		case circuitHalfOpen:
+			if trial {
+				g.successes++
+				if g.successes >= circuitHalfOpenRequests(cb) {
+					g.failures = 0
+					g.setState(node.Name, circuitClosed)
+				}
+			}
+		}
+	}
+}
+
+func (g *nodeGuard) open(nodeName string) {
+	g.openedAt = time.Now()
+	g.setState(nodeName, circuitOpen)
+}
+
+// isCircuitFailure checks whether a failed call counts against the circuit breaker. Calls failing without a status,
+// such as connection errors and timeouts, always count.
+func isCircuitFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	code, ok := errorStatus(err)
+	if !ok {
+		return true
+	}
+	if httpCode, err := strconv.Atoi(code); err == nil {
+		return httpCode >= http.StatusInternalServerError
+	}
+	return circuitFailureStatus[code]
+}
+
+func circuitOpenDuration(cb *v1.CircuitBreaker) time.Duration {
+	if cb.OpenMs > 0 {
+		return time.Duration(cb.OpenMs) * time.Millisecond
+	}
+	return circuitBreakerDefaultOpenMs * time.Millisecond
+}
+
+func circuitHalfOpenRequests(cb *v1.CircuitBreaker) int32 {
+	if cb.HalfOpenRequests > 0 {
+		return cb.HalfOpenRequests
+	}
+	return circuitBreakerDefaultHalfOpenRequests
+}
@@ -0,0 +1,132 @@
+package predictor
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func resetNodeGuard(name string) {
+	nodeGuards.delete(name)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetNodeGuard("breaker-model")
+	graph := createResilientModel("breaker-model")
+	graph.CircuitBreaker = &v1.CircuitBreaker{FailureThreshold: 2, OpenMs: 50}
+
+	client := &flakyTestClient{failModel: "breaker-model", failures: 2, err: status.Error(codes.Unavailable, "unavailable")}
+	pp := createPredictorProcessWithClient(t, client)
+	for i := 0; i < 2; i++ {
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		g.Expect(err).ShouldNot(BeNil())
+	}
+
+	// Circuit is open so the model is not called
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["breaker-model"]).To(Equal(2))
+	var rerr *rejectedError
+	g.Expect(errors.As(err, &rerr)).To(BeTrue())
+	g.Expect(rerr.HttpStatusCode()).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(status.Code(err)).To(Equal(codes.Unavailable))
+
+	// After the open time a trial call is let through and closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(getNodeGuard(graph).state).To(Equal(circuitClosed))
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetNodeGuard("breaker-half-open")
+	graph := createResilientModel("breaker-half-open")
+	graph.CircuitBreaker = &v1.CircuitBreaker{FailureThreshold: 1, OpenMs: 10}
+
+	client := &flakyTestClient{failModel: "breaker-half-open", failures: -1, err: errors.New("connection refused")}
+	pp := createPredictorProcessWithClient(t, client)
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(getNodeGuard(graph).state).To(Equal(circuitOpen))
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["breaker-half-open"]).To(Equal(2))
+	g.Expect(getNodeGuard(graph).state).To(Equal(circuitOpen))
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetNodeGuard("breaker-client-errors")
+	graph := createResilientModel("breaker-client-errors")
+	graph.CircuitBreaker = &v1.CircuitBreaker{FailureThreshold: 1}
+
+	client := &flakyTestClient{failModel: "breaker-client-errors", failures: -1, err: status.Error(codes.InvalidArgument, "bad input")}
+	pp := createPredictorProcessWithClient(t, client)
+	for i := 0; i < 3; i++ {
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		g.Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	}
+	g.Expect(client.calls["breaker-client-errors"]).To(Equal(3))
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetNodeGuard("breaker-primary")
+	resetNodeGuard("breaker-fallback")
+	graph := createResilientModel("breaker-primary")
+	graph.CircuitBreaker = &v1.CircuitBreaker{FailureThreshold: 1}
+	graph.Retries = &v1.RetryPolicy{MaxRetries: 3}
+	graph.Fallback = createResilientModel("breaker-fallback")
+
+	client := &flakyTestClient{failModel: "breaker-primary", failures: -1, err: status.Error(codes.Unavailable, "unavailable")}
+	pp := createPredictorProcessWithClient(t, client)
+	for i := 0; i < 2; i++ {
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		g.Expect(err).Should(BeNil())
+	}
+	// The retry after the first failure is rejected by the open circuit
+	g.Expect(client.calls["breaker-primary"]).To(Equal(1))
+	g.Expect(client.calls["breaker-fallback"]).To(Equal(2))
+}
+
+func TestMaxInFlight(t *testing.T) {
+	g := NewGomegaWithT(t)
+	maxInFlight := int32(1)
+	timeout := int32(200)
+	resetNodeGuard("max-in-flight")
+	graph := createResilientModel("max-in-flight")
+	graph.MaxInFlight = &maxInFlight
+	graph.TimeoutMs = &timeout
+
+	client := &flakyTestClient{failModel: "max-in-flight", failures: 1, hang: true}
+	pp := createPredictorProcessWithClient(t, client)
+	done := make(chan error)
+	go func() {
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		done <- err
+	}()
+	g.Eventually(func() int32 {
+		guard := getNodeGuard(graph)
+		guard.mu.Lock()
+		defer guard.mu.Unlock()
+		return guard.inFlight
+	}).Should(Equal(int32(1)))
+
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+	g.Expect(<-done).ShouldNot(BeNil())
+
+	// The slot is released once the first call times out
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+}
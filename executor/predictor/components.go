@@ -1,24 +1,274 @@
 package predictor
 
 import (
-	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
+
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// Parameters understood by the built-in RANDOM_ABTEST and AVERAGE_COMBINER.
+const (
+	abTestRatioAParameter   = "ratioA"
+	abTestHashKeyParameter  = "hashKey"
+	weightsParameter        = "weights"
+	combinerMethodParameter = "method"
+
+	combinerMethodAverage         = "average"
+	combinerMethodWeightedAverage = "weighted_average"
+	combinerMethodMajorityVote    = "majority_vote"
+)
+
+var (
+	simpleModelNames  = []string{"class0", "class1", "class2"}
+	simpleModelValues = []float64{0.1, 0.9, 0.5}
 )
 
-func (p *PredictorProcess) abTestRouter(node *v1.PredictiveUnit) (int, error) {
-	ratioA := 0.5
-	var err error
-	if len(node.Parameters) == 1 && node.Parameters[0].Name == "ratioA" {
-		ratioA, err = strconv.ParseFloat(node.Parameters[0].Value, 32)
+func hasImplementation(node *v1.PredictiveUnit, implementation v1.PredictiveUnitImplementation) bool {
+	return node.Implementation != nil && *node.Implementation == implementation
+}
+
+func getParameter(node *v1.PredictiveUnit, name string) (string, bool) {
+	for _, param := range node.Parameters {
+		if param.Name == name {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// abTestRouter splits traffic between the children, either at random or, when the hashKey parameter is set,
+// consistently for each value of the key. The split is set by ratioA for two children or by weights for any number.
+func (p *PredictorProcess) abTestRouter(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+	weights, err := parseWeights(node, len(node.Children))
+	if err != nil {
+		return 0, err
+	}
+	if weights == nil {
+		ratioA := 0.5
+		if val, ok := getParameter(node, abTestRatioAParameter); ok {
+			ratioA, err = strconv.ParseFloat(val, 32)
+			if err != nil {
+				return 0, err
+			}
+		}
+		weights = []float64{ratioA, 1 - ratioA}
+	}
+
+	if hashKey, ok := getParameter(node, abTestHashKeyParameter); ok {
+		key, found, err := p.splitKey(node, hashKey, msg)
 		if err != nil {
 			return 0, err
 		}
+		if found {
+			return stickyRoute(node, key, weights), nil
+		}
+		p.Log.V(1).Info("Hash key not found in request, routing at random", "node", node.Name, "hashKey", hashKey)
+	}
+
+	return weightedRandomRoute(weights), nil
+}
+
+func weightedRandomRoute(weights []float64) int {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return len(weights) - 1
+}
+
+// simpleRouter always routes to the first child.
+func (p *PredictorProcess) simpleRouter(node *v1.PredictiveUnit) (int, error) {
+	return 0, nil
+}
+
+// simpleModel returns a fixed set of class probabilities, which is useful for testing graphs without a model container.
+func (p *PredictorProcess) simpleModel(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	sm, err := seldonMessageFromPayload(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%s on node %s: %w", v1.SIMPLE_MODEL, node.Name, err)
+	}
+	res := &proto.SeldonMessage{
+		Meta: sm.GetMeta(),
+		DataOneof: &proto.SeldonMessage_Data{
+			Data: &proto.DefaultData{
+				Names: simpleModelNames,
+				DataOneof: &proto.DefaultData_Tensor{
+					Tensor: &proto.Tensor{
+						Shape:  []int32{1, int32(len(simpleModelValues))},
+						Values: simpleModelValues,
+					},
+				},
+			},
+		},
+	}
+	return seldonMessageToPayload(res, msg)
+}
+
+// averageCombiner combines the children outputs element-wise. By default it takes the mean, a weighted mean or a
+// majority vote can be selected with the method and weights parameters.
+func (p *PredictorProcess) averageCombiner(node *v1.PredictiveUnit, msgs []payload.SeldonPayload) (payload.SeldonPayload, error) {
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("%s on node %s received no messages to combine", v1.AVERAGE_COMBINER, node.Name)
+	}
+
+	method := combinerMethodAverage
+	if val, ok := getParameter(node, combinerMethodParameter); ok {
+		method = val
+	}
+	weights, err := parseWeights(node, len(msgs))
+	if err != nil {
+		return nil, err
+	}
+	if method == combinerMethodWeightedAverage && weights == nil {
+		return nil, fmt.Errorf("%s on node %s requires the %s parameter for method %s", v1.AVERAGE_COMBINER, node.Name, weightsParameter, method)
+	}
+
+	sms := make([]*proto.SeldonMessage, len(msgs))
+	tensors := make([]*ndTensor, len(msgs))
+	for i, msg := range msgs {
+		sms[i], err = seldonMessageFromPayload(msg)
+		if err != nil {
+			return nil, fmt.Errorf("%s on node %s: %w", v1.AVERAGE_COMBINER, node.Name, err)
+		}
+		tensors[i], err = tensorFromDefaultData(sms[i].GetData())
+		if err != nil {
+			return nil, fmt.Errorf("%s on node %s: %w", v1.AVERAGE_COMBINER, node.Name, err)
+		}
+		if i > 0 && !tensors[i].sameShape(tensors[0]) {
+			return nil, fmt.Errorf("%s on node %s: shape %v of child %d does not match shape %v", v1.AVERAGE_COMBINER, node.Name, tensors[i].shape, i, tensors[0].shape)
+		}
+	}
+
+	var combined *ndTensor
+	switch method {
+	case combinerMethodAverage, combinerMethodWeightedAverage:
+		combined = weightedAverage(tensors, weights)
+	case combinerMethodMajorityVote:
+		combined = majorityVote(tensors, weights)
+	default:
+		return nil, fmt.Errorf("%s on node %s: unknown method %s", v1.AVERAGE_COMBINER, node.Name, method)
 	}
 
-	if rand.Float64() < ratioA {
-		return 0, nil
-	} else {
-		return 1, nil
+	res := &proto.SeldonMessage{
+		Status: sms[0].GetStatus(),
+		Meta:   sms[0].GetMeta(),
+		DataOneof: &proto.SeldonMessage_Data{
+			Data: combined.toDefaultData(sms[0].GetData()),
+		},
+	}
+	return seldonMessageToPayload(res, msgs[0])
+}
+
+// parseWeights parses the optional comma separated list of weights, one per child.
+func parseWeights(node *v1.PredictiveUnit, numChildren int) ([]float64, error) {
+	val, ok := getParameter(node, weightsParameter)
+	if !ok {
+		return nil, nil
+	}
+	parts := strings.Split(val, ",")
+	if len(parts) != numChildren {
+		return nil, fmt.Errorf("%s on node %s has %d weights for %d children", *node.Implementation, node.Name, len(parts), numChildren)
+	}
+	weights := make([]float64, len(parts))
+	total := 0.0
+	for i, part := range parts {
+		w, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		if w < 0 {
+			return nil, fmt.Errorf("%s on node %s has negative weight %v", *node.Implementation, node.Name, w)
+		}
+		weights[i] = w
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%s on node %s has weights summing to zero", *node.Implementation, node.Name)
+	}
+	return weights, nil
+}
+
+func weightedAverage(tensors []*ndTensor, weights []float64) *ndTensor {
+	res := &ndTensor{shape: tensors[0].shape, values: make([]float64, len(tensors[0].values))}
+	total := 0.0
+	for i, t := range tensors {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		total += w
+		for j, v := range t.values {
+			res.values[j] += w * v
+		}
+	}
+	for j := range res.values {
+		res.values[j] /= total
+	}
+	return res
+}
+
+// majorityVote treats the last dimension as classes and returns, for each row, the (weighted) share of children
+// whose highest scoring class was each class. For one dimensional outputs every element is a vote and the most
+// voted value is returned, ties going to the earliest child.
+func majorityVote(tensors []*ndTensor, weights []float64) *ndTensor {
+	weight := func(i int) float64 {
+		if weights != nil {
+			return weights[i]
+		}
+		return 1.0
+	}
+	total := 0.0
+	for i := range tensors {
+		total += weight(i)
+	}
+
+	first := tensors[0]
+	res := &ndTensor{shape: first.shape, values: make([]float64, len(first.values))}
+	if len(first.shape) < 2 {
+		for j := range first.values {
+			votes := make(map[float64]float64)
+			for i, t := range tensors {
+				votes[t.values[j]] += weight(i)
+			}
+			// Children are checked in order once all votes are counted so ties go to the earliest
+			best := first.values[j]
+			for _, t := range tensors {
+				if votes[t.values[j]] > votes[best] {
+					best = t.values[j]
+				}
+			}
+			res.values[j] = best
+		}
+		return res
+	}
+
+	classes := first.shape[len(first.shape)-1]
+	if classes == 0 {
+		return res
+	}
+	for row := 0; row < len(first.values)/classes; row++ {
+		offset := row * classes
+		for i, t := range tensors {
+			argmax := 0
+			for c := 1; c < classes; c++ {
+				if t.values[offset+c] > t.values[offset+argmax] {
+					argmax = c
+				}
+			}
+			res.values[offset+argmax] += weight(i) / total
+		}
 	}
+	return res
 }
@@ -0,0 +1,137 @@
+package predictor
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func createSeldonMessagePayload(g *GomegaWithT, data string) payload.SeldonPayload {
+	var sm proto.SeldonMessage
+	err := jsonpb.UnmarshalString(data, &sm)
+	g.Expect(err).Should(BeNil())
+	return &payload.ProtoPayload{Msg: &sm}
+}
+
+func createAverageCombiner(params ...v1.Parameter) *v1.PredictiveUnit {
+	averageCombiner := v1.AVERAGE_COMBINER
+	return &v1.PredictiveUnit{
+		Name:           "combiner",
+		Implementation: &averageCombiner,
+		Parameters:     params,
+	}
+}
+
+func TestAverageCombinerNdarray(t *testing.T) {
+	g := NewGomegaWithT(t)
+	msgs := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"names":["a","b"],"ndarray":[[0.2,0.8],[0.6,0.4]]}}`),
+		createSeldonMessagePayload(g, `{"data":{"names":["a","b"],"ndarray":[[0.4,0.6],[1.0,0.0]]}}`),
+	}
+
+	res, err := createPredictorProcess(t).averageCombiner(createAverageCombiner(), msgs)
+	g.Expect(err).Should(BeNil())
+	sm := res.GetPayload().(*proto.SeldonMessage)
+	g.Expect(sm.GetData().GetNames()).Should(Equal([]string{"a", "b"}))
+	rows := sm.GetData().GetNdarray().GetValues()
+	g.Expect(rows).Should(HaveLen(2))
+	g.Expect(rows[0].GetListValue().GetValues()[0].GetNumberValue()).Should(BeNumerically("~", 0.3))
+	g.Expect(rows[0].GetListValue().GetValues()[1].GetNumberValue()).Should(BeNumerically("~", 0.7))
+	g.Expect(rows[1].GetListValue().GetValues()[0].GetNumberValue()).Should(BeNumerically("~", 0.8))
+	g.Expect(rows[1].GetListValue().GetValues()[1].GetNumberValue()).Should(BeNumerically("~", 0.2))
+}
+
+func TestAverageCombinerWeighted(t *testing.T) {
+	g := NewGomegaWithT(t)
+	msgs := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"tensor":{"shape":[1,2],"values":[1,0]}}}`),
+		createSeldonMessagePayload(g, `{"data":{"tensor":{"shape":[1,2],"values":[0,1]}}}`),
+	}
+	node := createAverageCombiner(
+		v1.Parameter{Name: "method", Value: "weighted_average", Type: v1.STRING},
+		v1.Parameter{Name: "weights", Value: "3, 1", Type: v1.STRING},
+	)
+
+	res, err := createPredictorProcess(t).averageCombiner(node, msgs)
+	g.Expect(err).Should(BeNil())
+	sm := res.GetPayload().(*proto.SeldonMessage)
+	g.Expect(sm.GetData().GetTensor().GetShape()).Should(Equal([]int32{1, 2}))
+	g.Expect(sm.GetData().GetTensor().GetValues()).Should(Equal([]float64{0.75, 0.25}))
+}
+
+func TestAverageCombinerMajorityVote(t *testing.T) {
+	g := NewGomegaWithT(t)
+	msgs := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"tensor":{"shape":[2,3],"values":[0.1,0.7,0.2,0.5,0.3,0.2]}}}`),
+		createSeldonMessagePayload(g, `{"data":{"tensor":{"shape":[2,3],"values":[0.2,0.5,0.3,0.1,0.1,0.8]}}}`),
+		createSeldonMessagePayload(g, `{"data":{"tensor":{"shape":[2,3],"values":[0.6,0.3,0.1,0.1,0.2,0.7]}}}`),
+		createSeldonMessagePayload(g, `{"data":{"tensor":{"shape":[2,3],"values":[0.1,0.8,0.1,0.9,0.0,0.1]}}}`),
+	}
+	node := createAverageCombiner(v1.Parameter{Name: "method", Value: "majority_vote", Type: v1.STRING})
+
+	res, err := createPredictorProcess(t).averageCombiner(node, msgs)
+	g.Expect(err).Should(BeNil())
+	sm := res.GetPayload().(*proto.SeldonMessage)
+	g.Expect(sm.GetData().GetTensor().GetValues()).Should(Equal([]float64{0.25, 0.75, 0, 0.5, 0, 0.5}))
+
+	labels := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,2,0]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,0,2]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[2,0,2]}}`),
+	}
+	res, err = createPredictorProcess(t).averageCombiner(node, labels)
+	g.Expect(err).Should(BeNil())
+	values := res.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()
+	g.Expect(values[0].GetNumberValue()).Should(Equal(1.0))
+	g.Expect(values[1].GetNumberValue()).Should(Equal(0.0))
+	g.Expect(values[2].GetNumberValue()).Should(Equal(2.0))
+
+	// Ties go to the value of the earliest child
+	tied := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,4]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[2,3]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[2,3]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,4]}}`),
+	}
+	res, err = createPredictorProcess(t).averageCombiner(node, tied)
+	g.Expect(err).Should(BeNil())
+	values = res.GetPayload().(*proto.SeldonMessage).GetData().GetNdarray().GetValues()
+	g.Expect(values[0].GetNumberValue()).Should(Equal(1.0))
+	g.Expect(values[1].GetNumberValue()).Should(Equal(4.0))
+}
+
+func TestAverageCombinerErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pp := createPredictorProcess(t)
+
+	mismatched := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,2]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,2,3]}}`),
+	}
+	_, err := pp.averageCombiner(createAverageCombiner(), mismatched)
+	g.Expect(err).ShouldNot(BeNil())
+
+	msgs := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[1,2]}}`),
+		createSeldonMessagePayload(g, `{"data":{"ndarray":[3,4]}}`),
+	}
+	_, err = pp.averageCombiner(createAverageCombiner(v1.Parameter{Name: "weights", Value: "1", Type: v1.STRING}), msgs)
+	g.Expect(err).ShouldNot(BeNil())
+
+	_, err = pp.averageCombiner(createAverageCombiner(v1.Parameter{Name: "method", Value: "weighted_average", Type: v1.STRING}), msgs)
+	g.Expect(err).ShouldNot(BeNil())
+
+	_, err = pp.averageCombiner(createAverageCombiner(v1.Parameter{Name: "method", Value: "median", Type: v1.STRING}), msgs)
+	g.Expect(err).ShouldNot(BeNil())
+
+	strData := []payload.SeldonPayload{
+		createSeldonMessagePayload(g, `{"strData":"foo"}`),
+		createSeldonMessagePayload(g, `{"strData":"bar"}`),
+	}
+	_, err = pp.averageCombiner(createAverageCombiner(), strData)
+	g.Expect(err).ShouldNot(BeNil())
+}
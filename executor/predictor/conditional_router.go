@@ -0,0 +1,145 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/protobuf/jsonpb"
+	protoV1 "github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// Parameters understood by the built-in CONDITIONAL_ROUTER.
+const (
+	conditionalRulesParameter   = "rules"
+	conditionalDefaultParameter = "default"
+)
+
+// conditionalRule routes to Child when the When expression is true.
+type conditionalRule struct {
+	When  string `json:"when"`
+	Child int    `json:"child"`
+	expr  exprNode
+}
+
+// conditionalRoutes are the parsed parameters of a CONDITIONAL_ROUTER node, or the error parsing them.
+type conditionalRoutes struct {
+	rules        []conditionalRule
+	defaultChild int
+	err          error
+	// Raw parameters the routes were parsed from, so changed parameters are parsed again.
+	source string
+}
+
+var conditionalRouters = newNodeStates[conditionalRoutes]()
+
+func getConditionalRoutes(node *v1.PredictiveUnit) (*conditionalRoutes, error) {
+	rulesParam, ok := getParameter(node, conditionalRulesParameter)
+	if !ok {
+		return nil, fmt.Errorf("%s router %s has no %s parameter", v1.CONDITIONAL_ROUTER, node.Name, conditionalRulesParameter)
+	}
+	defaultParam, hasDefault := getParameter(node, conditionalDefaultParameter)
+	source := rulesParam + "\n" + defaultParam
+
+	routes := conditionalRouters.getValid(node.Name, func(r *conditionalRoutes) bool {
+		return r.source == source
+	}, func() *conditionalRoutes {
+		routes := &conditionalRoutes{source: source}
+		routes.err = routes.parse(node, rulesParam, defaultParam, hasDefault)
+		return routes
+	})
+	if routes.err != nil {
+		return nil, routes.err
+	}
+	return routes, nil
+}
+
+func (routes *conditionalRoutes) parse(node *v1.PredictiveUnit, rulesParam string, defaultParam string, hasDefault bool) error {
+	err := json.Unmarshal([]byte(rulesParam), &routes.rules)
+	if err != nil {
+		return fmt.Errorf("%s router %s has invalid rules: %w", v1.CONDITIONAL_ROUTER, node.Name, err)
+	}
+	for i := range routes.rules {
+		routes.rules[i].expr, err = parseExpression(routes.rules[i].When)
+		if err != nil {
+			return fmt.Errorf("%s router %s rule %d: %w", v1.CONDITIONAL_ROUTER, node.Name, i, err)
+		}
+	}
+	if hasDefault {
+		routes.defaultChild, err = strconv.Atoi(defaultParam)
+		if err != nil {
+			return fmt.Errorf("%s router %s has invalid default: %w", v1.CONDITIONAL_ROUTER, node.Name, err)
+		}
+	}
+	return nil
+}
+
+// conditionalRouter routes to the child of the first rule whose expression is true, or to the default child.
+func (p *PredictorProcess) conditionalRouter(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+	routes, err := getConditionalRoutes(node)
+	if err != nil {
+		return 0, err
+	}
+
+	env := p.newExprEnv(msg)
+	route := routes.defaultChild
+	for i, rule := range routes.rules {
+		matched, err := rule.expr.eval(env)
+		if err != nil {
+			return 0, fmt.Errorf("%s router %s rule %d: %w", v1.CONDITIONAL_ROUTER, node.Name, i, err)
+		}
+		if truthy(matched) {
+			p.Log.V(1).Info("Conditional route matched", "node", node.Name, "rule", rule.When, "child", rule.Child)
+			route = rule.Child
+			break
+		}
+	}
+	if route < 0 || route >= len(node.Children) {
+		return 0, fmt.Errorf("%s router %s has no child %d", v1.CONDITIONAL_ROUTER, node.Name, route)
+	}
+	return route, nil
+}
+
+// newExprEnv gives expressions access to the request headers and payload, which is only decoded if used.
+func (p *PredictorProcess) newExprEnv(msg payload.SeldonPayload) *exprEnv {
+	var body interface{}
+	var bodyErr error
+	decoded := false
+	return &exprEnv{
+		headers: p.Meta.Meta,
+		payload: func() (interface{}, error) {
+			if !decoded {
+				body, bodyErr = payloadToJSON(msg)
+				decoded = true
+			}
+			return body, bodyErr
+		},
+	}
+}
+
+// payloadToJSON decodes a payload into generic JSON values. Protobuf payloads use their JSON mapping.
+func payloadToJSON(msg payload.SeldonPayload) (interface{}, error) {
+	var data []byte
+	if pm, ok := msg.GetPayload().(protoV1.Message); ok {
+		m := jsonpb.Marshaler{}
+		jStr, err := m.MarshalToString(pm)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(jStr)
+	} else {
+		var err error
+		data, err = payload.DecompressSeldonPayload(msg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var body interface{}
+	err := json.Unmarshal(data, &body)
+	if err != nil {
+		return nil, fmt.Errorf("payload is not JSON: %w", err)
+	}
+	return body, nil
+}
@@ -0,0 +1,86 @@
+package predictor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func createConditionalRouter(name string, rules string, defaultChild string) *v1.PredictiveUnit {
+	conditionalRouter := v1.CONDITIONAL_ROUTER
+	params := []v1.Parameter{{Name: conditionalRulesParameter, Type: v1.STRING, Value: rules}}
+	if defaultChild != "" {
+		params = append(params, v1.Parameter{Name: conditionalDefaultParameter, Type: v1.INT, Value: defaultChild})
+	}
+	return &v1.PredictiveUnit{
+		Name:           name,
+		Implementation: &conditionalRouter,
+		Parameters:     params,
+		Children: []v1.PredictiveUnit{
+			{Name: "model-a"},
+			{Name: "model-b"},
+			{Name: "model-c"},
+		},
+	}
+}
+
+func TestConditionalRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createConditionalRouter("conditional", `[
+		{"when": "headers['X-Tenant'] == 'acme'", "child": 1},
+		{"when": "$.data.ndarray[0][0] > 10", "child": 2}
+	]`, "0")
+
+	tests := []struct {
+		headers map[string][]string
+		data    string
+		route   int32
+	}{
+		{map[string][]string{"X-Tenant": {"acme"}}, `{"data":{"ndarray":[[20]]}}`, 1},
+		{map[string][]string{"X-Tenant": {"initech"}}, `{"data":{"ndarray":[[20]]}}`, 2},
+		{map[string][]string{}, `{"data":{"ndarray":[[1]]}}`, 0},
+	}
+	for _, test := range tests {
+		// Both Seldon JSON and protobuf payloads are routed
+		for _, msg := range []payload.SeldonPayload{
+			&payload.BytesPayload{Msg: []byte(test.data), ContentType: "application/json"},
+			createSeldonMessagePayload(g, test.data),
+		} {
+			pp := createPredictorProcessWithMeta(t, test.headers)
+			_, err := pp.Predict(graph, msg)
+			g.Expect(err).Should(BeNil())
+			g.Expect(pp.Routing[graph.Name]).To(Equal(test.route))
+		}
+	}
+}
+
+func TestConditionalRouterTags(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createConditionalRouter("conditional-tags", `[{"when": "tags.version in ['v2', 'v3']", "child": 2}]`, "")
+
+	pp := createPredictorProcess(t)
+	_, err := pp.Predict(graph, createSeldonMessagePayload(g, `{"meta":{"tags":{"version":"v3"}},"data":{"ndarray":[1]}}`))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing[graph.Name]).To(Equal(int32(2)))
+
+	// Without a default parameter requests go to the first child
+	pp = createPredictorProcess(t)
+	_, err = pp.Predict(graph, createSeldonMessagePayload(g, `{"data":{"ndarray":[1]}}`))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing[graph.Name]).To(Equal(int32(0)))
+}
+
+func TestConditionalRouterErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for i, graph := range []*v1.PredictiveUnit{
+		createConditionalRouter("conditional-bad-json", `not json`, ""),
+		createConditionalRouter("conditional-bad-expr", `[{"when": "$.data ==", "child": 1}]`, ""),
+		createConditionalRouter("conditional-bad-child", `[{"when": "true", "child": 5}]`, ""),
+		createConditionalRouter("conditional-bad-default", `[]`, "x"),
+	} {
+		_, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+		g.Expect(err).ShouldNot(BeNil(), "graph %d", i)
+	}
+}
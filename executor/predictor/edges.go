@@ -0,0 +1,243 @@
+package predictor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// The edges of the graph are checked once for each graph hash, and the result is refreshed in the background every
+// edgeCheckRefresh so that ready probes never wait on the graph's metadata. The graph is not ready until its first
+// check completes.
+const (
+	edgeCheckRefresh = 30 * time.Second
+	edgeCheckTimeout = 10 * time.Second
+)
+
+var (
+	edgeReadinessGate  bool
+	edgeCheck          *edgeCheckResult
+	edgeCheckRunning   string
+	edgeReadinessMutex sync.Mutex
+)
+
+type edgeCheckResult struct {
+	hash    string
+	err     error
+	checked time.Time
+}
+
+// SetEdgeReadinessGate makes the executor unready while any edge of the graph is incompatible.
+func SetEdgeReadinessGate(enabled bool) {
+	edgeReadinessMutex.Lock()
+	defer edgeReadinessMutex.Unlock()
+	edgeReadinessGate = enabled
+	edgeCheck = nil
+	edgeCheckRunning = ""
+}
+
+// EdgeWarning describes a parent to child edge of the graph along which the messages sent do not match the metadata
+// of the receiving node.
+type EdgeWarning struct {
+	Parent  string `json:"parent"`
+	Child   string `json:"child"`
+	Message string `json:"message"`
+}
+
+// ReadyEdges returns the last result of checking the edges of the graph when the edge readiness gate is enabled, which
+// is an error if the graph's metadata could not be fetched or any edge is incompatible. Checks run in the background.
+func (p *PredictorProcess) ReadyEdges(spec *v1.PredictorSpec) error {
+	edgeReadinessMutex.Lock()
+	defer edgeReadinessMutex.Unlock()
+	if !edgeReadinessGate {
+		return nil
+	}
+	hash := GraphHash(spec)
+	result := edgeCheck
+	if result == nil || result.hash != hash {
+		result = nil
+	}
+	if (result == nil || time.Since(result.checked) > edgeCheckRefresh) && edgeCheckRunning != hash {
+		edgeCheckRunning = hash
+		go p.checkGraphEdges(spec, hash)
+	}
+	if result == nil {
+		return fmt.Errorf("Edges of graph %s have not been checked yet", hash)
+	}
+	return result.err
+}
+
+// checkGraphEdges checks the edges of the graph and publishes the result. The check is not tied to the request that
+// started it.
+func (p *PredictorProcess) checkGraphEdges(spec *v1.PredictorSpec, hash string) {
+	ctx, cancel := context.WithTimeout(context.Background(), edgeCheckTimeout)
+	defer cancel()
+	cp := *p
+	cp.Ctx = ctx
+	result := &edgeCheckResult{hash: hash, checked: time.Now()}
+	if gm, err := cp.GraphMetadata(spec); err != nil {
+		result.err = fmt.Errorf("Failed to get graph metadata to check its edges: %w", err)
+	} else if len(gm.EdgeWarnings) > 0 {
+		w := gm.EdgeWarnings[0]
+		result.err = fmt.Errorf("Graph has %d incompatible edges, including %s -> %s: %s", len(gm.EdgeWarnings), w.Parent, w.Child, w.Message)
+	}
+
+	edgeReadinessMutex.Lock()
+	defer edgeReadinessMutex.Unlock()
+	if edgeCheckRunning == hash {
+		edgeCheck = result
+		edgeCheckRunning = ""
+	}
+}
+
+// checkEdges compares what is sent along every parent to child edge below node with what the receiving end of the edge
+// expects. Edges where either end has no metadata, or where the ends describe different protocols, are not checked.
+func (gm *GraphMetadata) checkEdges(node *v1.PredictiveUnit) []EdgeWarning {
+	var warnings []EdgeWarning
+	nodeMeta := gm.Models[node.Name]
+	nodeType := graphNodeType(node)
+	nodeInputs := signatureSpecs(nodeMeta.Inputs)
+	for i := range node.Children {
+		child := &node.Children[i]
+		childInput, childOutput := gm.getEdgeNodes(child)
+		var problems []string
+		switch nodeType {
+		case v1.COMBINER:
+			// Combiners may describe the output of each child as a separate input
+			expected := nodeInputs
+			if len(nodeInputs) == len(node.Children) {
+				expected = nodeInputs[i : i+1]
+			}
+			problems = compareSignatures(signatureSpecs(childOutput.Outputs), expected)
+		case v1.OUTPUT_TRANSFORMER:
+			// The child's output is sent back to the node
+			problems = compareSignatures(signatureSpecs(childOutput.Outputs), nodeInputs)
+		case v1.ROUTER:
+			// The node's input is passed on to the child
+			problems = compareSignatures(nodeInputs, signatureSpecs(childInput.Inputs))
+		default:
+			problems = compareSignatures(signatureSpecs(nodeMeta.Outputs), signatureSpecs(childInput.Inputs))
+		}
+		for _, problem := range problems {
+			warnings = append(warnings, EdgeWarning{Parent: node.Name, Child: child.Name, Message: problem})
+		}
+		warnings = append(warnings, gm.checkEdges(child)...)
+	}
+	return warnings
+}
+
+// signatureSpecs reads the inputs or outputs of model metadata, returning nothing if they are missing or invalid.
+func signatureSpecs(signature interface{}) []inputSpec {
+	if signature == nil {
+		return nil
+	}
+	specs, err := parseInputSpecs(signature)
+	if err != nil {
+		return nil
+	}
+	return specs
+}
+
+// compareSignatures compares the metadata of the messages sent along an edge with the metadata of the messages
+// expected at its end, describing each mismatch found.
+func compareSignatures(sent []inputSpec, expected []inputSpec) []string {
+	if len(sent) == 0 || len(expected) == 0 {
+		return nil
+	}
+	sentV2, expectedV2 := sent[0].Name != "", expected[0].Name != ""
+	switch {
+	case sentV2 && expectedV2:
+		return compareV2Signatures(sent, expected)
+	case !sentV2 && !expectedV2:
+		return compareSeldonSignatures(sent[0], expected[0])
+	}
+	return nil
+}
+
+// compareSeldonSignatures compares the message type and shape of Seldon messages. Names are not compared as nodes
+// commonly label their data differently from the nodes that send it.
+func compareSeldonSignatures(sent inputSpec, expected inputSpec) []string {
+	if !isSeldonMessagetype(sent.Messagetype) || !isSeldonMessagetype(expected.Messagetype) {
+		// Custom message types are not checked
+		return nil
+	}
+	if sent.Messagetype != expected.Messagetype {
+		return []string{fmt.Sprintf("%s is sent but %s is expected", sent.Messagetype, expected.Messagetype)}
+	}
+	if !compatibleShapes(sent.Shape, expected.Shape, true) {
+		return []string{fmt.Sprintf("data is sent with shape %v but %v is expected", sent.Shape, expected.Shape)}
+	}
+	return nil
+}
+
+func isSeldonMessagetype(messagetype string) bool {
+	switch messagetype {
+	case "ndarray", "tensor", "tftensor", "jsonData", "strData", "binData":
+		return true
+	}
+	return false
+}
+
+// compareV2Signatures compares V2 tensors by name, or directly where a single tensor is sent to a node with a single
+// input.
+func compareV2Signatures(sent []inputSpec, expected []inputSpec) []string {
+	if len(sent) == 1 && len(expected) == 1 {
+		return compareV2Tensors(sent[0], expected[0])
+	}
+	var problems []string
+	byName := make(map[string]inputSpec)
+	for _, spec := range sent {
+		byName[spec.Name] = spec
+	}
+	for _, spec := range expected {
+		s, ok := byName[spec.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("tensor %q is expected but not sent", spec.Name))
+			continue
+		}
+		delete(byName, spec.Name)
+		problems = append(problems, compareV2Tensors(s, spec)...)
+	}
+	for _, spec := range sent {
+		if _, ok := byName[spec.Name]; ok {
+			problems = append(problems, fmt.Sprintf("tensor %q is sent but not expected", spec.Name))
+		}
+	}
+	return problems
+}
+
+func compareV2Tensors(sent inputSpec, expected inputSpec) []string {
+	var problems []string
+	if sent.Datatype != "" && expected.Datatype != "" && sent.Datatype != expected.Datatype {
+		problems = append(problems, fmt.Sprintf("tensor %q is sent as %s but %s is expected", sent.Name, sent.Datatype, expected.Datatype))
+	}
+	if !compatibleShapes(sent.Shape, expected.Shape, false) {
+		problems = append(problems, fmt.Sprintf("tensor %q is sent with shape %v but %v is expected", sent.Name, sent.Shape, expected.Shape))
+	}
+	return problems
+}
+
+// compatibleShapes reports whether data of one shape can be of the other, where -1 on either side matches any size and
+// a missing shape matches any shape. With batched, as in checkShape, one shape may leave out the first dimension.
+func compatibleShapes(a []int64, b []int64, batched bool) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	if batched && len(a) == len(b)+1 {
+		a = a[1:]
+	} else if batched && len(b) == len(a)+1 {
+		b = b[1:]
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] >= 0 && b[i] >= 0 && a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
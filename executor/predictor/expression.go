@@ -0,0 +1,591 @@
+package predictor
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions used by the CONDITIONAL_ROUTER. They follow a small subset of CEL:
+//
+//	$.data.ndarray[0][1] > 10 && headers['X-Client-Version'] >= 2
+//	tags.tenant in ['acme', 'initech'] || !has(headers.X-Beta)
+//
+// Paths start at $ for the request payload, tags for the tags of a Seldon message (parameters of a V2 request) or
+// headers for the request headers. Supported operators are ||, &&, !, ==, !=, <, <=, >, >=, in, contains and
+// matches (a regular expression). A path on its own is true when it exists and is not false, zero or empty.
+
+// exprEnv gives expressions access to the request being routed.
+type exprEnv struct {
+	payload func() (interface{}, error)
+	headers map[string][]string
+}
+
+type exprNode interface {
+	eval(env *exprEnv) (interface{}, error)
+}
+
+type exprLiteral struct {
+	value interface{}
+}
+
+type exprList struct {
+	items []exprNode
+}
+
+type exprPath struct {
+	root     string
+	segments []interface{} // string keys or int indices
+}
+
+type exprHas struct {
+	path *exprPath
+}
+
+type exprNot struct {
+	operand exprNode
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+	re          *regexp.Regexp // set for matches with a literal pattern
+}
+
+const (
+	exprRootPayload = "$"
+	exprRootTags    = "tags"
+	exprRootHeaders = "headers"
+)
+
+func (n *exprLiteral) eval(env *exprEnv) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *exprList) eval(env *exprEnv) (interface{}, error) {
+	values := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (n *exprPath) eval(env *exprEnv) (interface{}, error) {
+	var current interface{}
+	segments := n.segments
+	switch n.root {
+	case exprRootHeaders:
+		if len(segments) == 0 {
+			return nil, nil
+		}
+		name, ok := segments[0].(string)
+		if !ok {
+			return nil, nil
+		}
+		for k, v := range env.headers {
+			if strings.EqualFold(k, name) && len(v) > 0 {
+				current = v[0]
+			}
+		}
+		segments = segments[1:]
+	case exprRootTags:
+		body, err := env.payload()
+		if err != nil {
+			return nil, err
+		}
+		current = lookupPath(body, []interface{}{"meta", "tags"})
+		if current == nil {
+			current = lookupPath(body, []interface{}{"parameters"})
+		}
+	default:
+		body, err := env.payload()
+		if err != nil {
+			return nil, err
+		}
+		current = body
+	}
+	return lookupPath(current, segments), nil
+}
+
+func lookupPath(current interface{}, segments []interface{}) interface{} {
+	for _, segment := range segments {
+		switch s := segment.(type) {
+		case string:
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = m[s]
+		case int:
+			l, ok := current.([]interface{})
+			if !ok || s < 0 || s >= len(l) {
+				return nil
+			}
+			current = l[s]
+		}
+	}
+	return current
+}
+
+func (n *exprHas) eval(env *exprEnv) (interface{}, error) {
+	v, err := n.path.eval(env)
+	return v != nil, err
+}
+
+func (n *exprNot) eval(env *exprEnv) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	return !truthy(v), err
+}
+
+func (n *exprBinary) eval(env *exprEnv) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	// Short circuit so paths that are only valid for some requests can be guarded
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		return truthy(right), nil
+	case "==":
+		return exprEqual(left, right), nil
+	case "!=":
+		return !exprEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		cmp, ok := exprCompare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "in":
+		list, ok := right.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		for _, item := range list {
+			if exprEqual(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "contains":
+		switch l := left.(type) {
+		case string:
+			s, ok := right.(string)
+			return ok && strings.Contains(l, s), nil
+		case []interface{}:
+			for _, item := range l {
+				if exprEqual(item, right) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case "matches":
+		s, ok := left.(string)
+		if !ok {
+			return false, nil
+		}
+		re := n.re
+		if re == nil {
+			pattern, ok := right.(string)
+			if !ok {
+				return false, nil
+			}
+			re, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return re.MatchString(s), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case float64:
+		return t != 0
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+	return true
+}
+
+// exprNumber converts numbers and numeric strings, such as header values, to float64.
+func exprNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func exprEqual(left, right interface{}) bool {
+	_, leftNumber := left.(float64)
+	_, rightNumber := right.(float64)
+	if leftNumber || rightNumber {
+		l, lok := exprNumber(left)
+		r, rok := exprNumber(right)
+		return lok && rok && l == r
+	}
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	}
+	return false
+}
+
+func exprCompare(left, right interface{}) (int, bool) {
+	ls, lString := left.(string)
+	rs, rString := right.(string)
+	if lString && rString {
+		return strings.Compare(ls, rs), true
+	}
+	l, lok := exprNumber(left)
+	r, rok := exprNumber(right)
+	if !lok || !rok {
+		return 0, false
+	}
+	switch {
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	}
+	return 0, true
+}
+
+// --- Parsing
+
+type exprToken struct {
+	kind  string // op, ident, string, number, punct, eof
+	value string
+	pos   int
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func parseExpression(src string) (exprNode, error) {
+	tokens, err := tokenizeExpression(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+	}
+	return node, nil
+}
+
+func tokenizeExpression(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: "string", value: sb.String(), pos: i})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' || runes[j] == 'e' || runes[j] == 'E') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: "number", value: string(runes[i:j]), pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			j := i + 1
+			// Dashes are allowed after the first character so header names can be written as paths
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: "ident", value: string(runes[i:j]), pos: i})
+			i = j
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "&&", "||", "==", "!=", "<=", ">=":
+					tokens = append(tokens, exprToken{kind: "op", value: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '<', '>', '!':
+				tokens = append(tokens, exprToken{kind: "op", value: string(r), pos: i})
+			case '(', ')', '[', ']', '.', ',':
+				tokens = append(tokens, exprToken{kind: "punct", value: string(r), pos: i})
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+			i++
+		}
+	}
+	return append(tokens, exprToken{kind: "eof", pos: len(runes)}), nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != "eof" {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) accept(kind string, value string) bool {
+	if t := p.peek(); t.kind == kind && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(kind string, value string) error {
+	if !p.accept(kind, value) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at position %d", value, t.pos)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("op", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("op", "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("op", "!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNot{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	isOp := false
+	switch {
+	case t.kind == "op" && t.value != "!" && t.value != "&&" && t.value != "||":
+		isOp = true
+	case t.kind == "ident" && (t.value == "in" || t.value == "contains" || t.value == "matches"):
+		isOp = true
+	}
+	if !isOp {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	node := &exprBinary{op: t.value, left: left, right: right}
+	if lit, ok := right.(*exprLiteral); ok && t.value == "matches" {
+		pattern, ok := lit.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("matches needs a string pattern at position %d", t.pos)
+		}
+		node.re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case "string":
+		return &exprLiteral{value: t.value}, nil
+	case "number":
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.value, t.pos)
+		}
+		return &exprLiteral{value: f}, nil
+	case "punct":
+		switch t.value {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect("punct", ")")
+		case "[":
+			list := &exprList{}
+			for !p.accept("punct", "]") {
+				if len(list.items) > 0 {
+					if err := p.expect("punct", ","); err != nil {
+						return nil, err
+					}
+				}
+				item, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+			}
+			return list, nil
+		}
+	case "ident":
+		switch t.value {
+		case "true":
+			return &exprLiteral{value: true}, nil
+		case "false":
+			return &exprLiteral{value: false}, nil
+		case "null":
+			return &exprLiteral{value: nil}, nil
+		case "has":
+			if err := p.expect("punct", "("); err != nil {
+				return nil, err
+			}
+			path, err := p.parsePath(p.next())
+			if err != nil {
+				return nil, err
+			}
+			return &exprHas{path: path}, p.expect("punct", ")")
+		}
+		return p.parsePath(t)
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+}
+
+func (p *exprParser) parsePath(root exprToken) (*exprPath, error) {
+	if root.kind != "ident" || (root.value != exprRootPayload && root.value != exprRootTags && root.value != exprRootHeaders) {
+		return nil, fmt.Errorf("path at position %d must start with $, tags or headers", root.pos)
+	}
+	path := &exprPath{root: root.value}
+	for {
+		switch {
+		case p.accept("punct", "."):
+			t := p.next()
+			if t.kind != "ident" {
+				return nil, fmt.Errorf("expected field name at position %d", t.pos)
+			}
+			path.segments = append(path.segments, t.value)
+		case p.accept("punct", "["):
+			t := p.next()
+			switch t.kind {
+			case "string":
+				path.segments = append(path.segments, t.value)
+			case "number":
+				index, err := strconv.Atoi(t.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q at position %d", t.value, t.pos)
+				}
+				path.segments = append(path.segments, index)
+			default:
+				return nil, fmt.Errorf("expected index or quoted field name at position %d", t.pos)
+			}
+			if err := p.expect("punct", "]"); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+}
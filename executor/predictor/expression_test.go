@@ -0,0 +1,77 @@
+package predictor
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func evalTestExpression(g *GomegaWithT, src string, body string, headers map[string][]string) bool {
+	expr, err := parseExpression(src)
+	g.Expect(err).Should(BeNil())
+	env := &exprEnv{
+		headers: headers,
+		payload: func() (interface{}, error) {
+			var v interface{}
+			err := json.Unmarshal([]byte(body), &v)
+			return v, err
+		},
+	}
+	res, err := expr.eval(env)
+	g.Expect(err).Should(BeNil())
+	return truthy(res)
+}
+
+func TestExpressionEval(t *testing.T) {
+	g := NewGomegaWithT(t)
+	body := `{"meta":{"tags":{"tenant":"acme","tier":2}},"data":{"names":["a","b"],"ndarray":[[1.5,20]]}}`
+	headers := map[string][]string{"X-Client-Version": {"3"}, "x-tenant": {"initech"}}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{`$.data.ndarray[0][1] > 10`, true},
+		{`$.data.ndarray[0][0] >= 2`, false},
+		{`$['data']['names'][1] == "b"`, true},
+		{`$.data.names contains 'a'`, true},
+		{`$.data.missing[3] == 1`, false},
+		{`tags.tenant == 'acme' && tags.tier < 3`, true},
+		{`tags.tenant in ['initech', 'globex']`, false},
+		{`headers['X-Tenant'] == 'initech'`, true},
+		{`headers.X-Client-Version >= 2`, true},
+		{`headers.x-client-version != 3`, false},
+		{`has(headers.X-Beta) || tags.tenant matches '^ac'`, true},
+		{`!has(tags.tier)`, false},
+		{`(tags.tier == 1 || tags.tier == 2) && !(tags.tenant == 'initech')`, true},
+		{`tags.tier`, true},
+		{`headers.X-Beta`, false},
+	}
+	for _, test := range tests {
+		g.Expect(evalTestExpression(g, test.expr, body, headers)).To(Equal(test.expected), test.expr)
+	}
+}
+
+func TestExpressionV2Parameters(t *testing.T) {
+	g := NewGomegaWithT(t)
+	body := `{"parameters":{"tenant":"acme"},"inputs":[{"name":"input-0","data":[1,2,3]}]}`
+	g.Expect(evalTestExpression(g, `tags.tenant == 'acme' && $.inputs[0].data[2] == 3`, body, nil)).To(BeTrue())
+}
+
+func TestExpressionParseErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	for _, src := range []string{
+		``,
+		`foo == 1`,
+		`$.data ==`,
+		`tags.tenant == 'acme`,
+		`(tags.tier == 1`,
+		`$.data[x]`,
+		`tags.tenant matches '['`,
+		`tags.tenant == 1 2`,
+	} {
+		_, err := parseExpression(src)
+		g.Expect(err).ShouldNot(BeNil(), src)
+	}
+}
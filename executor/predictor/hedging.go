@@ -0,0 +1,104 @@
+package predictor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const hedgingDefaultMaxHedges = 1
+
+var (
+	hedgingMetrics     *metric.HedgingMetrics
+	hedgingMetricsOnce sync.Once
+)
+
+func getHedgingMetrics() *metric.HedgingMetrics {
+	hedgingMetricsOnce.Do(func() {
+		hedgingMetrics = metric.NewHedgingMetrics()
+	})
+	return hedgingMetrics
+}
+
+type hedgedResult[T any] struct {
+	res   T
+	err   error
+	hedge int
+}
+
+// closeResults waits for the calls still running when a reply has been returned and closes any streamed responses
+// they return.
+func closeResults[T any](results chan hedgedResult[T], pending int) {
+	for ; pending > 0; pending-- {
+		r := <-results
+		if closer, ok := any(r.res).(io.Closer); ok && r.err == nil {
+			closer.Close()
+		}
+	}
+}
+
+// hedgedCall runs call and, if the node has a hedging policy and no reply arrives within its delay, sends duplicate
+// calls each a further delay apart. The first successful reply is returned and the other calls are cancelled. If all
+// calls fail the error of the last one to finish is returned.
+func hedgedCall[T any](p *PredictorProcess, node *v1.PredictiveUnit, ctx context.Context, call func(ctx context.Context) (T, error)) (T, error) {
+	if node.Hedging == nil || node.Hedging.DelayMs <= 0 {
+		return call(ctx)
+	}
+	maxHedges := int(node.Hedging.MaxHedges)
+	if maxHedges == 0 {
+		maxHedges = hedgingDefaultMaxHedges
+	}
+	delay := time.Duration(node.Hedging.DelayMs) * time.Millisecond
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Buffered so calls still running when a reply is returned do not block
+	results := make(chan hedgedResult[T], maxHedges+1)
+	send := func(hedge int) {
+		go func() {
+			res, err := call(ctx)
+			results <- hedgedResult[T]{res: res, err: err, hedge: hedge}
+		}()
+	}
+
+	send(0)
+	sent, pending := 1, 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var last hedgedResult[T]
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if r.hedge > 0 {
+					getHedgingMetrics().Win(node.Name)
+				}
+				if pending > 0 {
+					go closeResults(results, pending)
+				}
+				return r.res, nil
+			}
+			last = r
+			// Once every call sent has failed further attempts are left to the retry policy
+			if pending == 0 {
+				return last.res, last.err
+			}
+		case <-timer.C:
+			if sent <= maxHedges && ctx.Err() == nil {
+				p.Log.V(1).Info("Sending hedged call", "node", node.Name, "hedge", sent)
+				getHedgingMetrics().Hedge(node.Name)
+				send(sent)
+				sent++
+				pending++
+				if sent <= maxHedges {
+					timer.Reset(delay)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package predictor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func TestHedging(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createResilientModel("hedged-model")
+	graph.Hedging = &v1.HedgingPolicy{DelayMs: 10}
+
+	// The first call hangs so the hedge replies first
+	client := &flakyTestClient{failModel: "hedged-model", failures: 1, hang: true}
+	_, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["hedged-model"]).To(Equal(2))
+
+	// No hedge is sent for a fast reply
+	client = &flakyTestClient{failModel: "hedged-model", failures: 0}
+	_, err = createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["hedged-model"]).To(Equal(1))
+}
+
+func TestHedgingMaxHedges(t *testing.T) {
+	g := NewGomegaWithT(t)
+	timeout := int32(200)
+	graph := createResilientModel("hedged-max")
+	graph.Hedging = &v1.HedgingPolicy{DelayMs: 10, MaxHedges: 2}
+	graph.TimeoutMs = &timeout
+
+	client := &flakyTestClient{failModel: "hedged-max", failures: 2, hang: true}
+	_, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["hedged-max"]).To(Equal(3))
+
+	// All calls hang so the attempt times out after sending the maximum number of hedges
+	client = &flakyTestClient{failModel: "hedged-max", failures: -1, hang: true}
+	_, err = createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["hedged-max"]).To(Equal(3))
+}
@@ -4,7 +4,6 @@ import (
 	// "github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type GraphMetadata struct {
@@ -12,6 +11,7 @@ type GraphMetadata struct {
 	Models       map[string]payload.ModelMetadata `json:"models"`
 	GraphInputs  interface{}                      `json:"graphinputs"`
 	GraphOutputs interface{}                      `json:"graphoutputs"`
+	EdgeWarnings []EdgeWarning                    `json:"edgewarnings,omitempty"`
 }
 
 type MetadataTensor struct {
@@ -32,30 +32,48 @@ func (gm *GraphMetadata) getEdgeNodes(node *v1.PredictiveUnit) (
 	}
 
 	// Multi nodes graphs
-	if *node.Type == v1.MODEL || *node.Type == v1.TRANSFORMER {
-		// Ignore all children except first one for Models and Transformers
-		_, childOutput := gm.getEdgeNodes(&node.Children[0])
-		return &nodeMeta, childOutput
-	} else if *node.Type == v1.OUTPUT_TRANSFORMER {
-		// Ignore all children except first one for Output Transformers
-		// OUTPUT_TRANSFORMER first passes its input to (first) child and returns the output.
-		childInput, _ := gm.getEdgeNodes(&node.Children[0])
-		return childInput, &nodeMeta
-	} else if *node.Type == v1.COMBINER {
-		// Combiner will pass request to all of its children and combine their output.
+	switch graphNodeType(node) {
+	case v1.OUTPUT_TRANSFORMER, v1.COMBINER:
+		// OUTPUT_TRANSFORMER first passes its input to its children and transforms their output.
+		// COMBINER passes its input to all of its children and combines their output.
 		// We assume that all children take same type of inputs.
 		childInput, _ := gm.getEdgeNodes(&node.Children[0])
-
 		return childInput, &nodeMeta
-	} else if *node.Type == v1.ROUTER {
+	case v1.ROUTER:
 		// ROUTER will pass request to one of its children and return child's output.
-		// We assume that all children take same type of inputs.
-		childInput, childOutputs := gm.getEdgeNodes(&node.Children[0])
-		return childInput, childOutputs
+		// We assume that all children take same type of inputs and outputs.
+		return gm.getEdgeNodes(&node.Children[0])
+	default:
+		// Models and Transformers pass their output to their children.
+		// We assume that all children return same type of outputs.
+		_, childOutput := gm.getEdgeNodes(&node.Children[0])
+		return &nodeMeta, childOutput
 	}
+}
 
-	// If we got here it means none of the cases above
-	logger := log.Log.WithName("GraphMetadata")
-	logger.Info("Unimplemented case: Couldn't derive graph-level inputs and outputs.")
-	return nil, nil
+// graphNodeType returns how a node treats the messages it passes to and from its children. Nodes without a type are
+// typed by their methods or built-in implementation.
+func graphNodeType(node *v1.PredictiveUnit) v1.PredictiveUnitType {
+	if node.Type != nil && *node.Type != v1.UNKNOWN_TYPE {
+		return *node.Type
+	}
+	if node.Implementation != nil {
+		switch *node.Implementation {
+		case v1.SIMPLE_ROUTER, v1.RANDOM_ABTEST, v1.EPSILON_GREEDY, v1.THOMPSON_SAMPLING, v1.CONDITIONAL_ROUTER:
+			return v1.ROUTER
+		case v1.AVERAGE_COMBINER:
+			return v1.COMBINER
+		}
+	}
+	switch {
+	case hasMethod(v1.ROUTE, node.Methods):
+		return v1.ROUTER
+	case hasMethod(v1.AGGREGATE, node.Methods):
+		return v1.COMBINER
+	case hasMethod(v1.TRANSFORM_OUTPUT, node.Methods) && !hasMethod(v1.TRANSFORM_INPUT, node.Methods):
+		return v1.OUTPUT_TRANSFORMER
+	case hasMethod(v1.TRANSFORM_INPUT, node.Methods):
+		return v1.TRANSFORMER
+	}
+	return v1.MODEL
 }
@@ -629,3 +629,106 @@ func TestGraphV1ArrayStringMix(t *testing.T) {
 
 	g.Expect(actualJson).To(MatchJSON(expectedJson))
 }
+
+func TestGraphMetadataEdgeWarnings(t *testing.T) {
+	t.Logf("Started")
+	g := NewGomegaWithT(t)
+	model := v1.MODEL
+
+	models := map[string]payload.ModelMetadata{
+		"router": {
+			Name:   "router",
+			Inputs: []MetadataTensor{{Name: "input", DataType: "BYTES", Shape: []int{1, 5}}},
+		},
+		"model-1":  metadataMap["model-1"],
+		"model-a1": metadataMap["model-a1"],
+		"model-2-v2": {
+			Name:   "model-2-v2",
+			Inputs: []MetadataTensor{{Name: "input", DataType: "BYTES", Shape: []int{1, 4}}},
+		},
+	}
+	spec := &v1.PredictorSpec{
+		Name: "predictor-name",
+		Graph: v1.PredictiveUnit{
+			Name:     "router",
+			Methods:  &[]v1.PredictiveUnitMethod{v1.ROUTE},
+			Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+			Children: []v1.PredictiveUnit{
+				{
+					Name:     "model-1",
+					Type:     &model,
+					Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9001},
+					Children: []v1.PredictiveUnit{
+						{
+							Name:     "model-2-v2",
+							Type:     &model,
+							Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9002},
+						},
+					},
+				},
+				{
+					Name:     "model-a1",
+					Type:     &model,
+					Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9003},
+				},
+			},
+		},
+	}
+
+	pp := createPredictorProcessWithMetadata(t, nil, models)
+	graphMetadata, err := pp.GraphMetadata(spec)
+	g.Expect(err).Should(BeNil())
+	g.Expect(graphMetadata.EdgeWarnings).To(Equal([]EdgeWarning{
+		{Parent: "model-1", Child: "model-2-v2", Message: `tensor "output" is sent with shape [1 3] but [1 4] is expected`},
+	}))
+
+	g.Expect(pp.ReadyEdges(spec)).Should(BeNil())
+	SetEdgeReadinessGate(true)
+	defer SetEdgeReadinessGate(false)
+	g.Expect(pp.ReadyEdges(spec)).To(MatchError(ContainSubstring("have not been checked yet")))
+	g.Eventually(func() error { return pp.ReadyEdges(spec) }).Should(MatchError(ContainSubstring("1 incompatible edges")))
+
+	// The result is kept for the graph until it is refreshed
+	models["model-2-v2"] = metadataMap["model-2"]
+	g.Expect(pp.ReadyEdges(spec)).ShouldNot(BeNil())
+	SetEdgeReadinessGate(true)
+	g.Eventually(func() error { return pp.ReadyEdges(spec) }).Should(BeNil())
+}
+
+func TestReadyEdgesWithBuiltInRoot(t *testing.T) {
+	g := NewGomegaWithT(t)
+	model := v1.MODEL
+	combiner := v1.AVERAGE_COMBINER
+	spec := &v1.PredictorSpec{
+		Name: "predictor-name",
+		Graph: v1.PredictiveUnit{
+			Name:           "combiner",
+			Implementation: &combiner,
+			Children: []v1.PredictiveUnit{
+				{
+					Name:     "model-1",
+					Type:     &model,
+					Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9001},
+				},
+			},
+		},
+	}
+
+	pp := createPredictorProcessWithMetadata(t, nil, map[string]payload.ModelMetadata{"model-1": metadataMap["model-1"]})
+	SetEdgeReadinessGate(true)
+	defer SetEdgeReadinessGate(false)
+	g.Eventually(func() error { return pp.ReadyEdges(spec) }).Should(BeNil())
+}
+
+func TestCompareSeldonSignatures(t *testing.T) {
+	t.Logf("Started")
+	g := NewGomegaWithT(t)
+
+	sent := signatureSpecs(map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"names": []string{"a"}, "shape": []int{3}}})
+	g.Expect(compareSignatures(sent, signatureSpecs(map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"shape": []int{-1, 3}}}))).To(BeEmpty())
+	g.Expect(compareSignatures(sent, signatureSpecs(map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"shape": []int{4}}}))).To(Equal([]string{"data is sent with shape [3] but [4] is expected"}))
+	g.Expect(compareSignatures(sent, signatureSpecs([]interface{}{map[string]interface{}{"messagetype": "jsonData"}}))).To(Equal([]string{"ndarray is sent but jsonData is expected"}))
+	// Custom message types and signatures of different protocols are not compared
+	g.Expect(compareSignatures(sent, signatureSpecs(map[string]interface{}{"messagetype": "custom"}))).To(BeEmpty())
+	g.Expect(compareSignatures(sent, signatureSpecs([]MetadataTensor{{Name: "x", DataType: "FP32"}}))).To(BeEmpty())
+}
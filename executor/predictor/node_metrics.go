@@ -0,0 +1,71 @@
+package predictor
+
+import (
+	"sync"
+
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	nodeMetrics               *metric.NodeMetrics
+	nodeMetricsPredictor      = &v1.PredictorSpec{}
+	nodeMetricsDeploymentName string
+	nodeMetricsMutex          sync.RWMutex
+)
+
+// SetNodeMetricsPredictor sets the deployment and predictor the metrics of graph nodes are labelled with.
+func SetNodeMetricsPredictor(spec *v1.PredictorSpec, deploymentName string) {
+	nodeMetricsMutex.Lock()
+	defer nodeMetricsMutex.Unlock()
+	nodeMetricsPredictor = spec
+	nodeMetricsDeploymentName = deploymentName
+	nodeMetrics = nil
+}
+
+func getNodeMetrics() *metric.NodeMetrics {
+	nodeMetricsMutex.RLock()
+	m := nodeMetrics
+	nodeMetricsMutex.RUnlock()
+	if m != nil {
+		return m
+	}
+	nodeMetricsMutex.Lock()
+	defer nodeMetricsMutex.Unlock()
+	if nodeMetrics == nil {
+		nodeMetrics = metric.NewNodeMetrics(nodeMetricsPredictor, nodeMetricsDeploymentName)
+	}
+	return nodeMetrics
+}
+
+// recordNodeMetrics records the payload sizes of a call to a node, or the status of its error. The sizes of streamed
+// responses are not known and are left out.
+func (p *PredictorProcess) recordNodeMetrics(node *v1.PredictiveUnit, method string, req []payload.SeldonPayload, res payload.SeldonPayload, err error) {
+	m := getNodeMetrics()
+	requestBytes := 0
+	for _, msg := range req {
+		requestBytes += payloadSize(msg)
+	}
+	m.ObserveRequest(p.Ctx, node.Name, method, requestBytes)
+	if err != nil {
+		code, ok := errorStatus(err)
+		if !ok {
+			code = normalizeStatus(codes.Unknown.String())
+		}
+		m.Error(node.Name, method, code)
+		return
+	}
+	if _, ok := res.(*payload.StreamPayload); res != nil && !ok {
+		m.ObserveResponse(p.Ctx, node.Name, method, payloadSize(res))
+	}
+}
+
+// recordRouteMetrics records the route chosen by a router, or the status of its error.
+func (p *PredictorProcess) recordRouteMetrics(node *v1.PredictiveUnit, msg payload.SeldonPayload, route int, err error) {
+	p.recordNodeMetrics(node, traceMethodRoute, []payload.SeldonPayload{msg}, nil, err)
+	if err == nil {
+		getNodeMetrics().Route(node.Name, route)
+	}
+}
@@ -0,0 +1,63 @@
+package predictor
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func TestNodeMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+	router := v1.ROUTER
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "metrics-router",
+		Type:     &router,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+		Children: []v1.PredictiveUnit{
+			{Name: "metrics-model-a", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9001}},
+			{Name: "metrics-model-b", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9002}},
+		},
+	}
+	m := getNodeMetrics()
+	route0 := testutil.ToFloat64(m.Routes.WithLabelValues("metrics-router", "0"))
+	route1 := testutil.ToFloat64(m.Routes.WithLabelValues("metrics-router", "1"))
+	errs := testutil.ToFloat64(m.Errors.WithLabelValues("metrics-model-a", traceMethodPredict, "UNKNOWN"))
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`), ContentType: "application/json"}
+
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{ChosenRoute: 1}, nil)
+	_, err := pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(testutil.ToFloat64(m.Routes.WithLabelValues("metrics-router", "1")) - route1).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(m.Routes.WithLabelValues("metrics-router", "0")) - route0).To(Equal(0.0))
+	g.Expect(testutil.CollectAndCount(m.RequestBytes, "seldon_api_executor_node_request_bytes")).To(BeNumerically(">=", 2))
+	g.Expect(testutil.ToFloat64(m.InFlight.WithLabelValues("metrics-model-b"))).To(Equal(0.0))
+
+	errMethod := v1.TRANSFORM_INPUT
+	client := &test.SeldonMessageTestClient{ChosenRoute: 0, ErrMethod: &errMethod, Err: errors.New("model failed")}
+	pp = createTracedPredictorProcess(t, client, nil)
+	_, err = pp.Predict(graph, msg)
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(testutil.ToFloat64(m.Routes.WithLabelValues("metrics-router", "0")) - route0).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(m.Errors.WithLabelValues("metrics-model-a", traceMethodPredict, "UNKNOWN")) - errs).To(Equal(1.0))
+}
+
+func TestNodeMetricsPredictorLabels(t *testing.T) {
+	g := NewGomegaWithT(t)
+	SetNodeMetricsPredictor(&v1.PredictorSpec{Name: "p", Annotations: map[string]string{"version": "v2"}}, "dep")
+	defer SetNodeMetricsPredictor(&v1.PredictorSpec{}, "")
+
+	labelled := getNodeMetrics()
+	before := testutil.ToFloat64(labelled.Routes.WithLabelValues("labels-router", "1"))
+	labelled.Route("labels-router", 1)
+	g.Expect(testutil.ToFloat64(labelled.Routes.WithLabelValues("labels-router", "1")) - before).To(Equal(1.0))
+
+	// The same node is counted separately for another predictor
+	SetNodeMetricsPredictor(&v1.PredictorSpec{}, "")
+	g.Expect(testutil.ToFloat64(getNodeMetrics().Routes.WithLabelValues("labels-router", "1"))).To(Equal(0.0))
+}
@@ -0,0 +1,50 @@
+package predictor
+
+import "sync"
+
+// nodeStates holds a state for each node of the graph, keyed by node name. A PredictorProcess only lives for a single
+// request, so state that has to outlast requests, such as circuit breakers, caches and statistics, is kept here for
+// the lifetime of the executor.
+type nodeStates[T any] struct {
+	mu     sync.Mutex
+	states map[string]*T
+}
+
+func newNodeStates[T any]() *nodeStates[T] {
+	return &nodeStates[T]{states: make(map[string]*T)}
+}
+
+// get returns the state of the named node, creating it the first time.
+func (s *nodeStates[T]) get(name string, create func() *T) *T {
+	return s.getValid(name, nil, create)
+}
+
+// getValid returns the state of the named node, creating it the first time or again once valid reports it no longer
+// fits the node.
+func (s *nodeStates[T]) getValid(name string, valid func(*T) bool, create func() *T) *T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	if !ok || (valid != nil && !valid(state)) {
+		state = create()
+		s.states[name] = state
+	}
+	return state
+}
+
+// all returns the states of every node so far.
+func (s *nodeStates[T]) all() map[string]*T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make(map[string]*T, len(s.states))
+	for name, state := range s.states {
+		states[name] = state
+	}
+	return states
+}
+
+func (s *nodeStates[T]) delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, name)
+}
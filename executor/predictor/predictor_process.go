@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	guuid "github.com/google/uuid"
@@ -13,6 +14,7 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/util"
+	"go.opentelemetry.io/otel/attribute"
 
 	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
@@ -49,19 +51,22 @@ type PredictorProcess struct {
 	Routing           map[string]int32
 	RoutingMutex      *sync.RWMutex
 	ModelNameOverride string
+	Trace             *ExecutionTrace
 }
 
 func NewPredictorProcess(context context.Context, client client.SeldonApiClient, log logr.Logger, serverUrl *url.URL, namespace string, meta map[string][]string, modelNameOverride string) PredictorProcess {
+	metaData := payload.NewFromMap(meta)
 	return PredictorProcess{
 		Ctx:               context,
 		Client:            client,
 		Log:               log,
 		ServerUrl:         serverUrl,
 		Namespace:         namespace,
-		Meta:              payload.NewFromMap(meta),
+		Meta:              metaData,
 		Routing:           make(map[string]int32),
 		RoutingMutex:      &sync.RWMutex{},
 		ModelNameOverride: modelNameOverride,
+		Trace:             newExecutionTrace(metaData),
 	}
 }
 
@@ -76,8 +81,8 @@ func hasMethod(method v1.PredictiveUnitMethod, methods *[]v1.PredictiveUnitMetho
 	return false
 }
 
-func (p *PredictorProcess) getPort(node *v1.PredictiveUnit) int32 {
-	if p.Client.IsGrpc() {
+func getPort(c client.SeldonApiClient, node *v1.PredictiveUnit) int32 {
+	if c.IsGrpc() {
 		return node.Endpoint.GrpcPort
 	} else {
 		return node.Endpoint.HttpPort
@@ -106,10 +111,11 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 	if hasMethod(v1.TRANSFORM_INPUT, node.Methods) {
 		callTransformInput = true
 	}
+	simpleModel := hasImplementation(node, v1.SIMPLE_MODEL)
 
 	modelName := p.getModelName(node)
 
-	if callModel || callTransformInput {
+	if callModel || callTransformInput || simpleModel {
 		msg, err := p.Client.Chain(p.Ctx, modelName, msg)
 		if err != nil {
 			return nil, err
@@ -127,11 +133,27 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 		p.Routing[node.Name] = -1
 		p.RoutingMutex.Unlock()
 
+		method := traceMethodPredict
 		if callTransformInput {
-			tmsg, err = p.Client.TransformInput(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+			method = traceMethodTransformInput
+		}
+		sp, span := p.startNodeSpan(node, method)
+		start := time.Now()
+		if simpleModel {
+			tmsg, err = sp.simpleModel(node, msg)
 		} else {
-			tmsg, err = p.Client.Predict(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+			// Responses from the cache are not inferences of the model
+			tmsg, err = sp.cachedCall(node, msg, func() (res payload.SeldonPayload, err error) {
+				defer func() { getModelStatistics(modelName).recordInference(start, err) }()
+				if callTransformInput {
+					return sp.transformInputNode(node, msg)
+				}
+				return sp.batchedPredict(node, msg)
+			})
 		}
+		endNodeSpan(span, []payload.SeldonPayload{msg}, tmsg, err)
+		sp.recordNodeMetrics(node, method, []payload.SeldonPayload{msg}, tmsg, err)
+		p.Trace.record(node.Name, method, start, []payload.SeldonPayload{msg}, tmsg, err)
 		if tmsg != nil && err == nil {
 			// Log Response
 			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
@@ -147,6 +169,28 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 	}
 }
 
+// predictNode calls the node's predict endpoint.
+func (p *PredictorProcess) predictNode(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	modelName := p.getModelName(node)
+	defer getModelStatistics(modelName).recordExecution(time.Now())
+	return p.translatedCall(node, msg, func(c client.SeldonApiClient, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+		return callNode(p, node, func(ctx context.Context) (payload.SeldonPayload, error) {
+			return c.Predict(ctx, modelName, node.Endpoint.ServiceHost, getPort(c, node), msg, p.Meta.Meta)
+		})
+	})
+}
+
+// transformInputNode calls the node's transform input endpoint.
+func (p *PredictorProcess) transformInputNode(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	modelName := p.getModelName(node)
+	defer getModelStatistics(modelName).recordExecution(time.Now())
+	return p.translatedCall(node, msg, func(c client.SeldonApiClient, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+		return callNode(p, node, func(ctx context.Context) (payload.SeldonPayload, error) {
+			return c.TransformInput(ctx, modelName, node.Endpoint.ServiceHost, getPort(c, node), msg, p.Meta.Meta)
+		})
+	})
+}
+
 func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
 	callClient := false
 	if (*node).Type != nil {
@@ -175,7 +219,16 @@ func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.
 			}
 		}
 
-		tmsg, err := p.Client.TransformOutput(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		sp, span := p.startNodeSpan(node, traceMethodTransformOutput)
+		start := time.Now()
+		tmsg, err := sp.translatedCall(node, msg, func(c client.SeldonApiClient, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+			return callNode(sp, node, func(ctx context.Context) (payload.SeldonPayload, error) {
+				return c.TransformOutput(ctx, modelName, node.Endpoint.ServiceHost, getPort(c, node), msg, p.Meta.Meta)
+			})
+		})
+		endNodeSpan(span, []payload.SeldonPayload{msg}, tmsg, err)
+		sp.recordNodeMetrics(node, traceMethodTransformOutput, []payload.SeldonPayload{msg}, tmsg, err)
+		p.Trace.record(node.Name, traceMethodTransformOutput, start, []payload.SeldonPayload{msg}, tmsg, err)
 		if tmsg != nil && err == nil {
 			// Log Response
 			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
@@ -204,17 +257,54 @@ func (p *PredictorProcess) feedback(node *v1.PredictiveUnit, msg payload.SeldonP
 		callClient = true
 	}
 
-	modelName := p.getModelName(node)
-
-	if callClient {
-		return p.Client.Feedback(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+	// Feedback is only defined for the Seldon protocol so is not sent to nodes with a translated protocol
+	from, to, err := p.nodeEndpoints(node)
+	if err != nil || from.protocol != to.protocol {
+		callClient = false
+	}
+
+	if isBandit(node) {
+		sp, span := p.startNodeSpan(node, traceMethodFeedback)
+		res, err := sp.banditFeedback(node, msg)
+		endNodeSpan(span, []payload.SeldonPayload{msg}, res, err)
+		sp.recordNodeMetrics(node, traceMethodFeedback, []payload.SeldonPayload{msg}, res, err)
+		return res, err
+	} else if callClient {
+		sp, span := p.startNodeSpan(node, traceMethodFeedback)
+		res, err := sp.feedbackNode(node, msg, from, to)
+		endNodeSpan(span, []payload.SeldonPayload{msg}, res, err)
+		sp.recordNodeMetrics(node, traceMethodFeedback, []payload.SeldonPayload{msg}, res, err)
+		return res, err
 	} else {
 		return msg, nil
 	}
 
 }
 
+// feedbackNode calls the node's send feedback endpoint.
+func (p *PredictorProcess) feedbackNode(node *v1.PredictiveUnit, msg payload.SeldonPayload, from nodeEndpoint, to nodeEndpoint) (payload.SeldonPayload, error) {
+	var err error
+	fmsg := msg
+	if !from.same(to) {
+		if fmsg, err = payload.ConvertFeedback(msg, to.grpc); err != nil {
+			return nil, &translationError{node: node.Name, err: err}
+		}
+	}
+	res, err := to.client.Feedback(p.Ctx, p.getModelName(node), node.Endpoint.ServiceHost, getPort(to.client, node), fmsg, p.Meta.Meta)
+	if err != nil {
+		return nil, err
+	}
+	return convertResponse(node, res, from, to)
+}
+
 func (p *PredictorProcess) routeFeedback(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+	if isBandit(node) {
+		fb, err := feedbackFromPayload(msg)
+		if err != nil {
+			return 0, err
+		}
+		return p.banditRouteFeedback(node, fb), nil
+	}
 	if msg.GetContentType() == payload.APPLICATION_TYPE_PROTOBUF {
 		return util.RouteFromFeedbackMessageMeta(msg.GetPayload().(*proto.Feedback), node.Name), nil
 	} else {
@@ -236,10 +326,26 @@ func (p *PredictorProcess) route(node *v1.PredictiveUnit, msg payload.SeldonPayl
 
 	modelName := p.getModelName(node)
 
-	if callClient {
-		return p.Client.Route(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
-	} else if node.Implementation != nil && *node.Implementation == v1.RANDOM_ABTEST {
-		return p.abTestRouter(node)
+	if hasImplementation(node, v1.RANDOM_ABTEST) {
+		return p.abTestRouter(node, msg)
+	} else if hasImplementation(node, v1.SIMPLE_ROUTER) {
+		return p.simpleRouter(node)
+	} else if isBandit(node) {
+		return p.banditRouter(node)
+	} else if hasImplementation(node, v1.CONDITIONAL_ROUTER) {
+		return p.conditionalRouter(node, msg)
+	} else if callClient {
+		from, to, err := p.nodeEndpoints(node)
+		if err != nil {
+			return 0, err
+		}
+		tmsg, err := convertRequest(node, msg, from, to)
+		if err != nil {
+			return 0, err
+		}
+		return callNode(p, node, func(ctx context.Context) (int, error) {
+			return to.client.Route(ctx, modelName, node.Endpoint.ServiceHost, getPort(to.client, node), tmsg, p.Meta.Meta)
+		})
 	} else {
 		return -1, nil
 	}
@@ -256,10 +362,9 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 	if hasMethod(v1.AGGREGATE, node.Methods) {
 		callClient = true
 	}
+	averageCombiner := hasImplementation(node, v1.AVERAGE_COMBINER)
 
-	modelName := p.getModelName(node)
-
-	if callClient {
+	if callClient || averageCombiner {
 		//Log Request
 		if node.Logger != nil && (node.Logger.Mode == v1.LogRequest || node.Logger.Mode == v1.LogAll) {
 			err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceRequest, msg, puid)
@@ -270,7 +375,18 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 		p.RoutingMutex.Lock()
 		p.Routing[node.Name] = -1
 		p.RoutingMutex.Unlock()
-		tmsg, err := p.Client.Combine(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), cmsg, p.Meta.Meta)
+		var tmsg payload.SeldonPayload
+		var err error
+		sp, span := p.startNodeSpan(node, traceMethodAggregate)
+		start := time.Now()
+		if averageCombiner {
+			tmsg, err = sp.averageCombiner(node, cmsg)
+		} else {
+			tmsg, err = sp.combineNode(node, cmsg)
+		}
+		endNodeSpan(span, cmsg, tmsg, err)
+		sp.recordNodeMetrics(node, traceMethodAggregate, cmsg, tmsg, err)
+		p.Trace.record(node.Name, traceMethodAggregate, start, cmsg, tmsg, err)
 		if tmsg != nil && err == nil {
 			// Log Response
 			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
@@ -286,6 +402,28 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 	}
 }
 
+// combineNode calls the node's aggregate endpoint.
+func (p *PredictorProcess) combineNode(node *v1.PredictiveUnit, cmsg []payload.SeldonPayload) (payload.SeldonPayload, error) {
+	modelName := p.getModelName(node)
+	from, to, err := p.nodeEndpoints(node)
+	if err != nil {
+		return nil, err
+	}
+	tmsgs := make([]payload.SeldonPayload, len(cmsg))
+	for i, msg := range cmsg {
+		if tmsgs[i], err = convertRequest(node, msg, from, to); err != nil {
+			return nil, err
+		}
+	}
+	res, err := callNode(p, node, func(ctx context.Context) (payload.SeldonPayload, error) {
+		return to.client.Combine(ctx, modelName, node.Endpoint.ServiceHost, getPort(to.client, node), tmsgs, p.Meta.Meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return convertResponse(node, res, from, to)
+}
+
 func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
 	if node.Children != nil && len(node.Children) > 0 {
 		//Log Request
@@ -295,7 +433,18 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 				return nil, err
 			}
 		}
-		route, err := p.route(node, msg)
+		var route int
+		var err error
+		if graphNodeType(node) == v1.ROUTER {
+			sp, span := p.startNodeSpan(node, traceMethodRoute)
+			start := time.Now()
+			route, err = sp.route(node, msg)
+			endRouteSpan(span, msg, route, err)
+			sp.recordRouteMetrics(node, msg, route, err)
+			p.Trace.recordRoute(node.Name, start, msg, route, err)
+		} else {
+			route, err = p.route(node, msg)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -315,6 +464,7 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 			p.RoutingMutex.Lock()
 			p.Routing[node.Name] = -1
 			p.RoutingMutex.Unlock()
+			recordRoutingDecision(node.Name, -1)
 			for i, err := range errs {
 				if err != nil {
 					return cmsgs[i], err
@@ -325,6 +475,7 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 			p.RoutingMutex.Lock()
 			p.Routing[node.Name] = -2
 			p.RoutingMutex.Unlock()
+			recordRoutingDecision(node.Name, -2)
 			return msg, nil
 		} else { // Calls SeldonApiClient.Predict.
 			cmsgs = make([]payload.SeldonPayload, 1)
@@ -332,6 +483,7 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 			p.RoutingMutex.Lock()
 			p.Routing[node.Name] = int32(route)
 			p.RoutingMutex.Unlock()
+			recordRoutingDecision(node.Name, int32(route))
 			if err != nil {
 				return cmsgs[0], err
 			}
@@ -407,15 +559,11 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 		return nil
 	}
 
-	data, err := msg.GetBytes()
-	if err != nil {
-		return err
-	}
 	logUrl, err := p.getLogUrl(logger)
 	if err != nil {
 		return err
 	}
-	go func() {
+	queueLogRequest := func(data []byte) {
 		err := payloadLogger.QueueLogRequest(payloadLogger.LogRequest{
 			Url:             logUrl,
 			Bytes:           &data,
@@ -430,7 +578,20 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 		if err != nil {
 			p.Log.Error(err, "failed to log request")
 		}
-	}()
+	}
+
+	// A streamed response is logged in full once it has been read to the end
+	if stream, ok := msg.(*payload.StreamPayload); ok {
+		stream.OnComplete(func(data []byte) {
+			go queueLogRequest(data)
+		})
+		return nil
+	}
+	data, err := msg.GetBytes()
+	if err != nil {
+		return err
+	}
+	go queueLogRequest(data)
 	return nil
 }
 
@@ -443,6 +604,21 @@ func (p *PredictorProcess) getPUIDHeader() (string, error) {
 }
 
 func (p *PredictorProcess) Predict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	p.shadow(node, msg)
+	sp, span := p.startNodeSpan(node, "")
+	response, err := sp.predict(node, msg)
+	if err != nil && node.Fallback != nil && p.Ctx.Err() == nil {
+		p.Log.Info("Calling fallback", "node", node.Name, "fallback", node.Fallback.Name, "error", err.Error())
+		p.Trace.recordFallback(node.Name, node.Fallback.Name, err)
+		span.SetAttributes(attribute.String(spanAttrFallback, node.Fallback.Name))
+		endNodeSpan(span, []payload.SeldonPayload{msg}, response, err)
+		return p.Predict(node.Fallback, msg)
+	}
+	endNodeSpan(span, []payload.SeldonPayload{msg}, response, err)
+	return response, err
+}
+
+func (p *PredictorProcess) predict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
 	puid, err := p.getPUIDHeader()
 	if err != nil {
 		return nil, err
@@ -470,16 +646,20 @@ func (p *PredictorProcess) Predict(node *v1.PredictiveUnit, msg payload.SeldonPa
 func (p *PredictorProcess) Status(node *v1.PredictiveUnit, modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
 	if nodeModel := v1.GetPredictiveUnit(node, modelName); nodeModel == nil {
 		return nil, fmt.Errorf("Failed to find model %s", modelName)
+	} else if c, err := p.nodeClient(nodeModel); err != nil {
+		return nil, err
 	} else {
-		return p.Client.Status(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, p.getPort(nodeModel), msg, p.Meta.Meta)
+		return c.Status(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, getPort(c, nodeModel), msg, p.Meta.Meta)
 	}
 }
 
 func (p *PredictorProcess) Metadata(node *v1.PredictiveUnit, modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
 	if nodeModel := v1.GetPredictiveUnit(node, modelName); nodeModel == nil {
 		return nil, fmt.Errorf("Failed to find model %s", modelName)
+	} else if c, err := p.nodeClient(nodeModel); err != nil {
+		return nil, err
 	} else {
-		return p.Client.Metadata(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, p.getPort(nodeModel), msg, p.Meta.Meta)
+		return c.Metadata(p.Ctx, modelName, nodeModel.Endpoint.ServiceHost, getPort(c, nodeModel), msg, p.Meta.Meta)
 	}
 }
 
@@ -497,6 +677,7 @@ func (p *PredictorProcess) GraphMetadata(spec *v1.PredictorSpec) (*GraphMetadata
 	inputNodeMeta, outputNodeMeta := output.getEdgeNodes(&spec.Graph)
 	output.GraphInputs = inputNodeMeta.Inputs
 	output.GraphOutputs = outputNodeMeta.Outputs
+	output.EdgeWarnings = output.checkEdges(&spec.Graph)
 
 	return output, nil
 }
@@ -515,24 +696,35 @@ func (p *PredictorProcess) Feedback(node *v1.PredictiveUnit, msg payload.SeldonP
 		}
 	}
 
-	tmsg, err := p.feedbackChildren(node, msg)
-	if err != nil {
-		return tmsg, err
+	sp, span := p.startNodeSpan(node, "")
+	tmsg, err := sp.feedbackChildren(node, msg)
+	if err == nil {
+		tmsg, err = sp.feedback(node, msg)
 	}
-	return p.feedback(node, msg)
+	endNodeSpan(span, []payload.SeldonPayload{msg}, tmsg, err)
+	return tmsg, err
 }
 
+// ModelMetadataMap returns the metadata of the node and its descendants, keyed by node name. Built-in nodes and nodes
+// without an endpoint have no metadata to fetch, so they are given empty metadata.
 func (p *PredictorProcess) ModelMetadataMap(node *v1.PredictiveUnit) (map[string]payload.ModelMetadata, error) {
-	resPayload, err := p.Client.ModelMetadata(p.Ctx, node.Name, node.Endpoint.ServiceHost, p.getPort(node), nil, p.Meta.Meta)
-	if err != nil {
-		return nil, err
+	resPayload := payload.ModelMetadata{Name: node.Name}
+	if node.Endpoint != nil && (node.Implementation == nil || *node.Implementation == v1.UNKNOWN_IMPLEMENTATION) {
+		c, err := p.nodeClient(node)
+		if err != nil {
+			return nil, err
+		}
+		resPayload, err = c.ModelMetadata(p.Ctx, node.Name, node.Endpoint.ServiceHost, getPort(c, node), nil, p.Meta.Meta)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var output = map[string]payload.ModelMetadata{
 		node.Name: resPayload,
 	}
-	for _, child := range node.Children {
-		childMeta, err := p.ModelMetadataMap(&child)
+	for _, child := range v1.GetSubUnits(node) {
+		childMeta, err := p.ModelMetadataMap(child)
 		if err != nil {
 			return nil, err
 		}
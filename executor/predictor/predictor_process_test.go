@@ -440,6 +440,80 @@ func TestABTest(t *testing.T) {
 	g.Expect(smRes.GetData().GetNdarray().Values[1].GetNumberValue()).Should(Equal(2.0))
 }
 
+func TestSimpleModelAndRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	simpleRouter := v1.SIMPLE_ROUTER
+	simpleModel := v1.SIMPLE_MODEL
+	graph := &v1.PredictiveUnit{
+		Name:           "router",
+		Implementation: &simpleRouter,
+		Children: []v1.PredictiveUnit{
+			{
+				Name:           "model",
+				Implementation: &simpleModel,
+			},
+			{
+				Name:           "unused",
+				Implementation: &simpleModel,
+			},
+		},
+	}
+
+	routed := RoutingTable()["router"][0]
+	pp := createPredictorProcess(t)
+	pResp, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	smRes := pResp.GetPayload().(*proto.SeldonMessage)
+	g.Expect(smRes.GetData().GetNames()).Should(Equal([]string{"class0", "class1", "class2"}))
+	g.Expect(smRes.GetData().GetTensor().GetValues()).Should(Equal([]float64{0.1, 0.9, 0.5}))
+	g.Expect(pp.Routing["router"]).Should(Equal(int32(0)))
+	g.Expect(RoutingTable()["router"][0]).Should(Equal(routed + 1))
+}
+
+func TestAverageCombiner(t *testing.T) {
+	g := NewGomegaWithT(t)
+	model := v1.MODEL
+	averageCombiner := v1.AVERAGE_COMBINER
+	graph := &v1.PredictiveUnit{
+		Name:           "combiner",
+		Implementation: &averageCombiner,
+		Children: []v1.PredictiveUnit{
+			{
+				Type: &model,
+				Endpoint: &v1.Endpoint{
+					ServiceHost: "foo2",
+					ServicePort: 9001,
+					Type:        v1.REST,
+				},
+			},
+			{
+				Type: &model,
+				Endpoint: &v1.Endpoint{
+					ServiceHost: "foo3",
+					ServicePort: 9002,
+					Type:        v1.REST,
+				},
+			},
+		},
+	}
+
+	pResp, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	smRes := pResp.GetPayload().(*proto.SeldonMessage)
+	g.Expect(smRes.GetData().GetNdarray().Values[0].GetNumberValue()).Should(Equal(1.1))
+	g.Expect(smRes.GetData().GetNdarray().Values[1].GetNumberValue()).Should(Equal(2.0))
+
+	reqPayload := &payload.BytesPayload{Msg: []byte(`{"data":{"tensor":{"shape":[1,2],"values":[1,3]}}}`), ContentType: test.TestContentType}
+	pResp, err = createPredictorProcess(t).Predict(graph, reqPayload)
+	g.Expect(err).Should(BeNil())
+	g.Expect(pResp.GetContentType()).Should(Equal(test.TestContentType))
+	var sm proto.SeldonMessage
+	err = jsonpb.UnmarshalString(string(pResp.GetPayload().([]byte)), &sm)
+	g.Expect(err).Should(BeNil())
+	g.Expect(sm.GetData().GetTensor().GetShape()).Should(Equal([]int32{1, 2}))
+	g.Expect(sm.GetData().GetTensor().GetValues()).Should(Equal([]float64{1, 3}))
+}
+
 func TestModelWithLogRequests(t *testing.T) {
 	t.Logf("Started")
 	g := NewGomegaWithT(t)
@@ -0,0 +1,230 @@
+package predictor
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The deployment's protocol and the clients for each protocol and transport nodes are called with. Payloads are
+// translated and converted on each call to a node with another protocol or transport so the rest of the graph only
+// sees the deployment's protocol over the transport of the request.
+var (
+	deploymentProtocol   string
+	protocolClients      = make(map[protocolClientKey]client.SeldonApiClient)
+	protocolClientsMutex sync.RWMutex
+)
+
+// Nodes may be called over either transport so there is a client for each.
+type protocolClientKey struct {
+	protocol string
+	grpc     bool
+}
+
+// SetDeploymentProtocol sets the protocol payloads are translated from for nodes that override it.
+func SetDeploymentProtocol(protocol string) {
+	protocolClientsMutex.Lock()
+	defer protocolClientsMutex.Unlock()
+	deploymentProtocol = protocol
+}
+
+// RegisterProtocolClient registers the client used to call nodes with the protocol over the client's transport.
+func RegisterProtocolClient(protocol string, c client.SeldonApiClient) {
+	protocolClientsMutex.Lock()
+	defer protocolClientsMutex.Unlock()
+	protocolClients[protocolClientKey{protocol: protocol, grpc: c.IsGrpc()}] = c
+}
+
+// NodeProtocols returns the protocols nodes in the graph override the deployment's protocol with.
+func NodeProtocols(node *v1.PredictiveUnit, protocol string) []string {
+	found := make(map[string]bool)
+	var protocols []string
+	var collect func(node *v1.PredictiveUnit)
+	collect = func(node *v1.PredictiveUnit) {
+		if p := string(node.Protocol); p != "" && !sameProtocol(p, protocol) && !found[p] {
+			found[p] = true
+			protocols = append(protocols, p)
+		}
+		for _, sub := range v1.GetSubUnits(node) {
+			collect(sub)
+		}
+	}
+	collect(node)
+	return protocols
+}
+
+func sameProtocol(a string, b string) bool {
+	isV2 := func(p string) bool { return p == api.ProtocolV2 || p == api.ProtocolKFServing }
+	return a == b || (isV2(a) && isV2(b))
+}
+
+// translationError is returned when a request can not be translated to a node's protocol or transport, which is a bad
+// request, or the node's response can not be translated back.
+type translationError struct {
+	node     string
+	response bool
+	err      error
+}
+
+func (e *translationError) Error() string {
+	if e.response {
+		return fmt.Sprintf("failed to translate response from %s: %s", e.node, e.err.Error())
+	}
+	return fmt.Sprintf("failed to translate request to %s: %s", e.node, e.err.Error())
+}
+
+func (e *translationError) Unwrap() error {
+	return e.err
+}
+
+func (e *translationError) HttpStatusCode() int {
+	if e.response {
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+func (e *translationError) GRPCStatus() *status.Status {
+	if e.response {
+		return status.New(codes.Internal, e.Error())
+	}
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// nodeEndpoint is a protocol and transport nodes are called with, and the client for them.
+type nodeEndpoint struct {
+	protocol string
+	grpc     bool
+	client   client.SeldonApiClient
+}
+
+func (e nodeEndpoint) same(o nodeEndpoint) bool {
+	return e.protocol == o.protocol && e.grpc == o.grpc
+}
+
+// protocolClient returns the client for the protocol and transport. Requests arrive with the deployment's protocol
+// over the transport of the process's own client, which is used for them. protocolClientsMutex must be held.
+func (p *PredictorProcess) protocolClient(protocol string, grpc bool, node *v1.PredictiveUnit) (client.SeldonApiClient, error) {
+	if protocol == deploymentProtocol && grpc == p.Client.IsGrpc() {
+		return p.Client, nil
+	}
+	c, ok := protocolClients[protocolClientKey{protocol: protocol, grpc: grpc}]
+	if !ok {
+		transport := "REST"
+		if grpc {
+			transport = "gRPC"
+		}
+		return nil, fmt.Errorf("no %s client for protocol %s of %s", transport, protocol, node.Name)
+	}
+	return c, nil
+}
+
+// nodeEndpoints returns the endpoint requests arrive with and the endpoint the node is called with. A node is called
+// with the deployment's protocol unless it overrides it, and over the transport of its endpoint type if it has one.
+// Both endpoints are the process's own until the deployment's protocol is set.
+func (p *PredictorProcess) nodeEndpoints(node *v1.PredictiveUnit) (nodeEndpoint, nodeEndpoint, error) {
+	protocolClientsMutex.RLock()
+	defer protocolClientsMutex.RUnlock()
+	from := nodeEndpoint{protocol: deploymentProtocol, grpc: p.Client.IsGrpc(), client: p.Client}
+	if deploymentProtocol == "" {
+		return from, from, nil
+	}
+	to := nodeEndpoint{protocol: string(node.Protocol), grpc: from.grpc}
+	if to.protocol == "" || sameProtocol(to.protocol, deploymentProtocol) {
+		to.protocol = deploymentProtocol
+	}
+	// Tensorflow protocol payloads can not be converted so those nodes are called over the transport of the request
+	if node.Endpoint != nil && node.Endpoint.Type != "" && to.protocol != api.ProtocolTensorflow {
+		to.grpc = node.Endpoint.Type == v1.GRPC
+	}
+	if to.same(from) {
+		return from, from, nil
+	}
+	var err error
+	to.client, err = p.protocolClient(to.protocol, to.grpc, node)
+	if err != nil {
+		return from, to, err
+	}
+	return from, to, nil
+}
+
+// nodeClient returns the client for the node's protocol over the transport of the request, which status and
+// metadata requests are sent with.
+func (p *PredictorProcess) nodeClient(node *v1.PredictiveUnit) (client.SeldonApiClient, error) {
+	protocolClientsMutex.RLock()
+	defer protocolClientsMutex.RUnlock()
+	protocol := string(node.Protocol)
+	if protocol == "" || deploymentProtocol == "" || sameProtocol(protocol, deploymentProtocol) {
+		return p.Client, nil
+	}
+	return p.protocolClient(protocol, p.Client.IsGrpc(), node)
+}
+
+// translatedCall calls the node with the request translated to the node's protocol and converted to the form for its
+// transport, then converts and translates the response back. Error responses are returned as the node sent them.
+func (p *PredictorProcess) translatedCall(node *v1.PredictiveUnit, msg payload.SeldonPayload, call func(c client.SeldonApiClient, msg payload.SeldonPayload) (payload.SeldonPayload, error)) (payload.SeldonPayload, error) {
+	from, to, err := p.nodeEndpoints(node)
+	if err != nil {
+		return nil, err
+	}
+	if from.same(to) {
+		return call(from.client, msg)
+	}
+
+	tmsg, err := payload.TranslateRequest(msg, from.protocol, to.protocol)
+	if err == nil {
+		tmsg, err = payload.ConvertRequest(tmsg, to.protocol, to.grpc)
+	}
+	if err != nil {
+		return nil, &translationError{node: node.Name, err: err}
+	}
+	// V2 gRPC requests name the model they are for while REST requests name it in the path
+	if req, ok := tmsg.GetPayload().(*inference.ModelInferRequest); ok && req.ModelName == "" {
+		req.ModelName = p.getModelName(node)
+	}
+	res, err := call(to.client, tmsg)
+	if err != nil || res == nil {
+		return res, err
+	}
+	tres, err := payload.ConvertResponse(res, to.protocol, from.grpc)
+	if err == nil {
+		tres, err = payload.TranslateResponse(tres, to.protocol, from.protocol)
+	}
+	if err != nil {
+		return nil, &translationError{node: node.Name, response: true, err: err}
+	}
+	return tres, nil
+}
+
+// convertRequest converts a request to the form for the node's transport. It is used for calls that only support the
+// deployment's protocol.
+func convertRequest(node *v1.PredictiveUnit, msg payload.SeldonPayload, from nodeEndpoint, to nodeEndpoint) (payload.SeldonPayload, error) {
+	if from.same(to) {
+		return msg, nil
+	}
+	tmsg, err := payload.ConvertRequest(msg, to.protocol, to.grpc)
+	if err != nil {
+		return nil, &translationError{node: node.Name, err: err}
+	}
+	return tmsg, nil
+}
+
+// convertResponse converts a node's response back to the form for the transport of the request.
+func convertResponse(node *v1.PredictiveUnit, res payload.SeldonPayload, from nodeEndpoint, to nodeEndpoint) (payload.SeldonPayload, error) {
+	if from.same(to) || res == nil {
+		return res, nil
+	}
+	tres, err := payload.ConvertResponse(res, from.protocol, from.grpc)
+	if err != nil {
+		return nil, &translationError{node: node.Name, response: true, err: err}
+	}
+	return tres, nil
+}
@@ -0,0 +1,171 @@
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// restTestClient records the JSON requests it is sent over REST and responds with them.
+type restTestClient struct {
+	test.SeldonMessageTestClient
+	requests []string
+}
+
+func (c *restTestClient) IsGrpc() bool {
+	return false
+}
+
+func (c *restTestClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	data := msg.GetPayload().([]byte)
+	c.requests = append(c.requests, string(data))
+	return &payload.BytesPayload{Msg: data, ContentType: "application/json"}, nil
+}
+
+// v2TestClient records the V2 requests it is sent and responds with the sum of each row of the input.
+type v2TestClient struct {
+	test.SeldonMessageTestClient
+	requests []string
+}
+
+func (c *v2TestClient) IsGrpc() bool {
+	return false
+}
+
+func (c *v2TestClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	data := msg.GetPayload().([]byte)
+	c.requests = append(c.requests, string(data))
+	var req struct {
+		Inputs []struct {
+			Shape []int     `json:"shape"`
+			Data  []float64 `json:"data"`
+		} `json:"inputs"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	input := req.Inputs[0]
+	cols := input.Shape[1]
+	var sums []float64
+	for i := 0; i < len(input.Data); i += cols {
+		sum := 0.0
+		for _, v := range input.Data[i : i+cols] {
+			sum += v
+		}
+		sums = append(sums, sum)
+	}
+	res, _ := json.Marshal(map[string]interface{}{
+		"outputs": []interface{}{map[string]interface{}{"name": "sum", "shape": []int{len(sums)}, "datatype": "FP64", "data": sums}},
+	})
+	return &payload.BytesPayload{Msg: res, ContentType: "application/json"}, nil
+}
+
+func setProtocolClients(t *testing.T, protocol string, clients map[string]client.SeldonApiClient) {
+	SetDeploymentProtocol(protocol)
+	for p, c := range clients {
+		RegisterProtocolClient(p, c)
+	}
+	t.Cleanup(func() {
+		protocolClientsMutex.Lock()
+		defer protocolClientsMutex.Unlock()
+		deploymentProtocol = ""
+		protocolClients = make(map[protocolClientKey]client.SeldonApiClient)
+	})
+}
+
+func TestTranslatedNodeProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+	v2Client := &v2TestClient{}
+	setProtocolClients(t, api.ProtocolSeldon, map[string]client.SeldonApiClient{api.ProtocolV2: v2Client})
+
+	transformer := v1.TRANSFORMER
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "transformer",
+		Type:     &transformer,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+		Children: []v1.PredictiveUnit{
+			{
+				Name:     "model",
+				Type:     &model,
+				Protocol: v1.ProtocolV2,
+				Endpoint: &v1.Endpoint{ServiceHost: "bar", ServicePort: 9000},
+			},
+		},
+	}
+	g.Expect(NodeProtocols(graph, api.ProtocolSeldon)).To(Equal([]string{api.ProtocolV2}))
+
+	serverUrl, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, &restTestClient{}, logf.Log.WithName("test"), serverUrl, "default", map[string][]string{}, "")
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2],[3,4]]}}`), ContentType: "application/json"}
+	res, err := pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(v2Client.requests).To(HaveLen(1))
+	g.Expect(v2Client.requests[0]).To(MatchJSON(`{"inputs":[{"name":"input-0","shape":[2,2],"datatype":"FP64","data":[1,2,3,4]}]}`))
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"meta":{},"data":{"names":["sum"],"ndarray":[3,7]}}`))
+
+	// Requests that have no V2 form are rejected before calling the node
+	msg = &payload.BytesPayload{Msg: []byte(`{"jsonData":{"a":1}}`), ContentType: "application/json"}
+	_, err = pp.Predict(graph, msg)
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(err.(*translationError).HttpStatusCode()).To(Equal(400))
+	g.Expect(v2Client.requests).To(HaveLen(1))
+}
+
+func TestTranslatedNodeProtocolMissingClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+	setProtocolClients(t, api.ProtocolSeldon, nil)
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Protocol: v1.ProtocolV2,
+		Endpoint: &v1.Endpoint{ServiceHost: "bar", ServicePort: 9000, Type: v1.REST},
+	}
+	serverUrl, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, &test.SeldonMessageTestClient{}, logf.Log.WithName("test"), serverUrl, "default", map[string][]string{}, "")
+	_, err := pp.Predict(graph, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1]]}}`), ContentType: "application/json"})
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestConvertedNodeTransport(t *testing.T) {
+	g := NewGomegaWithT(t)
+	restClient := &restTestClient{}
+	setProtocolClients(t, api.ProtocolSeldon, map[string]client.SeldonApiClient{api.ProtocolSeldon: restClient})
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "bar", ServicePort: 9000, Type: v1.REST},
+	}
+	serverUrl, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, &test.SeldonMessageTestClient{}, logf.Log.WithName("test"), serverUrl, "default", map[string][]string{}, "")
+
+	// The gRPC request is sent to the REST node as JSON and the response returned as protobuf
+	var sm proto.SeldonMessage
+	err := jsonpb.UnmarshalString(`{"data":{"ndarray":[[1,2]]}}`, &sm)
+	g.Expect(err).Should(BeNil())
+	res, err := pp.Predict(graph, &payload.ProtoPayload{Msg: &sm})
+	g.Expect(err).Should(BeNil())
+	g.Expect(restClient.requests).To(HaveLen(1))
+	g.Expect(restClient.requests[0]).To(MatchJSON(`{"data":{"ndarray":[[1,2]]}}`))
+	resMsg, ok := res.GetPayload().(*proto.SeldonMessage)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(resMsg.GetData().GetNdarray().GetValues()).To(HaveLen(1))
+}
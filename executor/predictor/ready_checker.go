@@ -8,33 +8,49 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
+// readyDialTimeout bounds each connection made to check a node so an unreachable node fails the check quickly.
+const readyDialTimeout = time.Second
+
 func Ready(protocol string, node *v1.PredictiveUnit, fullHealthCheck bool) error {
 	if !fullHealthCheck {
 		return ReadyTCP(node)
 	}
-	switch protocol {
+	for _, child := range readinessSubUnits(node) {
+		err := Ready(protocol, child, fullHealthCheck)
+		if err != nil {
+			return err
+		}
+	}
+	// Each node is checked with its own protocol's health endpoint
+	switch string(node.GetProtocol(v1.Protocol(protocol))) {
 	case api.ProtocolSeldon:
-		return ReadyHealth(node, "/api/v1.0/health/status")
+		return readyNodeHealth(node, "/api/v1.0/health/status")
 	case api.ProtocolTensorflow:
-		return ReadyTCP(node)
+		return readyNodeTCP(node)
 	case api.ProtocolV2, api.ProtocolKFServing:
-		return ReadyHealth(node, "/v2/health/ready")
+		return readyNodeHealth(node, "/v2/health/ready")
 	default:
 		return fmt.Errorf("Unknown protocol for health check: %s", protocol)
 	}
 }
 
 func ReadyTCP(node *v1.PredictiveUnit) error {
-	for _, child := range node.Children {
-		err := ReadyTCP(&child)
+	for _, child := range readinessSubUnits(node) {
+		err := ReadyTCP(child)
 		if err != nil {
 			return err
 		}
 	}
+	return readyNodeTCP(node)
+}
+
+func readyNodeTCP(node *v1.PredictiveUnit) error {
 	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0 {
-		c, err := net.Dial("tcp", fmt.Sprintf("%s:%d", node.Endpoint.ServiceHost, node.Endpoint.ServicePort))
+		address := net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort)))
+		c, err := net.DialTimeout("tcp", address, readyDialTimeout)
 		if err != nil {
 			return err
 		} else {
@@ -47,12 +63,16 @@ func ReadyTCP(node *v1.PredictiveUnit) error {
 }
 
 func ReadyHealth(node *v1.PredictiveUnit, healthPath string) error {
-	for _, child := range node.Children {
-		err := ReadyHealth(&child, healthPath)
+	for _, child := range readinessSubUnits(node) {
+		err := ReadyHealth(child, healthPath)
 		if err != nil {
 			return err
 		}
 	}
+	return readyNodeHealth(node, healthPath)
+}
+
+func readyNodeHealth(node *v1.PredictiveUnit, healthPath string) error {
 	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0 {
 		urlHealth := &url.URL{
 			Scheme: "http",
@@ -72,3 +92,42 @@ func ReadyHealth(node *v1.PredictiveUnit, healthPath string) error {
 		return nil
 	}
 }
+
+// readinessSubUnits returns the units below node that must be ready for it to be. Shadows are left out as their
+// failures never reach the caller.
+func readinessSubUnits(node *v1.PredictiveUnit) []*v1.PredictiveUnit {
+	var units []*v1.PredictiveUnit
+	for _, sub := range v1.GetSubUnits(node) {
+		if node.Shadow == nil || sub != &node.Shadow.Unit {
+			units = append(units, sub)
+		}
+	}
+	return units
+}
+
+// NodeHealth is the result of connecting to the endpoint of a node of the graph.
+type NodeHealth struct {
+	Node  string `json:"node"`
+	Host  string `json:"host"`
+	Port  int32  `json:"port"`
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+// GraphHealth connects to the endpoint of every node of the graph that has one, including shadows, and reports
+// whether each could be reached.
+func GraphHealth(node *v1.PredictiveUnit) []NodeHealth {
+	var health []NodeHealth
+	for _, n := range v1.GetPredictiveUnitList(node) {
+		if n.Endpoint == nil || n.Endpoint.ServiceHost == "" || n.Endpoint.ServicePort <= 0 {
+			continue
+		}
+		h := NodeHealth{Node: n.Name, Host: n.Endpoint.ServiceHost, Port: n.Endpoint.ServicePort, Ready: true}
+		if err := readyNodeTCP(n); err != nil {
+			h.Ready = false
+			h.Error = err.Error()
+		}
+		health = append(health, h)
+	}
+	return health
+}
@@ -0,0 +1,187 @@
+package predictor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// DefaultGraphReloadInterval is how often the file the graph is reloaded from is checked for changes.
+const DefaultGraphReloadInterval = 5 * time.Second
+
+// The predictor new requests run on once the graph has been reloaded. Requests load it once when they arrive so those
+// in flight during a reload finish on the graph they started on.
+var (
+	activePredictor       *v1.PredictorSpec
+	activePredictorHash   string
+	activePredictorLoaded time.Time
+	activePredictorMutex  sync.RWMutex
+)
+
+// ActiveGraph describes the graph requests are running on.
+type ActiveGraph struct {
+	Predictor string    `json:"predictor"`
+	Hash      string    `json:"hash"`
+	Loaded    time.Time `json:"loaded"`
+}
+
+// SetActivePredictor sets the predictor new requests run on.
+func SetActivePredictor(spec *v1.PredictorSpec) {
+	hash := GraphHash(spec)
+	activePredictorMutex.Lock()
+	activePredictor = spec
+	activePredictorHash = hash
+	activePredictorLoaded = time.Now()
+	activePredictorMutex.Unlock()
+
+	// The inputs of the replaced graph are no longer needed
+	requestValidationMutex.Lock()
+	defer requestValidationMutex.Unlock()
+	resetGraphInputs()
+}
+
+// ActivePredictor returns the predictor new requests run on, which is spec until another has been set.
+func ActivePredictor(spec *v1.PredictorSpec) *v1.PredictorSpec {
+	activePredictorMutex.RLock()
+	defer activePredictorMutex.RUnlock()
+	if activePredictor == nil {
+		return spec
+	}
+	return activePredictor
+}
+
+// GetActiveGraph describes the graph new requests run on, which is that of spec until another predictor has been set.
+func GetActiveGraph(spec *v1.PredictorSpec) ActiveGraph {
+	activePredictorMutex.RLock()
+	defer activePredictorMutex.RUnlock()
+	if activePredictor == nil {
+		return ActiveGraph{Predictor: spec.Name, Hash: GraphHash(spec)}
+	}
+	return ActiveGraph{Predictor: activePredictor.Name, Hash: activePredictorHash, Loaded: activePredictorLoaded}
+}
+
+// GraphHash returns the SHA-256 hash of the predictor's graph as JSON.
+func GraphHash(spec *v1.PredictorSpec) string {
+	data, err := json.Marshal(spec.Graph)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// GraphWatcher reloads the predictor from a file when it changes, such as a mounted ConfigMap. Each new graph is
+// validated against the active one and only applied if it is valid, otherwise requests stay on the active graph.
+type GraphWatcher struct {
+	predictorName string
+	filename      string
+	interval      time.Duration
+	log           logr.Logger
+	metrics       *metric.ReloadMetrics
+	data          []byte
+}
+
+// NewGraphWatcher creates a watcher of the file the predictor is reloaded from, which it checks for changes every
+// interval. The file is polled as ConfigMaps are updated by swapping a symlink to their data.
+func NewGraphWatcher(predictorName string, filename string, interval time.Duration, log logr.Logger) *GraphWatcher {
+	return &GraphWatcher{
+		predictorName: predictorName,
+		filename:      filename,
+		interval:      interval,
+		log:           log.WithName("GraphWatcher"),
+		metrics:       metric.NewReloadMetrics(),
+	}
+}
+
+// Watch checks the file for changes until stopped.
+func (w *GraphWatcher) Watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := w.check(); err != nil {
+				w.log.Error(err, "Graph reload rejected", "file", w.filename)
+			}
+		}
+	}
+}
+
+// check reloads the predictor if the file has changed, returning whether the new graph was applied.
+func (w *GraphWatcher) check() (bool, error) {
+	data, err := ioutil.ReadFile(w.filename)
+	if err != nil {
+		// The file may be missing for a moment while it is replaced
+		return false, nil
+	}
+	if bytes.Equal(data, w.data) {
+		return false, nil
+	}
+	w.data = data
+	spec, err := getPredictorFromFile(w.predictorName, w.filename)
+	if err != nil {
+		w.metrics.Rejected(w.predictorName)
+		return false, err
+	}
+	active := ActivePredictor(nil)
+	if active != nil && GraphHash(spec) == GraphHash(active) {
+		return false, nil
+	}
+	if err = validateReload(active, spec); err != nil {
+		w.metrics.Rejected(w.predictorName)
+		return false, err
+	}
+	SetActivePredictor(spec)
+	w.metrics.Applied(w.predictorName)
+	w.log.Info("Graph reloaded", "file", w.filename, "hash", GraphHash(spec))
+	return true, nil
+}
+
+// validateReload checks a reloaded predictor can replace the active one. The executor serves a single predictor with
+// clients set up for the protocols of its graph, so the predictor's name must not change and its nodes may only use
+// protocols there are clients for. Every node that is not implemented by the executor must have an endpoint.
+func validateReload(active *v1.PredictorSpec, spec *v1.PredictorSpec) error {
+	if active != nil && spec.Name != active.Name {
+		return fmt.Errorf("predictor name changed from %s to %s", active.Name, spec.Name)
+	}
+	names := make(map[string]bool)
+	for _, node := range v1.GetPredictiveUnitList(&spec.Graph) {
+		if node.Name == "" {
+			return fmt.Errorf("graph has a node without a name")
+		}
+		if names[node.Name] {
+			return fmt.Errorf("graph has more than one node named %s", node.Name)
+		}
+		names[node.Name] = true
+		if node.Implementation != nil && *node.Implementation != v1.UNKNOWN_IMPLEMENTATION {
+			continue
+		}
+		if node.Endpoint == nil || node.Endpoint.ServiceHost == "" || (node.Endpoint.ServicePort <= 0 && node.Endpoint.GrpcPort <= 0 && node.Endpoint.HttpPort <= 0) {
+			return fmt.Errorf("node %s has no endpoint", node.Name)
+		}
+	}
+	protocolClientsMutex.RLock()
+	defer protocolClientsMutex.RUnlock()
+	if deploymentProtocol == "" {
+		return nil
+	}
+	for _, protocol := range NodeProtocols(&spec.Graph, deploymentProtocol) {
+		_, rest := protocolClients[protocolClientKey{protocol: protocol, grpc: false}]
+		_, grpc := protocolClients[protocolClientKey{protocol: protocol, grpc: true}]
+		if !rest || !grpc {
+			return fmt.Errorf("graph uses protocol %s which the executor was not started with", protocol)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package predictor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createReloadPredictor(host string) *v1.PredictorSpec {
+	model := v1.MODEL
+	return &v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name:     "model",
+			Type:     &model,
+			Endpoint: &v1.Endpoint{ServiceHost: host, ServicePort: 9000},
+		},
+	}
+}
+
+func writePredictorFile(t *testing.T, filename string, spec *v1.PredictorSpec) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createGraphWatcher(t *testing.T, spec *v1.PredictorSpec) (*GraphWatcher, string) {
+	filename := filepath.Join(t.TempDir(), "predictor.json")
+	writePredictorFile(t, filename, spec)
+	SetActivePredictor(spec)
+	t.Cleanup(func() {
+		activePredictorMutex.Lock()
+		defer activePredictorMutex.Unlock()
+		activePredictor = nil
+	})
+	return NewGraphWatcher(spec.Name, filename, time.Second, logf.Log), filename
+}
+
+func TestGraphWatcherReload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createReloadPredictor("old")
+	w, filename := createGraphWatcher(t, spec)
+
+	// The file holds the graph already running
+	applied, err := w.check()
+	g.Expect(err).Should(BeNil())
+	g.Expect(applied).To(BeFalse())
+	g.Expect(GetActiveGraph(nil).Hash).To(Equal(GraphHash(spec)))
+
+	// Requests that started on the old graph keep it
+	inFlight := ActivePredictor(nil)
+	reloaded := createReloadPredictor("new")
+	writePredictorFile(t, filename, reloaded)
+	applied, err = w.check()
+	g.Expect(err).Should(BeNil())
+	g.Expect(applied).To(BeTrue())
+	g.Expect(ActivePredictor(nil).Graph.Endpoint.ServiceHost).To(Equal("new"))
+	g.Expect(inFlight.Graph.Endpoint.ServiceHost).To(Equal("old"))
+	g.Expect(GetActiveGraph(nil).Hash).To(Equal(GraphHash(reloaded)))
+	g.Expect(GraphHash(reloaded)).ToNot(Equal(GraphHash(spec)))
+
+	// Unchanged files are not reloaded again
+	applied, err = w.check()
+	g.Expect(err).Should(BeNil())
+	g.Expect(applied).To(BeFalse())
+}
+
+func TestGraphWatcherRejectsInvalidGraph(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	spec := createReloadPredictor("old")
+	w, filename := createGraphWatcher(t, spec)
+
+	invalid := createReloadPredictor("new")
+	invalid.Graph.Endpoint = nil
+	writePredictorFile(t, filename, invalid)
+	applied, err := w.check()
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(applied).To(BeFalse())
+	g.Expect(ActivePredictor(nil)).To(Equal(spec))
+
+	renamed := createReloadPredictor("new")
+	renamed.Name = "other"
+	writePredictorFile(t, filename, renamed)
+	applied, err = w.check()
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(applied).To(BeFalse())
+	g.Expect(ActivePredictor(nil)).To(Equal(spec))
+
+	g.Expect(ioutil.WriteFile(filename, []byte("{"), 0644)).To(Succeed())
+	applied, err = w.check()
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(applied).To(BeFalse())
+	g.Expect(ActivePredictor(nil)).To(Equal(spec))
+}
@@ -0,0 +1,103 @@
+package predictor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/status"
+)
+
+// Statuses retried when a RetryPolicy does not list any.
+var defaultRetryableStatus = []string{"502", "503", "504", "UNAVAILABLE"}
+
+// callNode runs call, which makes one request to the node, applying the node's circuit breaker, concurrency limit,
+// timeout and hedging to each attempt and retrying failed attempts allowed by its retry policy.
+func callNode[T any](p *PredictorProcess, node *v1.PredictiveUnit, call func(ctx context.Context) (T, error)) (T, error) {
+	var maxRetries int32
+	var backoff, maxBackoff time.Duration
+	if node.Retries != nil {
+		maxRetries = node.Retries.MaxRetries
+		backoff = time.Duration(node.Retries.BackoffMs) * time.Millisecond
+		maxBackoff = time.Duration(node.Retries.MaxBackoffMs) * time.Millisecond
+	}
+
+	for attempt := int32(0); ; attempt++ {
+		// Rejected calls are not retried so a fallback can take over straight away
+		release, err := p.acquireNode(node)
+		if err != nil {
+			var empty T
+			return empty, err
+		}
+
+		ctx := p.Ctx
+		cancel := func() {}
+		if node.TimeoutMs != nil && *node.TimeoutMs > 0 {
+			ctx, cancel = context.WithTimeout(p.Ctx, time.Duration(*node.TimeoutMs)*time.Millisecond)
+		}
+		getNodeMetrics().Started(node.Name)
+		res, err := hedgedCall(p, node, ctx, call)
+		getNodeMetrics().Finished(node.Name)
+		timedOut := ctx.Err() == context.DeadlineExceeded
+		cancel()
+		release(err)
+		if err == nil || attempt >= maxRetries || p.Ctx.Err() != nil {
+			return res, err
+		}
+		if !timedOut && !isRetryable(node.Retries, err) {
+			return res, err
+		}
+
+		p.Log.Info("Retrying failed call", "node", node.Name, "attempt", attempt+1, "error", err.Error())
+		if backoff > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-p.Ctx.Done():
+				return res, err
+			}
+			backoff *= 2
+			if maxBackoff > 0 && backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// isRetryable checks the status of a failed call against the retry policy. Calls failing without a status, such as
+// connection errors, are always retryable.
+func isRetryable(policy *v1.RetryPolicy, err error) bool {
+	code, ok := errorStatus(err)
+	if !ok {
+		return true
+	}
+	retryable := defaultRetryableStatus
+	if policy != nil && len(policy.RetryableStatus) > 0 {
+		retryable = policy.RetryableStatus
+	}
+	for _, s := range retryable {
+		if normalizeStatus(s) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// errorStatus returns the HTTP status code or gRPC code name of a failed call.
+func errorStatus(err error) (string, bool) {
+	var httpErr interface{ HttpStatusCode() int }
+	if errors.As(err, &httpErr) {
+		return strconv.Itoa(httpErr.HttpStatusCode()), true
+	}
+	if st, ok := status.FromError(err); ok {
+		return normalizeStatus(st.Code().String()), true
+	}
+	return "", false
+}
+
+// normalizeStatus allows gRPC codes to be given as UNAVAILABLE, Unavailable or DEADLINE_EXCEEDED.
+func normalizeStatus(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), "_", ""))
+}
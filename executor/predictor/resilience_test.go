@@ -0,0 +1,135 @@
+package predictor
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+
+	protoV1 "github.com/golang/protobuf/proto"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// flakyTestClient fails the first failures predict calls to the model named failModel.
+type flakyTestClient struct {
+	test.SeldonMessageTestClient
+	failModel string
+	failures  int
+	err       error
+	// Block failing calls until their context is done instead of returning err.
+	hang  bool
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *flakyTestClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	c.mu.Lock()
+	c.calls[modelName]++
+	fail := modelName == c.failModel && (c.failures < 0 || c.calls[modelName] <= c.failures)
+	c.mu.Unlock()
+	if !fail {
+		return msg, nil
+	}
+	if c.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return nil, c.err
+}
+
+func createPredictorProcessWithClient(t *testing.T, client *flakyTestClient) *PredictorProcess {
+	url, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	client.calls = make(map[string]int)
+	pp := NewPredictorProcess(ctx, client, logf.Log.WithName("SeldonMessageRestClient"), url, "default", map[string][]string{}, "")
+	return &pp
+}
+
+func createResilientModel(name string) *v1.PredictiveUnit {
+	model := v1.MODEL
+	return &v1.PredictiveUnit{
+		Name: name,
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.GRPC,
+		},
+	}
+}
+
+func TestRetries(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createResilientModel("primary")
+	graph.Retries = &v1.RetryPolicy{MaxRetries: 2, BackoffMs: 1}
+
+	client := &flakyTestClient{failModel: "primary", failures: 2, err: status.Error(codes.Unavailable, "unavailable")}
+	_, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["primary"]).To(Equal(3))
+
+	client = &flakyTestClient{failModel: "primary", failures: 3, err: status.Error(codes.Unavailable, "unavailable")}
+	_, err = createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["primary"]).To(Equal(3))
+}
+
+func TestRetryableStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createResilientModel("primary")
+	graph.Retries = &v1.RetryPolicy{MaxRetries: 2}
+
+	client := &flakyTestClient{failModel: "primary", failures: -1, err: status.Error(codes.InvalidArgument, "bad input")}
+	_, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["primary"]).To(Equal(1))
+
+	graph.Retries.RetryableStatus = []string{"INVALID_ARGUMENT"}
+	client = &flakyTestClient{failModel: "primary", failures: -1, err: status.Error(codes.InvalidArgument, "bad input")}
+	_, err = createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["primary"]).To(Equal(3))
+
+	// Errors without a status are always retried
+	client = &flakyTestClient{failModel: "primary", failures: 1, err: errors.New("connection refused")}
+	_, err = createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["primary"]).To(Equal(2))
+}
+
+func TestTimeoutAndFallback(t *testing.T) {
+	g := NewGomegaWithT(t)
+	timeout := int32(10)
+	graph := createResilientModel("primary")
+	graph.TimeoutMs = &timeout
+	graph.Retries = &v1.RetryPolicy{MaxRetries: 1}
+
+	client := &flakyTestClient{failModel: "primary", failures: -1, hang: true}
+	_, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(client.calls["primary"]).To(Equal(2))
+
+	graph.Fallback = createResilientModel("fallback")
+	client = &flakyTestClient{failModel: "primary", failures: -1, hang: true}
+	pResp, err := createPredictorProcessWithClient(t, client).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(protoV1.Equal(pResp.(*payload.ProtoPayload).Msg, createPredictPayload(g).(*payload.ProtoPayload).Msg)).To(BeTrue())
+	g.Expect(client.calls["primary"]).To(Equal(2))
+	g.Expect(client.calls["fallback"]).To(Equal(1))
+}
+
+func TestIsRetryable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(isRetryable(nil, status.Error(codes.Unavailable, ""))).To(BeTrue())
+	g.Expect(isRetryable(nil, status.Error(codes.Internal, ""))).To(BeFalse())
+	g.Expect(isRetryable(&v1.RetryPolicy{RetryableStatus: []string{"Deadline_Exceeded"}}, status.Error(codes.DeadlineExceeded, ""))).To(BeTrue())
+	g.Expect(isRetryable(&v1.RetryPolicy{RetryableStatus: []string{"500"}}, status.Error(codes.Unavailable, ""))).To(BeFalse())
+	g.Expect(isRetryable(nil, errors.New("connection refused"))).To(BeTrue())
+}
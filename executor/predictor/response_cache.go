@@ -0,0 +1,230 @@
+package predictor
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	protoV1 "github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const cacheDefaultMaxEntries = 1000
+
+type cacheEntry struct {
+	key     string
+	msg     payload.SeldonPayload
+	expires time.Time
+}
+
+// responseCache is an LRU cache of the responses of a node with a TTL for each entry.
+type responseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	// Most recently used entries are at the front.
+	lru     *list.List
+	metrics *metric.CacheMetrics
+}
+
+var responseCaches = newNodeStates[responseCache]()
+
+func getResponseCache(node *v1.PredictiveUnit) *responseCache {
+	c := responseCaches.get(node.Name, func() *responseCache {
+		return &responseCache{
+			entries: make(map[string]*list.Element),
+			lru:     list.New(),
+			metrics: metric.NewCacheMetrics(),
+		}
+	})
+
+	maxEntries := int(node.Cache.MaxEntries)
+	if maxEntries == 0 {
+		maxEntries = cacheDefaultMaxEntries
+	}
+	c.mu.Lock()
+	c.ttl = time.Duration(node.Cache.TtlMs) * time.Millisecond
+	c.maxEntries = maxEntries
+	c.mu.Unlock()
+	return c
+}
+
+func (c *responseCache) get(key string, now time.Time) (payload.SeldonPayload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if now.After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.msg, true
+}
+
+func (c *responseCache) put(key string, msg payload.SeldonPayload, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.msg = msg
+		entry.expires = now.Add(c.ttl)
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, msg: msg, expires: now.Add(c.ttl)})
+	}
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cachedCall returns the cached response of the node to an identical request or runs call and caches its response.
+// Requests with the bypass header always run call but still refresh the cache.
+func (p *PredictorProcess) cachedCall(node *v1.PredictiveUnit, msg payload.SeldonPayload, call func() (payload.SeldonPayload, error)) (payload.SeldonPayload, error) {
+	if node.Cache == nil {
+		return call()
+	}
+	key, err := cacheKey(node, msg, p.acceptsEventStream())
+	if err != nil {
+		p.Log.Error(err, "Failed to create cache key", "node", node.Name)
+		return call()
+	}
+
+	cache := getResponseCache(node)
+	if !p.bypassCache() {
+		if cached, ok := cache.get(key, time.Now()); ok {
+			cache.metrics.Hit(node.Name)
+			return p.withRequestPuid(copyPayload(cached)), nil
+		}
+		cache.metrics.Miss(node.Name)
+	}
+
+	tmsg, err := call()
+	if stream, ok := tmsg.(*payload.StreamPayload); ok && err == nil {
+		// A streamed response is cached once it has been read to the end
+		stream.OnComplete(func(data []byte) {
+			cache.put(key, &payload.BytesPayload{Msg: data, ContentType: stream.ContentType, ContentEncoding: stream.ContentEncoding}, time.Now())
+		})
+	} else if err == nil && tmsg != nil {
+		cache.put(key, copyPayload(tmsg), time.Now())
+	}
+	return tmsg, err
+}
+
+// acceptsEventStream reports whether the caller accepts a streamed response. Streamed and buffered responses to the
+// same request are cached apart so each caller gets the kind of response it asked for.
+func (p *PredictorProcess) acceptsEventStream() bool {
+	// gRPC metadata keys are lower case
+	for _, key := range []string{"Accept", "accept"} {
+		for _, accept := range p.Meta.Meta[key] {
+			for _, mediaType := range strings.Split(accept, ",") {
+				if strings.HasPrefix(strings.ToLower(strings.TrimSpace(mediaType)), payload.TEXT_EVENT_STREAM) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (p *PredictorProcess) bypassCache() bool {
+	// gRPC metadata keys are lower case
+	return p.Meta.GetAsBoolean(payload.SeldonCacheBypassHeader, false) ||
+		p.Meta.GetAsBoolean(strings.ToLower(payload.SeldonCacheBypassHeader), false)
+}
+
+// cacheKey hashes the node name and whether the caller accepts a streamed response with the canonical form of the
+// request. JSON is compacted and protobuf messages are marshalled deterministically so equivalent requests share an
+// entry.
+func cacheKey(node *v1.PredictiveUnit, msg payload.SeldonPayload, streaming bool) (string, error) {
+	var data []byte
+	if pm, ok := msg.GetPayload().(protoV1.Message); ok {
+		buf := protoV1.NewBuffer(nil)
+		buf.SetDeterministic(true)
+		if err := buf.Marshal(pm); err != nil {
+			return "", err
+		}
+		data = buf.Bytes()
+	} else {
+		var err error
+		data, err = msg.GetBytes()
+		if err != nil {
+			return "", err
+		}
+		if msg.GetContentEncoding() == "" {
+			var compacted bytes.Buffer
+			if json.Compact(&compacted, data) == nil {
+				data = compacted.Bytes()
+			}
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(node.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(msg.GetContentType()))
+	h.Write([]byte{0})
+	h.Write([]byte(msg.GetContentEncoding()))
+	h.Write([]byte{0})
+	if streaming {
+		h.Write([]byte(payload.TEXT_EVENT_STREAM))
+	}
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// withRequestPuid sets the PUID in the meta of a cached Seldon response to that of the current request, as the response
+// was cached for the request that first made the call.
+func (p *PredictorProcess) withRequestPuid(msg payload.SeldonPayload) payload.SeldonPayload {
+	puid, err := p.getPUIDHeader()
+	if err != nil {
+		return msg
+	}
+	switch v := msg.(type) {
+	case *payload.ProtoPayload:
+		if sm, ok := v.Msg.(*proto.SeldonMessage); ok && sm.GetMeta().GetPuid() != "" {
+			sm.Meta.Puid = puid
+		}
+	case *payload.BytesPayload:
+		if v.ContentEncoding != "" {
+			return msg
+		}
+		var body map[string]json.RawMessage
+		var meta map[string]json.RawMessage
+		if json.Unmarshal(v.Msg, &body) != nil || json.Unmarshal(body["meta"], &meta) != nil || meta["puid"] == nil {
+			return msg
+		}
+		meta["puid"], _ = json.Marshal(puid)
+		body["meta"], _ = json.Marshal(meta)
+		data, err := json.Marshal(body)
+		if err != nil {
+			return msg
+		}
+		return &payload.BytesPayload{Msg: data, ContentType: v.ContentType}
+	}
+	return msg
+}
+
+// copyPayload copies protobuf messages, which later nodes may change, so cached responses are not shared.
+func copyPayload(msg payload.SeldonPayload) payload.SeldonPayload {
+	if pm, ok := msg.GetPayload().(protoV1.Message); ok {
+		return &payload.ProtoPayload{Msg: protoV1.Clone(pm)}
+	}
+	return msg
+}
@@ -0,0 +1,167 @@
+package predictor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func resetResponseCache(name string) {
+	responseCaches.delete(name)
+}
+
+func resetModelStatistics(name string) {
+	modelStatisticsMap.delete(name)
+}
+
+func TestResponseCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetResponseCache("cached-model")
+	graph := createResilientModel("cached-model")
+	graph.Cache = &v1.CachePolicy{TtlMs: 50}
+
+	client := &flakyTestClient{}
+	pp := createPredictorProcessWithClient(t, client)
+	for i := 0; i < 3; i++ {
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		g.Expect(err).Should(BeNil())
+	}
+	g.Expect(client.calls["cached-model"]).To(Equal(1))
+
+	// The bypass header skips the cache
+	pp.Meta = payload.NewFromMap(map[string][]string{"seldon-cache-bypass": {"true"}})
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["cached-model"]).To(Equal(2))
+
+	// Entries expire after the TTL
+	pp.Meta = payload.NewFromMap(map[string][]string{})
+	time.Sleep(60 * time.Millisecond)
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["cached-model"]).To(Equal(3))
+}
+
+func TestResponseCachePuid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetResponseCache("cached-puid")
+	graph := createResilientModel("cached-puid")
+	graph.Cache = &v1.CachePolicy{TtlMs: 1000}
+
+	client := &flakyTestClient{}
+	pp := createPredictorProcessWithClient(t, client)
+	for i, puid := range []string{"puid-1", "puid-2"} {
+		pp.Ctx = context.WithValue(context.Background(), payload.SeldonPUIDHeader, puid)
+		res, err := pp.Predict(graph, createSeldonPayload(g, `{"meta":{"puid":"puid-1"},"data":{"ndarray":[[1]]}}`))
+		g.Expect(err).Should(BeNil())
+		g.Expect(res.GetPayload().(*proto.SeldonMessage).GetMeta().GetPuid()).To(Equal(puid), "call %d", i)
+
+		msg := &payload.BytesPayload{Msg: []byte(`{"meta":{"puid":"puid-1"},"data":{"ndarray":[[2]]}}`), ContentType: "application/json"}
+		res, err = pp.Predict(graph, msg)
+		g.Expect(err).Should(BeNil())
+		g.Expect(res.GetPayload()).To(MatchJSON(`{"data":{"ndarray":[[2]]},"meta":{"puid":"`+puid+`"}}`), "call %d", i)
+	}
+	g.Expect(client.calls["cached-puid"]).To(Equal(2))
+}
+
+func TestResponseCacheStatistics(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetResponseCache("cached-stats")
+	resetModelStatistics("cached-stats")
+	graph := createResilientModel("cached-stats")
+	graph.Cache = &v1.CachePolicy{TtlMs: 1000}
+
+	pp := createPredictorProcessWithClient(t, &flakyTestClient{})
+	for i := 0; i < 3; i++ {
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		g.Expect(err).Should(BeNil())
+	}
+	// Cache hits are not inferences of the model
+	stats := getModelStatistics("cached-stats").toProto("cached-stats")
+	g.Expect(stats.InferenceCount).To(Equal(uint64(1)))
+	g.Expect(stats.InferenceStats.Success.Count).To(Equal(uint64(1)))
+}
+
+func TestResponseCacheIgnoresErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetResponseCache("cached-errors")
+	graph := createResilientModel("cached-errors")
+	graph.Cache = &v1.CachePolicy{TtlMs: 60000}
+
+	client := &flakyTestClient{failModel: "cached-errors", failures: 1, err: status.Error(codes.Unavailable, "unavailable")}
+	pp := createPredictorProcessWithClient(t, client)
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["cached-errors"]).To(Equal(2))
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetResponseCache("cache-eviction")
+	node := &v1.PredictiveUnit{Name: "cache-eviction", Cache: &v1.CachePolicy{TtlMs: 60000, MaxEntries: 2}}
+	cache := getResponseCache(node)
+	now := time.Now()
+	msg := &payload.BytesPayload{Msg: []byte("{}")}
+	cache.put("a", msg, now)
+	cache.put("b", msg, now)
+	_, ok := cache.get("a", now)
+	g.Expect(ok).To(BeTrue())
+
+	// b is the least recently used entry
+	cache.put("c", msg, now)
+	_, ok = cache.get("b", now)
+	g.Expect(ok).To(BeFalse())
+	_, ok = cache.get("a", now)
+	g.Expect(ok).To(BeTrue())
+	_, ok = cache.get("c", now)
+	g.Expect(ok).To(BeTrue())
+}
+
+func TestCacheKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	node := &v1.PredictiveUnit{Name: "model"}
+	key1, err := cacheKey(node, &payload.BytesPayload{Msg: []byte(`{"data": {"ndarray": [1, 2]}}`), ContentType: "application/json"}, false)
+	g.Expect(err).Should(BeNil())
+	key2, err := cacheKey(node, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[1,2]}}`), ContentType: "application/json"}, false)
+	g.Expect(err).Should(BeNil())
+	g.Expect(key1).To(Equal(key2))
+
+	key3, err := cacheKey(&v1.PredictiveUnit{Name: "other"}, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[1,2]}}`), ContentType: "application/json"}, false)
+	g.Expect(err).Should(BeNil())
+	g.Expect(key3).ToNot(Equal(key1))
+
+	// Streamed responses are cached apart from buffered ones
+	key4, err := cacheKey(node, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[1,2]}}`), ContentType: "application/json"}, true)
+	g.Expect(err).Should(BeNil())
+	g.Expect(key4).ToNot(Equal(key1))
+}
+
+func TestResponseCacheStreaming(t *testing.T) {
+	g := NewGomegaWithT(t)
+	resetResponseCache("cached-stream")
+	graph := createResilientModel("cached-stream")
+	graph.Cache = &v1.CachePolicy{TtlMs: 60000}
+
+	client := &flakyTestClient{}
+	pp := createPredictorProcessWithClient(t, client)
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	// A caller accepting a stream is not answered with a buffered response
+	pp.Meta = payload.NewFromMap(map[string][]string{"Accept": {"application/json, text/event-stream"}})
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["cached-stream"]).To(Equal(2))
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(client.calls["cached-stream"]).To(Equal(2))
+}
@@ -0,0 +1,38 @@
+package predictor
+
+import (
+	"sync"
+)
+
+// The routes taken at each node with children since the executor started. Routes are those of the routing metadata:
+// the index of the child called, -1 where all children were called and -2 where none were.
+var (
+	routingTable      = make(map[string]map[int32]uint64)
+	routingTableMutex sync.Mutex
+)
+
+func recordRoutingDecision(nodeName string, route int32) {
+	routingTableMutex.Lock()
+	defer routingTableMutex.Unlock()
+	routes, ok := routingTable[nodeName]
+	if !ok {
+		routes = make(map[int32]uint64)
+		routingTable[nodeName] = routes
+	}
+	routes[route]++
+}
+
+// RoutingTable returns the number of requests sent along each route of each node with children, keyed by node name
+// then route.
+func RoutingTable() map[string]map[int32]uint64 {
+	routingTableMutex.Lock()
+	defer routingTableMutex.Unlock()
+	table := make(map[string]map[int32]uint64, len(routingTable))
+	for nodeName, routes := range routingTable {
+		table[nodeName] = make(map[int32]uint64, len(routes))
+		for route, count := range routes {
+			table[nodeName][route] = count
+		}
+	}
+	return table
+}
@@ -0,0 +1,74 @@
+package predictor
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// Shadow calls are dropped rather than queued once shadowMaxInFlight are running, so a slow shadow can not pile up
+// calls in the background. Shadow units without a timeout of their own are given shadowDefaultTimeout.
+const (
+	shadowMaxInFlight    = 100
+	shadowDefaultTimeout = 30 * time.Second
+)
+
+var shadowSlots = make(chan struct{}, shadowMaxInFlight)
+
+// shadow calls the node's shadow unit in the background with the request sent to the node, for the sampled share of
+// requests. The shadow's response is sent to the request logger and discarded. Shadows without a logger of their own
+// are logged to the default request logger, if there is one.
+func (p *PredictorProcess) shadow(node *v1.PredictiveUnit, msg payload.SeldonPayload) {
+	if node.Shadow == nil || !sampleShadow(node.Shadow.Percent) {
+		return
+	}
+	puid, err := p.getPUIDHeader()
+	if err != nil {
+		return
+	}
+	unit := node.Shadow.Unit
+	if unit.TimeoutMs == nil || *unit.TimeoutMs <= 0 {
+		timeoutMs := int32(shadowDefaultTimeout / time.Millisecond)
+		unit.TimeoutMs = &timeoutMs
+	}
+	if unit.Logger == nil && envRequestLoggerDefaultEndpoint != "" {
+		unit.Logger = &v1.Logger{Mode: v1.LogAll}
+	}
+
+	// The shadow outlives the request so is not cancelled with it
+	sp := &PredictorProcess{
+		Ctx:          context.WithValue(context.Background(), payload.SeldonPUIDHeader, puid),
+		Client:       p.Client,
+		Log:          p.Log.WithName("shadow"),
+		ServerUrl:    p.ServerUrl,
+		Namespace:    p.Namespace,
+		Meta:         p.Meta,
+		Routing:      make(map[string]int32),
+		RoutingMutex: &sync.RWMutex{},
+	}
+	// Protobuf payloads may be changed in place as they pass through the graph
+	smsg := copyPayload(msg)
+	select {
+	case shadowSlots <- struct{}{}:
+	default:
+		p.Log.V(1).Info("Shadow call dropped as too many are in flight", "node", node.Name, "shadow", unit.Name)
+		return
+	}
+	go func() {
+		defer func() { <-shadowSlots }()
+		if _, err := sp.Predict(&unit, smsg); err != nil {
+			sp.Log.Info("Shadow call failed", "node", node.Name, "shadow", unit.Name, "error", err.Error())
+		}
+	}()
+}
+
+func sampleShadow(percent *int32) bool {
+	if percent == nil {
+		return true
+	}
+	return rand.Float64()*100 < float64(*percent)
+}
@@ -0,0 +1,118 @@
+package predictor
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// hostRecordingClient sends the host of each predict call on a channel.
+type hostRecordingClient struct {
+	test.SeldonMessageTestClient
+	hosts chan string
+}
+
+func (c *hostRecordingClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	c.hosts <- host
+	return msg, nil
+}
+
+func createShadowGraph(percent *int32) *v1.PredictiveUnit {
+	model := v1.MODEL
+	return &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "production", ServicePort: 9000},
+		Shadow: &v1.ShadowUnit{
+			Percent: percent,
+			Unit: v1.PredictiveUnit{
+				Name:     "candidate",
+				Type:     &model,
+				Endpoint: &v1.Endpoint{ServiceHost: "candidate", ServicePort: 9000},
+			},
+		},
+	}
+}
+
+func TestShadow(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &hostRecordingClient{hosts: make(chan string, 2)}
+	serverUrl, _ := url.Parse(testSourceUrl)
+	ctx, cancel := context.WithCancel(context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid))
+	pp := NewPredictorProcess(ctx, client, logf.Log.WithName("test"), serverUrl, "default", map[string][]string{}, "")
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1]]}}`), ContentType: "application/json"}
+	res, err := pp.Predict(createShadowGraph(nil), msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).To(BeIdenticalTo(msg))
+	// The shadow carries on once the request has finished
+	cancel()
+
+	var hosts []string
+	for i := 0; i < 2; i++ {
+		select {
+		case host := <-client.hosts:
+			hosts = append(hosts, host)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for calls")
+		}
+	}
+	g.Expect(hosts).To(ConsistOf("production", "candidate"))
+}
+
+func TestShadowSampling(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &hostRecordingClient{hosts: make(chan string, 20)}
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{}, map[string][]string{})
+	pp.Client = client
+
+	none := int32(0)
+	for i := 0; i < 10; i++ {
+		_, err := pp.Predict(createShadowGraph(&none), &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1]]}}`), ContentType: "application/json"})
+		g.Expect(err).Should(BeNil())
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(client.hosts)
+	for host := range client.hosts {
+		g.Expect(host).To(Equal("production"))
+	}
+}
+
+func TestShadowDroppedWhenFull(t *testing.T) {
+	g := NewGomegaWithT(t)
+	client := &hostRecordingClient{hosts: make(chan string, 2)}
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{}, map[string][]string{})
+	pp.Client = client
+
+	for i := 0; i < shadowMaxInFlight; i++ {
+		shadowSlots <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < shadowMaxInFlight; i++ {
+			<-shadowSlots
+		}
+	}()
+	_, err := pp.Predict(createShadowGraph(nil), &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1]]}}`), ContentType: "application/json"})
+	g.Expect(err).Should(BeNil())
+	time.Sleep(50 * time.Millisecond)
+	close(client.hosts)
+	var hosts []string
+	for host := range client.hosts {
+		hosts = append(hosts, host)
+	}
+	g.Expect(hosts).To(Equal([]string{"production"}))
+}
+
+func TestShadowNotNeededForReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createShadowGraph(nil)
+	g.Expect(v1.GetSubUnits(graph)).To(HaveLen(1))
+	g.Expect(readinessSubUnits(graph)).To(BeEmpty())
+}
@@ -0,0 +1,84 @@
+package predictor
+
+import (
+	"context"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the spans opened for the nodes of the graph.
+const (
+	spanAttrNodeName           = "seldon.node.name"
+	spanAttrNodeType           = "seldon.node.type"
+	spanAttrNodeImplementation = "seldon.node.implementation"
+	spanAttrMethod             = "seldon.node.method"
+	spanAttrRoute              = "seldon.node.route"
+	spanAttrFallback           = "seldon.node.fallback"
+	spanAttrRequestBytes       = "seldon.request.size"
+	spanAttrResponseBytes      = "seldon.response.size"
+)
+
+// startNodeSpan opens a span for the node, or for one method of it, as a child of the span of the caller. It returns
+// a copy of the process whose calls are made within the new span, so the spans of a request form the tree of its
+// graph. Without tracing enabled the process itself is returned with a span that records nothing.
+func (p *PredictorProcess) startNodeSpan(node *v1.PredictiveUnit, method string) (*PredictorProcess, trace.Span) {
+	if !tracing.IsEnabled() {
+		return p, trace.SpanFromContext(context.Background())
+	}
+	name := node.Name
+	attrs := []attribute.KeyValue{
+		attribute.String(spanAttrNodeName, node.Name),
+		attribute.String(spanAttrNodeType, string(graphNodeType(node))),
+	}
+	if node.Implementation != nil {
+		attrs = append(attrs, attribute.String(spanAttrNodeImplementation, string(*node.Implementation)))
+	}
+	if method != "" {
+		name += " " + method
+		attrs = append(attrs, attribute.String(spanAttrMethod, method))
+	}
+	ctx, span := tracing.Tracer().Start(p.Ctx, name, trace.WithAttributes(attrs...))
+	sp := *p
+	sp.Ctx = ctx
+	return &sp, span
+}
+
+// endNodeSpan records the payload sizes and error of a call and ends its span.
+func endNodeSpan(span trace.Span, req []payload.SeldonPayload, res payload.SeldonPayload, err error) {
+	if span.IsRecording() {
+		requestBytes := 0
+		for _, msg := range req {
+			requestBytes += payloadSize(msg)
+		}
+		span.SetAttributes(
+			attribute.Int(spanAttrRequestBytes, requestBytes),
+			attribute.Int(spanAttrResponseBytes, payloadSize(res)),
+		)
+		recordSpanError(span, err)
+	}
+	span.End()
+}
+
+// endRouteSpan records the route chosen by a router and ends its span.
+func endRouteSpan(span trace.Span, req payload.SeldonPayload, route int, err error) {
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Int(spanAttrRequestBytes, payloadSize(req)))
+		if err == nil {
+			span.SetAttributes(attribute.Int(spanAttrRoute, route))
+		}
+		recordSpanError(span, err)
+	}
+	span.End()
+}
+
+func recordSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,142 @@
+package predictor
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		tracing.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+	return recorder
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestNodeSpans(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := recordSpans(t)
+	transformer := v1.TRANSFORMER
+	router := v1.ROUTER
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "transformer",
+		Type:     &transformer,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+		Children: []v1.PredictiveUnit{
+			{
+				Name:     "router",
+				Type:     &router,
+				Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9001},
+				Children: []v1.PredictiveUnit{
+					{Name: "model-a", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9002}},
+					{Name: "model-b", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9003}},
+				},
+			},
+		},
+	}
+
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{ChosenRoute: 1}, nil)
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`), ContentType: "application/json"}
+	_, err := pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	g.Expect(spans).To(HaveLen(6))
+	g.Expect(spans).To(HaveKey("transformer"))
+	g.Expect(spans).To(HaveKey("transformer transform-input"))
+	g.Expect(spans).To(HaveKey("router"))
+	g.Expect(spans).To(HaveKey("model-b"))
+	g.Expect(spans).To(HaveKey("model-b predict"))
+
+	// Spans form the tree of the graph
+	g.Expect(spans["router"].Parent().SpanID()).To(Equal(spans["transformer"].SpanContext().SpanID()))
+	g.Expect(spans["router route"].Parent().SpanID()).To(Equal(spans["router"].SpanContext().SpanID()))
+	g.Expect(spans["model-b"].Parent().SpanID()).To(Equal(spans["router"].SpanContext().SpanID()))
+	g.Expect(spans["model-b predict"].Parent().SpanID()).To(Equal(spans["model-b"].SpanContext().SpanID()))
+
+	attrs := spanAttributes(spans["router route"])
+	g.Expect(attrs[spanAttrNodeName].AsString()).To(Equal("router"))
+	g.Expect(attrs[spanAttrNodeType].AsString()).To(Equal(string(v1.ROUTER)))
+	g.Expect(attrs[spanAttrMethod].AsString()).To(Equal(traceMethodRoute))
+	g.Expect(attrs[spanAttrRoute].AsInt64()).To(Equal(int64(1)))
+
+	attrs = spanAttributes(spans["transformer transform-input"])
+	g.Expect(attrs[spanAttrRequestBytes].AsInt64()).To(Equal(int64(len(msg.Msg))))
+	g.Expect(attrs[spanAttrResponseBytes].AsInt64()).To(BeNumerically(">", 0))
+}
+
+func TestNodeSpanError(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := recordSpans(t)
+	model := v1.MODEL
+	simpleModel := v1.SIMPLE_MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+		Fallback: &v1.PredictiveUnit{Name: "fallback", Implementation: &simpleModel},
+	}
+
+	errMethod := v1.TRANSFORM_INPUT
+	client := &test.SeldonMessageTestClient{ErrMethod: &errMethod, Err: errors.New("model failed")}
+	pp := createTracedPredictorProcess(t, client, nil)
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`), ContentType: "application/json"}
+	_, err := pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	g.Expect(spans["model predict"].Status().Code).To(Equal(codes.Error))
+	g.Expect(spans["model predict"].Status().Description).To(Equal("model failed"))
+	g.Expect(spans["model"].Status().Code).To(Equal(codes.Error))
+	g.Expect(spanAttributes(spans["model"])[spanAttrFallback].AsString()).To(Equal("fallback"))
+	g.Expect(spans["fallback"].Status().Code).To(Equal(codes.Unset))
+	g.Expect(spanAttributes(spans["fallback"])[spanAttrNodeImplementation].AsString()).To(Equal(string(v1.SIMPLE_MODEL)))
+}
+
+func TestNodeSpansFeedback(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := recordSpans(t)
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+	}
+
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{}, nil)
+	_, err := pp.Feedback(graph, createFeedbackPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(2))
+	g.Expect(spans[0].Name()).To(Equal("model feedback"))
+	g.Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+	g.Expect(spanAttributes(spans[0])[spanAttrMethod].AsString()).To(Equal(traceMethodFeedback))
+}
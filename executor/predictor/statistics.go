@@ -0,0 +1,128 @@
+package predictor
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// modelStatistics holds the V2 protocol statistics of a model.
+type modelStatistics struct {
+	mu             sync.Mutex
+	lastInference  time.Time
+	inferenceCount uint64
+	executionCount uint64
+	success        inference.StatisticDuration
+	fail           inference.StatisticDuration
+	queue          inference.StatisticDuration
+	compute        inference.StatisticDuration
+}
+
+// Statistics are kept by model name, which is the node name unless the request names the model.
+var modelStatisticsMap = newNodeStates[modelStatistics]()
+
+func getModelStatistics(modelName string) *modelStatistics {
+	return modelStatisticsMap.get(modelName, func() *modelStatistics {
+		return &modelStatistics{}
+	})
+}
+
+func addDuration(d *inference.StatisticDuration, duration time.Duration) {
+	d.Count++
+	d.Ns += uint64(duration.Nanoseconds())
+}
+
+// recordInference records a request handled by the model, from its arrival at the model to the response.
+func (s *modelStatistics) recordInference(start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		addDuration(&s.fail, time.Since(start))
+		return
+	}
+	addDuration(&s.success, time.Since(start))
+	s.inferenceCount++
+	s.lastInference = time.Now()
+}
+
+// recordExecution records a call to the model, which may answer several batched requests.
+func (s *modelStatistics) recordExecution(start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addDuration(&s.compute, time.Since(start))
+	s.executionCount++
+}
+
+// recordQueue records the time a request waited before being sent to the model.
+func (s *modelStatistics) recordQueue(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addDuration(&s.queue, duration)
+}
+
+func (s *modelStatistics) toProto(modelName string) *inference.ModelStatistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := &inference.ModelStatistics{
+		Name:           modelName,
+		InferenceCount: s.inferenceCount,
+		ExecutionCount: s.executionCount,
+		InferenceStats: &inference.InferStatistics{
+			Success:       &inference.StatisticDuration{Count: s.success.Count, Ns: s.success.Ns},
+			Fail:          &inference.StatisticDuration{Count: s.fail.Count, Ns: s.fail.Ns},
+			Queue:         &inference.StatisticDuration{Count: s.queue.Count, Ns: s.queue.Ns},
+			ComputeInput:  &inference.StatisticDuration{},
+			ComputeInfer:  &inference.StatisticDuration{Count: s.compute.Count, Ns: s.compute.Ns},
+			ComputeOutput: &inference.StatisticDuration{},
+		},
+	}
+	if !s.lastInference.IsZero() {
+		stats.LastInference = uint64(s.lastInference.UnixNano() / int64(time.Millisecond))
+	}
+	return stats
+}
+
+// statisticsNotFoundError is returned as a 404 over REST and NOT_FOUND over gRPC.
+type statisticsNotFoundError struct {
+	model string
+}
+
+func (e *statisticsNotFoundError) Error() string {
+	return fmt.Sprintf("no statistics for model %s", e.model)
+}
+
+func (e *statisticsNotFoundError) HttpStatusCode() int {
+	return http.StatusNotFound
+}
+
+func (e *statisticsNotFoundError) GRPCStatus() *status.Status {
+	return status.New(codes.NotFound, e.Error())
+}
+
+// ModelStatistics returns the V2 protocol statistics of the named model, or of every model called so far if the name
+// is empty. A model appears once it has been called.
+func ModelStatistics(modelName string) (*inference.ModelStatisticsResponse, error) {
+	stats := modelStatisticsMap.all()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		if modelName == "" || name == modelName {
+			names = append(names, name)
+		}
+	}
+
+	if modelName != "" && len(names) == 0 {
+		return nil, &statisticsNotFoundError{model: modelName}
+	}
+	sort.Strings(names)
+	res := &inference.ModelStatisticsResponse{}
+	for _, name := range names {
+		res.ModelStats = append(res.ModelStats, stats[name].toProto(name))
+	}
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package predictor
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// splitKeyExpression is the parsed hashKey parameter of a node, or the error parsing it.
+type splitKeyExpression struct {
+	expr exprNode
+	err  error
+	// Raw parameter the expression was parsed from, so a changed parameter is parsed again.
+	source string
+}
+
+var splitKeyExpressions = newNodeStates[splitKeyExpression]()
+
+func getSplitKeyExpression(node *v1.PredictiveUnit, hashKey string) (exprNode, error) {
+	e := splitKeyExpressions.getValid(node.Name, func(e *splitKeyExpression) bool {
+		return e.source == hashKey
+	}, func() *splitKeyExpression {
+		expr, err := parseExpression(hashKey)
+		if err != nil {
+			err = fmt.Errorf("%s on node %s has invalid %s: %w", *node.Implementation, node.Name, abTestHashKeyParameter, err)
+		}
+		return &splitKeyExpression{expr: expr, err: err, source: hashKey}
+	})
+	return e.expr, e.err
+}
+
+// splitKey evaluates the hashKey expression, such as headers['X-User-Id'] or tags.user, for the request.
+func (p *PredictorProcess) splitKey(node *v1.PredictiveUnit, hashKey string, msg payload.SeldonPayload) (string, bool, error) {
+	expr, err := getSplitKeyExpression(node, hashKey)
+	if err != nil {
+		return "", false, err
+	}
+	value, err := expr.eval(p.newExprEnv(msg))
+	if err != nil {
+		return "", false, err
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, v != "", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true, nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err == nil, err
+	}
+}
+
+// stickyRoute picks a child for the key with weighted rendezvous hashing. Every child gets a score from a hash of
+// the key and the child name, scaled by its weight, and the highest score wins. The same key always picks the same
+// child, and changing a weight only moves keys to or from that child.
+func stickyRoute(node *v1.PredictiveUnit, key string, weights []float64) int {
+	best := 0
+	bestScore := math.Inf(-1)
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		childName := strconv.Itoa(i)
+		if i < len(node.Children) && node.Children[i].Name != "" {
+			childName = node.Children[i].Name
+		}
+		score := -w / math.Log(hashUnit(key, childName))
+		if score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// hashUnit hashes the key and child name to a number in the open interval (0, 1).
+func hashUnit(key string, childName string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(childName))
+	// FNV barely changes the high bits for the last bytes written, so mix them in with the MurmurHash3 finalizer
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return (float64(x>>11) + 0.5) / (1 << 53)
+}
@@ -0,0 +1,125 @@
+package predictor
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func createStickySplit(hashKey string, weights string) *v1.PredictiveUnit {
+	abTest := v1.RANDOM_ABTEST
+	params := []v1.Parameter{{Name: abTestHashKeyParameter, Type: v1.STRING, Value: hashKey}}
+	if weights != "" {
+		params = append(params, v1.Parameter{Name: weightsParameter, Type: v1.STRING, Value: weights})
+	}
+	return &v1.PredictiveUnit{
+		Name:           "split",
+		Implementation: &abTest,
+		Parameters:     params,
+		Children: []v1.PredictiveUnit{
+			{Name: "model-a"},
+			{Name: "model-b"},
+			{Name: "model-c"},
+		},
+	}
+}
+
+func TestStickySplitSameKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createStickySplit("headers['X-User-Id']", "1,1,1")
+
+	routes := make(map[int32]bool)
+	for i := 0; i < 20; i++ {
+		pp := createPredictorProcessWithMeta(t, map[string][]string{"X-User-Id": {"user-42"}})
+		_, err := pp.Predict(graph, createPredictPayload(g))
+		g.Expect(err).Should(BeNil())
+		routes[pp.Routing[graph.Name]] = true
+	}
+	g.Expect(routes).To(HaveLen(1))
+}
+
+func TestStickySplitKeyExpression(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createStickySplit("headers['X-User-Id']", "")
+	expr, err := getSplitKeyExpression(graph, "headers['X-User-Id']")
+	g.Expect(err).Should(BeNil())
+	cached, err := getSplitKeyExpression(graph, "headers['X-User-Id']")
+	g.Expect(err).Should(BeNil())
+	g.Expect(cached).To(BeIdenticalTo(expr))
+
+	// A changed hashKey is parsed again
+	changed, err := getSplitKeyExpression(graph, "tags.user")
+	g.Expect(err).Should(BeNil())
+	g.Expect(changed).ToNot(BeIdenticalTo(expr))
+	_, err = getSplitKeyExpression(graph, "tags.")
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestStickySplitPayloadKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createStickySplit("tags.user", "1,1,1")
+	msg := `{"meta":{"tags":{"user":"user-7"}},"data":{"ndarray":[1]}}`
+
+	pp := createPredictorProcess(t)
+	_, err := pp.Predict(graph, createSeldonMessagePayload(g, msg))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing[graph.Name]).To(Equal(int32(stickyRoute(graph, "user-7", []float64{1, 1, 1}))))
+
+	// Requests without the key are routed at random
+	pp = createPredictorProcess(t)
+	_, err = pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	g.Expect(pp.Routing[graph.Name]).To(BeNumerically("<", 3))
+}
+
+func TestStickySplitWeights(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createStickySplit("headers['X-User-Id']", "")
+	counts := make([]int, 3)
+	for i := 0; i < 10000; i++ {
+		counts[stickyRoute(graph, fmt.Sprintf("user-%d", i), []float64{0.5, 0.3, 0.2})]++
+	}
+	g.Expect(counts[0]).To(BeNumerically("~", 5000, 250))
+	g.Expect(counts[1]).To(BeNumerically("~", 3000, 250))
+	g.Expect(counts[2]).To(BeNumerically("~", 2000, 250))
+}
+
+func TestStickySplitMinimalReshuffle(t *testing.T) {
+	g := NewGomegaWithT(t)
+	graph := createStickySplit("headers['X-User-Id']", "")
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		before := stickyRoute(graph, key, []float64{1, 1, 1})
+		after := stickyRoute(graph, key, []float64{1, 1, 2})
+		if before != after {
+			// Only keys moving to the child whose weight grew change route
+			g.Expect(after).To(Equal(2))
+			moved++
+		}
+	}
+	// A third of the traffic becomes a half, so about a quarter of keys move
+	g.Expect(moved).To(BeNumerically("~", 1667, 250))
+}
+
+func TestABTestWeights(t *testing.T) {
+	g := NewGomegaWithT(t)
+	abTest := v1.RANDOM_ABTEST
+	graph := &v1.PredictiveUnit{
+		Name:           "abtest-weights",
+		Implementation: &abTest,
+		Parameters:     []v1.Parameter{{Name: weightsParameter, Type: v1.STRING, Value: "0,0,1"}},
+		Children:       []v1.PredictiveUnit{{Name: "model-a"}, {Name: "model-b"}, {Name: "model-c"}},
+	}
+	for i := 0; i < 10; i++ {
+		route, err := createPredictorProcess(t).abTestRouter(graph, createPredictPayload(g))
+		g.Expect(err).Should(BeNil())
+		g.Expect(route).To(Equal(2))
+	}
+
+	graph.Parameters[0].Value = "1,1"
+	_, err := createPredictorProcess(t).abTestRouter(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+}
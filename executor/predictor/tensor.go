@@ -0,0 +1,162 @@
+package predictor
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// ndTensor is a dense row-major view of the numeric data in a SeldonMessage.
+type ndTensor struct {
+	shape  []int
+	values []float64
+}
+
+func (t *ndTensor) size() int {
+	size := 1
+	for _, dim := range t.shape {
+		size *= dim
+	}
+	return size
+}
+
+func (t *ndTensor) sameShape(other *ndTensor) bool {
+	if len(t.shape) != len(other.shape) {
+		return false
+	}
+	for i := range t.shape {
+		if t.shape[i] != other.shape[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// seldonMessageFromPayload decodes a SeldonMessage from either a protobuf or a Seldon JSON payload.
+func seldonMessageFromPayload(msg payload.SeldonPayload) (*proto.SeldonMessage, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("empty payload")
+	}
+	if sm, ok := msg.GetPayload().(*proto.SeldonMessage); ok {
+		return sm, nil
+	}
+	data, err := payload.DecompressSeldonPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	var sm proto.SeldonMessage
+	err = jsonpb.UnmarshalString(string(data), &sm)
+	if err != nil {
+		return nil, fmt.Errorf("payload is not a SeldonMessage: %w", err)
+	}
+	return &sm, nil
+}
+
+// seldonMessageToPayload encodes a SeldonMessage in the same representation as the original payload.
+func seldonMessageToPayload(sm *proto.SeldonMessage, original payload.SeldonPayload) (payload.SeldonPayload, error) {
+	if original.GetContentType() == payload.APPLICATION_TYPE_PROTOBUF {
+		return &payload.ProtoPayload{Msg: sm}, nil
+	}
+	m := jsonpb.Marshaler{}
+	jStr, err := m.MarshalToString(sm)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.BytesPayload{Msg: []byte(jStr), ContentType: original.GetContentType()}, nil
+}
+
+func tensorFromDefaultData(data *proto.DefaultData) (*ndTensor, error) {
+	switch data.GetDataOneof().(type) {
+	case *proto.DefaultData_Tensor:
+		tensor := data.GetTensor()
+		t := &ndTensor{shape: make([]int, len(tensor.GetShape())), values: tensor.GetValues()}
+		for i, dim := range tensor.GetShape() {
+			t.shape[i] = int(dim)
+		}
+		if t.size() != len(t.values) {
+			return nil, fmt.Errorf("tensor shape %v does not match %d values", t.shape, len(t.values))
+		}
+		return t, nil
+	case *proto.DefaultData_Ndarray:
+		t := &ndTensor{shape: ndarrayShape(data.GetNdarray())}
+		err := flattenNdarray(data.GetNdarray(), t)
+		if err != nil {
+			return nil, err
+		}
+		if len(t.values) != t.size() {
+			return nil, fmt.Errorf("ndarray is not rectangular for shape %v", t.shape)
+		}
+		return t, nil
+	default:
+		return nil, fmt.Errorf("only tensor and ndarray data can be combined")
+	}
+}
+
+// ndarrayShape infers the shape of a nested list from its first element at each level.
+func ndarrayShape(list *structpb.ListValue) []int {
+	shape := []int{len(list.GetValues())}
+	if len(list.GetValues()) == 0 {
+		return shape
+	}
+	if inner := list.GetValues()[0].GetListValue(); inner != nil {
+		shape = append(shape, ndarrayShape(inner)...)
+	}
+	return shape
+}
+
+func flattenNdarray(list *structpb.ListValue, t *ndTensor) error {
+	for _, value := range list.GetValues() {
+		switch kind := value.GetKind().(type) {
+		case *structpb.Value_NumberValue:
+			t.values = append(t.values, kind.NumberValue)
+		case *structpb.Value_BoolValue:
+			if kind.BoolValue {
+				t.values = append(t.values, 1)
+			} else {
+				t.values = append(t.values, 0)
+			}
+		case *structpb.Value_ListValue:
+			err := flattenNdarray(kind.ListValue, t)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("ndarray contains non numeric value %v", value)
+		}
+	}
+	return nil
+}
+
+// toDefaultData builds DefaultData holding the tensor using the same names and encoding as template.
+func (t *ndTensor) toDefaultData(template *proto.DefaultData) *proto.DefaultData {
+	data := &proto.DefaultData{Names: template.GetNames()}
+	if _, ok := template.GetDataOneof().(*proto.DefaultData_Ndarray); ok {
+		list, _ := t.toListValue(0, 0)
+		data.DataOneof = &proto.DefaultData_Ndarray{Ndarray: list}
+	} else {
+		shape := make([]int32, len(t.shape))
+		for i, dim := range t.shape {
+			shape[i] = int32(dim)
+		}
+		data.DataOneof = &proto.DefaultData_Tensor{Tensor: &proto.Tensor{Shape: shape, Values: t.values}}
+	}
+	return data
+}
+
+func (t *ndTensor) toListValue(dim int, offset int) (*structpb.ListValue, int) {
+	list := &structpb.ListValue{Values: make([]*structpb.Value, t.shape[dim])}
+	for i := 0; i < t.shape[dim]; i++ {
+		if dim == len(t.shape)-1 {
+			list.Values[i] = &structpb.Value{Kind: &structpb.Value_NumberValue{NumberValue: t.values[offset]}}
+			offset++
+		} else {
+			var inner *structpb.ListValue
+			inner, offset = t.toListValue(dim+1, offset)
+			list.Values[i] = &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: inner}}
+		}
+	}
+	return list, offset
+}
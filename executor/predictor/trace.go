@@ -0,0 +1,168 @@
+package predictor
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// Methods recorded in an execution trace and in the spans of the graph.
+const (
+	traceMethodPredict         = "predict"
+	traceMethodTransformInput  = "transform-input"
+	traceMethodTransformOutput = "transform-output"
+	traceMethodRoute           = "route"
+	traceMethodAggregate       = "aggregate"
+	traceMethodFallback        = "fallback"
+	traceMethodFeedback        = "feedback"
+)
+
+// ExecutionTrace records each call made while a request runs through the graph. It is collected for requests with the
+// Seldon-Trace header and returned in the same header of the response. A nil trace records nothing.
+type ExecutionTrace struct {
+	mu    sync.Mutex
+	start time.Time
+	steps []TraceStep
+}
+
+// TraceStep is a call made to a node of the graph. Times are in milliseconds, with Start relative to the start of the
+// request. Sizes are those of the payloads as sent over the wire, and are left out for streamed responses.
+type TraceStep struct {
+	Node          string  `json:"node"`
+	Method        string  `json:"method"`
+	Route         *int    `json:"route,omitempty"`
+	Fallback      string  `json:"fallback,omitempty"`
+	Start         float64 `json:"startMs"`
+	Duration      float64 `json:"durationMs"`
+	RequestBytes  int     `json:"requestBytes,omitempty"`
+	ResponseBytes int     `json:"responseBytes,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+type traceJson struct {
+	Total float64     `json:"totalMs"`
+	Steps []TraceStep `json:"steps"`
+}
+
+func newExecutionTrace(meta *payload.MetaData) *ExecutionTrace {
+	if meta.GetAsBoolean(payload.SeldonTraceHeader, false) || meta.GetAsBoolean(strings.ToLower(payload.SeldonTraceHeader), false) {
+		return &ExecutionTrace{start: time.Now()}
+	}
+	return nil
+}
+
+func (t *ExecutionTrace) record(node string, method string, start time.Time, req []payload.SeldonPayload, res payload.SeldonPayload, err error) {
+	if t == nil {
+		return
+	}
+	step := TraceStep{
+		Node:          node,
+		Method:        method,
+		Start:         milliseconds(start.Sub(t.start)),
+		Duration:      milliseconds(time.Since(start)),
+		ResponseBytes: payloadSize(res),
+	}
+	for _, msg := range req {
+		step.RequestBytes += payloadSize(msg)
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+}
+
+func (t *ExecutionTrace) recordRoute(node string, start time.Time, req payload.SeldonPayload, route int, err error) {
+	if t == nil {
+		return
+	}
+	step := TraceStep{
+		Node:         node,
+		Method:       traceMethodRoute,
+		Start:        milliseconds(start.Sub(t.start)),
+		Duration:     milliseconds(time.Since(start)),
+		RequestBytes: payloadSize(req),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	} else {
+		step.Route = &route
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+}
+
+func (t *ExecutionTrace) recordFallback(node string, fallback string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, TraceStep{
+		Node:     node,
+		Method:   traceMethodFallback,
+		Fallback: fallback,
+		Start:    milliseconds(time.Since(t.start)),
+		Error:    err.Error(),
+	})
+}
+
+// Steps returns the calls recorded so far in the order they were started.
+func (t *ExecutionTrace) Steps() []TraceStep {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	steps := make([]TraceStep, len(t.steps))
+	copy(steps, t.steps)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Start < steps[j].Start })
+	return steps
+}
+
+// TraceHeader returns the execution trace of the request as JSON for the Seldon-Trace response header, if the request
+// asked for one.
+func (p *PredictorProcess) TraceHeader() (string, bool) {
+	if p.Trace == nil {
+		return "", false
+	}
+	data, err := json.Marshal(traceJson{
+		Total: milliseconds(time.Since(p.Trace.start)),
+		Steps: p.Trace.Steps(),
+	})
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+func payloadSize(msg payload.SeldonPayload) int {
+	if msg == nil {
+		return 0
+	}
+	// Protobuf and byte payloads are sized as they are rather than marshalled again
+	switch v := msg.(type) {
+	case *payload.StreamPayload:
+		return 0
+	case *payload.ProtoPayload:
+		return proto.Size(v.Msg)
+	case *payload.BytesPayload:
+		return len(v.Msg)
+	}
+	data, err := msg.GetBytes()
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,102 @@
+package predictor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createTracedPredictorProcess(t *testing.T, client *test.SeldonMessageTestClient, meta map[string][]string) *PredictorProcess {
+	serverUrl, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, client, logf.Log.WithName("test"), serverUrl, "default", meta, "")
+	return &pp
+}
+
+func TestExecutionTrace(t *testing.T) {
+	g := NewGomegaWithT(t)
+	transformer := v1.TRANSFORMER
+	router := v1.ROUTER
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "transformer",
+		Type:     &transformer,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+		Children: []v1.PredictiveUnit{
+			{
+				Name:     "router",
+				Type:     &router,
+				Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9001},
+				Children: []v1.PredictiveUnit{
+					{Name: "model-a", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9002}},
+					{Name: "model-b", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9003}},
+				},
+			},
+		},
+	}
+
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{ChosenRoute: 1}, map[string][]string{"seldon-trace": {"true"}})
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`), ContentType: "application/json"}
+	_, err := pp.Predict(graph, msg)
+	g.Expect(err).Should(BeNil())
+
+	steps := pp.Trace.Steps()
+	g.Expect(steps).To(HaveLen(3))
+	g.Expect(steps[0].Node).To(Equal("transformer"))
+	g.Expect(steps[0].Method).To(Equal(traceMethodTransformInput))
+	g.Expect(steps[0].RequestBytes).To(Equal(len(msg.Msg)))
+	g.Expect(steps[1].Node).To(Equal("router"))
+	g.Expect(steps[1].Method).To(Equal(traceMethodRoute))
+	g.Expect(*steps[1].Route).To(Equal(1))
+	g.Expect(steps[2].Node).To(Equal("model-b"))
+	g.Expect(steps[2].Method).To(Equal(traceMethodPredict))
+	g.Expect(steps[2].ResponseBytes).To(BeNumerically(">", 0))
+
+	header, ok := pp.TraceHeader()
+	g.Expect(ok).To(BeTrue())
+	var trace traceJson
+	g.Expect(json.Unmarshal([]byte(header), &trace)).Should(BeNil())
+	g.Expect(trace.Steps).To(HaveLen(3))
+}
+
+func TestExecutionTraceFallback(t *testing.T) {
+	g := NewGomegaWithT(t)
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "primary",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+		Fallback: &v1.PredictiveUnit{Name: "fallback", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "bar", ServicePort: 9000}},
+	}
+	errMethod := v1.TRANSFORM_INPUT
+	client := &test.SeldonMessageTestClient{ErrMethod: &errMethod, Err: errors.New("unavailable")}
+	pp := createTracedPredictorProcess(t, client, map[string][]string{payload.SeldonTraceHeader: {"true"}})
+	_, _ = pp.Predict(graph, &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1]]}}`), ContentType: "application/json"})
+
+	var fallbacks []TraceStep
+	for _, step := range pp.Trace.Steps() {
+		g.Expect(step.Error).ToNot(BeEmpty())
+		if step.Method == traceMethodFallback {
+			fallbacks = append(fallbacks, step)
+		}
+	}
+	g.Expect(fallbacks).To(HaveLen(1))
+	g.Expect(fallbacks[0].Node).To(Equal("primary"))
+	g.Expect(fallbacks[0].Fallback).To(Equal("fallback"))
+}
+
+func TestExecutionTraceNotRequested(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pp := createTracedPredictorProcess(t, &test.SeldonMessageTestClient{}, map[string][]string{})
+	g.Expect(pp.Trace).To(BeNil())
+	_, ok := pp.TraceHeader()
+	g.Expect(ok).To(BeFalse())
+}
@@ -64,6 +64,17 @@ func getPredictorFromFile(predictorName string, filename string) (*v1.PredictorS
 			}
 		}
 		return nil, fmt.Errorf("Predictor not found %s", predictorName)
+	} else if strings.HasSuffix(filename, "json") {
+		// A predictor as passed in the environment, such as from a ConfigMap
+		var predictor v1.PredictorSpec
+		err = json.Unmarshal(dat, &predictor)
+		if err != nil {
+			return nil, err
+		}
+		if predictorName != "" && predictor.Name != predictorName {
+			return nil, fmt.Errorf("Predictor not found %s", predictorName)
+		}
+		return &predictor, nil
 	} else {
 		return nil, fmt.Errorf("Unsupported file type %s", filename)
 	}
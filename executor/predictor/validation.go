@@ -0,0 +1,508 @@
+package predictor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Graph inputs are fetched from the models' metadata the first time a request to the graph is validated, with
+// requests to the graph waiting for the fetch. Failed fetches are retried in the background after graphInputsRetry,
+// with requests passed on unchecked until then. Inputs are kept until the active graph is replaced.
+const (
+	graphInputsRetry   = 30 * time.Second
+	graphInputsTimeout = 10 * time.Second
+)
+
+var (
+	validateRequests       bool
+	castRequests           bool
+	graphInputsCache       = make(map[*v1.PredictiveUnit]*graphInputs)
+	graphInputsFetches     = make(map[*v1.PredictiveUnit]chan struct{})
+	requestValidationMutex sync.Mutex
+)
+
+type graphInputs struct {
+	specs   []inputSpec
+	fetched time.Time
+	err     error
+}
+
+// SetRequestValidation enables checking requests against the inputs in the graph's metadata and, with cast, converting
+// V2 input tensors to the datatype the model expects where their values allow it.
+func SetRequestValidation(validate bool, cast bool) {
+	requestValidationMutex.Lock()
+	defer requestValidationMutex.Unlock()
+	validateRequests = validate
+	castRequests = cast
+	resetGraphInputs()
+}
+
+// resetGraphInputs forgets the inputs of every graph, such as when the active graph is replaced. Fetches still running
+// are not published. The caller must hold requestValidationMutex.
+func resetGraphInputs() {
+	graphInputsCache = make(map[*v1.PredictiveUnit]*graphInputs)
+	graphInputsFetches = make(map[*v1.PredictiveUnit]chan struct{})
+}
+
+// inputSpec is a graph input described in either the Seldon or the V2 metadata format.
+type inputSpec struct {
+	Messagetype string          `json:"messagetype"`
+	Schema      json.RawMessage `json:"schema"`
+	Name        string          `json:"name"`
+	Datatype    string          `json:"datatype"`
+	Shape       []int64         `json:"shape"`
+	names       []string
+}
+
+// validationError is returned for requests that do not match the graph's inputs.
+type validationError struct {
+	msg string
+}
+
+func newValidationError(format string, args ...interface{}) *validationError {
+	return &validationError{msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *validationError) Error() string {
+	return "invalid request: " + e.msg
+}
+
+func (e *validationError) HttpStatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *validationError) GRPCStatus() *status.Status {
+	return status.New(codes.InvalidArgument, e.Error())
+}
+
+// ValidateRequest checks the request against the inputs in the graph's metadata when request validation is enabled,
+// returning the request to send through the graph. Requests are passed on unchecked if the graph has no input metadata.
+func (p *PredictorProcess) ValidateRequest(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	requestValidationMutex.Lock()
+	if !validateRequests {
+		requestValidationMutex.Unlock()
+		return msg, nil
+	}
+	cast := castRequests
+	inputs, ok := graphInputsCache[node]
+	fetch, fetching := graphInputsFetches[node]
+	if !fetching && (!ok || (inputs.err != nil && time.Since(inputs.fetched) > graphInputsRetry)) {
+		fetch = make(chan struct{})
+		graphInputsFetches[node] = fetch
+		go p.fetchGraphInputs(node, fetch)
+	}
+	requestValidationMutex.Unlock()
+	if !ok {
+		select {
+		case <-fetch:
+		case <-p.Ctx.Done():
+			return nil, p.Ctx.Err()
+		}
+		requestValidationMutex.Lock()
+		inputs = graphInputsCache[node]
+		requestValidationMutex.Unlock()
+	}
+	if inputs == nil || len(inputs.specs) == 0 {
+		return msg, nil
+	}
+
+	switch req := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		return msg, validateSeldonRequest(req, inputs.specs[0])
+	case *inference.ModelInferRequest:
+		return msg, validateV2ProtoRequest(req, inputs.specs, cast)
+	}
+	protocolClientsMutex.RLock()
+	protocol := deploymentProtocol
+	protocolClientsMutex.RUnlock()
+	switch protocol {
+	case api.ProtocolSeldon:
+		sm, err := seldonMessageFromPayload(msg)
+		if err != nil {
+			return nil, newValidationError("%s", err.Error())
+		}
+		return msg, validateSeldonRequest(sm, inputs.specs[0])
+	case api.ProtocolV2, api.ProtocolKFServing:
+		return validateV2JsonRequest(msg, inputs.specs, cast)
+	}
+	return msg, nil
+}
+
+// fetchGraphInputs fetches the graph's inputs, publishes them and then closes fetch. The fetch is not tied to the
+// request that started it, so a cancelled request does not turn validation off.
+func (p *PredictorProcess) fetchGraphInputs(node *v1.PredictiveUnit, fetch chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), graphInputsTimeout)
+	defer cancel()
+	fp := *p
+	fp.Ctx = ctx
+	inputs := fp.graphInputs(node)
+
+	requestValidationMutex.Lock()
+	defer requestValidationMutex.Unlock()
+	if graphInputsFetches[node] == fetch {
+		graphInputsCache[node] = inputs
+		delete(graphInputsFetches, node)
+	}
+	close(fetch)
+}
+
+func (p *PredictorProcess) graphInputs(node *v1.PredictiveUnit) *graphInputs {
+	inputs := &graphInputs{fetched: time.Now()}
+	metadataMap, err := p.ModelMetadataMap(node)
+	if err != nil {
+		p.Log.Info("Requests will not be validated as the graph's metadata is unavailable", "error", err.Error())
+		inputs.err = err
+		return inputs
+	}
+	gm := &GraphMetadata{Models: metadataMap}
+	input, _ := gm.getEdgeNodes(node)
+	if input == nil || input.Inputs == nil {
+		return inputs
+	}
+	inputs.specs, inputs.err = parseInputSpecs(input.Inputs)
+	if inputs.err != nil {
+		p.Log.Info("Requests will not be validated as the graph's input metadata is invalid", "error", inputs.err.Error())
+	}
+	return inputs
+}
+
+// parseInputSpecs reads the inputs or outputs of model metadata, which are decoded from JSON or are protobuf messages.
+// Seldon format metadata may describe a single message rather than a list.
+func parseInputSpecs(inputs interface{}) ([]inputSpec, error) {
+	var data []byte
+	var err error
+	if messages, ok := inputs.([]*proto.SeldonMessageMetadata); ok {
+		ma := jsonpb.Marshaler{}
+		var items []json.RawMessage
+		for _, m := range messages {
+			s, err := ma.MarshalToString(m)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, json.RawMessage(s))
+		}
+		data, err = json.Marshal(items)
+	} else {
+		data, err = json.Marshal(inputs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+	var specs []inputSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, err
+	}
+	for i := range specs {
+		// Only the names and shape of tensor schemas are checked. Other schemas are free form.
+		var schema struct {
+			Names []string `json:"names"`
+			Shape []int64  `json:"shape"`
+		}
+		if len(specs[i].Schema) > 0 && json.Unmarshal(specs[i].Schema, &schema) == nil {
+			specs[i].names = schema.Names
+			if specs[i].Shape == nil {
+				specs[i].Shape = schema.Shape
+			}
+		}
+	}
+	return specs, nil
+}
+
+func validateSeldonRequest(sm *proto.SeldonMessage, spec inputSpec) error {
+	kind := ""
+	var names []string
+	var shape []int64
+	switch {
+	case sm.GetData() != nil:
+		data := sm.GetData()
+		names = data.GetNames()
+		switch {
+		case data.GetTensor() != nil:
+			kind = "tensor"
+			for _, d := range data.GetTensor().GetShape() {
+				shape = append(shape, int64(d))
+			}
+		case data.GetNdarray() != nil:
+			kind = "ndarray"
+			for list := data.GetNdarray(); list != nil; {
+				shape = append(shape, int64(len(list.Values)))
+				if len(list.Values) == 0 {
+					break
+				}
+				list = list.Values[0].GetListValue()
+			}
+		case data.GetTftensor() != nil:
+			kind = "tftensor"
+			for _, d := range data.GetTftensor().GetTensorShape().GetDim() {
+				shape = append(shape, d.GetSize())
+			}
+		}
+	case sm.GetJsonData() != nil:
+		kind = "jsonData"
+	case sm.GetStrData() != "":
+		kind = "strData"
+	case sm.GetBinData() != nil:
+		kind = "binData"
+	}
+
+	switch spec.Messagetype {
+	case "":
+		// V2 format metadata only describes tensors
+		if shape == nil {
+			return newValidationError("request has %s but the model expects a tensor", kind)
+		}
+	case "ndarray", "tensor", "tftensor", "jsonData", "strData", "binData":
+		if kind != spec.Messagetype {
+			return newValidationError("request has %s but the model expects %s", kind, spec.Messagetype)
+		}
+	default:
+		// Custom message types are not checked
+		return nil
+	}
+	if len(spec.names) > 0 && len(names) > 0 && !equalNames(names, spec.names) {
+		return newValidationError("request names %v do not match the model's input names %v", names, spec.names)
+	}
+	if shape != nil {
+		return checkShape("request data", shape, spec.Shape, true)
+	}
+	return nil
+}
+
+func equalNames(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkShape checks a shape against the expected shape, where -1 matches any size. Seldon metadata may describe a
+// single row so, with batched, the expected shape may leave out the first dimension.
+func checkShape(desc string, shape []int64, expected []int64, batched bool) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	offset := 0
+	if batched && len(shape) == len(expected)+1 {
+		offset = 1
+	}
+	if len(shape) != len(expected)+offset {
+		return newValidationError("%s has %d dimensions but the model expects %d", desc, len(shape), len(expected))
+	}
+	for i, size := range expected {
+		if size >= 0 && shape[i+offset] != size {
+			return newValidationError("%s dimension %d is %d but the model expects %d", desc, i+offset, shape[i+offset], size)
+		}
+	}
+	return nil
+}
+
+// v2Input is the part of a V2 input tensor that is validated.
+type v2Input struct {
+	name     string
+	datatype string
+	shape    []int64
+}
+
+// checkV2Inputs checks the inputs of a V2 request, returning the datatype to cast each input to where it differs.
+func checkV2Inputs(inputs []v2Input, specs []inputSpec, cast bool) (map[int]string, error) {
+	casts := make(map[int]string)
+	byName := make(map[string]int)
+	for i, input := range inputs {
+		byName[input.name] = i
+	}
+	for _, spec := range specs {
+		if spec.Name == "" {
+			// Seldon format metadata does not describe V2 tensors
+			return nil, nil
+		}
+		i, ok := byName[spec.Name]
+		if !ok {
+			return nil, newValidationError("input tensor %q is missing", spec.Name)
+		}
+		delete(byName, spec.Name)
+		input := inputs[i]
+		if spec.Datatype != "" && input.datatype != spec.Datatype {
+			if !cast || !castable(input.datatype, spec.Datatype) {
+				return nil, newValidationError("input tensor %q has datatype %s but the model expects %s", input.name, input.datatype, spec.Datatype)
+			}
+			casts[i] = spec.Datatype
+		}
+		if err := checkShape(fmt.Sprintf("input tensor %q", input.name), input.shape, spec.Shape, false); err != nil {
+			return nil, err
+		}
+	}
+	for name := range byName {
+		return nil, newValidationError("input tensor %q is not an input of the model", name)
+	}
+	return casts, nil
+}
+
+// Ranges of the V2 numeric datatypes values can be cast to.
+var v2Ranges = map[string][2]float64{
+	"INT8":   {math.MinInt8, math.MaxInt8},
+	"INT16":  {math.MinInt16, math.MaxInt16},
+	"INT32":  {math.MinInt32, math.MaxInt32},
+	"INT64":  {math.MinInt64, math.MaxInt64},
+	"UINT8":  {0, math.MaxUint8},
+	"UINT16": {0, math.MaxUint16},
+	"UINT32": {0, math.MaxUint32},
+	"UINT64": {0, math.MaxUint64},
+	"FP32":   {-math.MaxFloat32, math.MaxFloat32},
+	"FP64":   {-math.MaxFloat64, math.MaxFloat64},
+}
+
+func castable(from string, to string) bool {
+	_, fromNumeric := v2Ranges[from]
+	_, toNumeric := v2Ranges[to]
+	return fromNumeric && toNumeric
+}
+
+// castValue checks a value fits the datatype it is cast to.
+func castValue(name string, v float64, datatype string) error {
+	r := v2Ranges[datatype]
+	if v < r[0] || v > r[1] || (datatype != "FP32" && datatype != "FP64" && v != math.Trunc(v)) {
+		return newValidationError("input tensor %q value %v can not be cast to %s", name, v, datatype)
+	}
+	return nil
+}
+
+func validateV2JsonRequest(msg payload.SeldonPayload, specs []inputSpec, cast bool) (payload.SeldonPayload, error) {
+	data, err := payload.DecompressSeldonPayload(msg)
+	if err != nil {
+		return nil, err
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, newValidationError("%s", err.Error())
+	}
+	tensors, _ := req["inputs"].([]interface{})
+	var inputs []v2Input
+	for _, t := range tensors {
+		tensor, _ := t.(map[string]interface{})
+		input := v2Input{}
+		input.name, _ = tensor["name"].(string)
+		input.datatype, _ = tensor["datatype"].(string)
+		shape, _ := tensor["shape"].([]interface{})
+		for _, d := range shape {
+			size, _ := d.(float64)
+			input.shape = append(input.shape, int64(size))
+		}
+		inputs = append(inputs, input)
+	}
+	casts, err := checkV2Inputs(inputs, specs, cast)
+	if err != nil || len(casts) == 0 {
+		return msg, err
+	}
+
+	for i, datatype := range casts {
+		tensor := tensors[i].(map[string]interface{})
+		var check func(v interface{}) error
+		check = func(v interface{}) error {
+			switch v := v.(type) {
+			case []interface{}:
+				for _, item := range v {
+					if err := check(item); err != nil {
+						return err
+					}
+				}
+				return nil
+			case float64:
+				return castValue(inputs[i].name, v, datatype)
+			}
+			return newValidationError("input tensor %q value %v can not be cast to %s", inputs[i].name, v, datatype)
+		}
+		if err := check(tensor["data"]); err != nil {
+			return nil, err
+		}
+		tensor["datatype"] = datatype
+	}
+	data, err = json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.BytesPayload{Msg: data, ContentType: msg.GetContentType()}, nil
+}
+
+func validateV2ProtoRequest(req *inference.ModelInferRequest, specs []inputSpec, cast bool) error {
+	var inputs []v2Input
+	for _, t := range req.Inputs {
+		inputs = append(inputs, v2Input{name: t.Name, datatype: t.Datatype, shape: t.Shape})
+	}
+	casts, err := checkV2Inputs(inputs, specs, cast)
+	if err != nil {
+		return err
+	}
+	for i, datatype := range casts {
+		tensor := req.Inputs[i]
+		if len(req.RawInputContents) > 0 {
+			return newValidationError("input tensor %q with raw contents can not be cast to %s", tensor.Name, datatype)
+		}
+		values := contentsValues(tensor.Contents)
+		contents := &inference.InferTensorContents{}
+		for _, v := range values {
+			if err := castValue(tensor.Name, v, datatype); err != nil {
+				return err
+			}
+			switch datatype {
+			case "INT8", "INT16", "INT32":
+				contents.IntContents = append(contents.IntContents, int32(v))
+			case "INT64":
+				contents.Int64Contents = append(contents.Int64Contents, int64(v))
+			case "UINT8", "UINT16", "UINT32":
+				contents.UintContents = append(contents.UintContents, uint32(v))
+			case "UINT64":
+				contents.Uint64Contents = append(contents.Uint64Contents, uint64(v))
+			case "FP32":
+				contents.Fp32Contents = append(contents.Fp32Contents, float32(v))
+			case "FP64":
+				contents.Fp64Contents = append(contents.Fp64Contents, v)
+			}
+		}
+		tensor.Datatype = datatype
+		tensor.Contents = contents
+	}
+	return nil
+}
+
+func contentsValues(c *inference.InferTensorContents) []float64 {
+	var values []float64
+	for _, v := range c.GetIntContents() {
+		values = append(values, float64(v))
+	}
+	for _, v := range c.GetInt64Contents() {
+		values = append(values, float64(v))
+	}
+	for _, v := range c.GetUintContents() {
+		values = append(values, float64(v))
+	}
+	for _, v := range c.GetUint64Contents() {
+		values = append(values, float64(v))
+	}
+	for _, v := range c.GetFp32Contents() {
+		values = append(values, float64(v))
+	}
+	return append(values, c.GetFp64Contents()...)
+}
@@ -0,0 +1,221 @@
+package predictor
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createValidationProcess(t *testing.T, protocol string, cast bool, inputs interface{}) (*PredictorProcess, *v1.PredictiveUnit) {
+	SetDeploymentProtocol(protocol)
+	SetRequestValidation(true, cast)
+	t.Cleanup(func() {
+		SetDeploymentProtocol("")
+		SetRequestValidation(false, false)
+	})
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000},
+	}
+	client := &test.SeldonMessageTestClient{
+		ModelMetadataMap: map[string]payload.ModelMetadata{"model": {Name: "model", Inputs: inputs}},
+	}
+	serverUrl, _ := url.Parse(testSourceUrl)
+	ctx := context.WithValue(context.TODO(), payload.SeldonPUIDHeader, testSeldonPuid)
+	pp := NewPredictorProcess(ctx, client, logf.Log.WithName("test"), serverUrl, "default", map[string][]string{}, "")
+	return &pp, graph
+}
+
+func TestValidateSeldonRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pp, graph := createValidationProcess(t, api.ProtocolSeldon, false, []interface{}{
+		map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"names": []string{"a", "b"}, "shape": []int{2}}},
+	})
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"names":["a","b"],"ndarray":[[1,2],[3,4]]}}`), ContentType: "application/json"}
+	res, err := pp.ValidateRequest(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).To(BeIdenticalTo(msg))
+
+	for data, expected := range map[string]string{
+		`{"data":{"ndarray":[[1,2,3]]}}`:                     "invalid request: request data dimension 1 is 3 but the model expects 2",
+		`{"data":{"names":["b","a"],"ndarray":[[1,2]]}}`:     "invalid request: request names [b a] do not match the model's input names [a b]",
+		`{"data":{"tensor":{"shape":[1,2],"values":[1,2]}}}`: "invalid request: request has tensor but the model expects ndarray",
+		`{"strData":"hello"}`:                                "invalid request: request has strData but the model expects ndarray",
+		`{"data":{"ndarray":[[[1,2]]]}}`:                     "invalid request: request data has 3 dimensions but the model expects 1",
+	} {
+		msg := &payload.BytesPayload{Msg: []byte(data), ContentType: "application/json"}
+		_, err := pp.ValidateRequest(graph, msg)
+		g.Expect(err).ToNot(BeNil(), data)
+		g.Expect(err.Error()).To(Equal(expected), data)
+		g.Expect(err.(*validationError).HttpStatusCode()).To(Equal(400))
+	}
+}
+
+func TestValidateV2Request(t *testing.T) {
+	g := NewGomegaWithT(t)
+	inputs := []MetadataTensor{{Name: "x", DataType: "FP32", Shape: []int{-1, 2}}}
+	pp, graph := createValidationProcess(t, api.ProtocolV2, false, inputs)
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"inputs":[{"name":"x","datatype":"FP32","shape":[3,2],"data":[1,2,3,4,5,6]}]}`), ContentType: "application/json"}
+	_, err := pp.ValidateRequest(graph, msg)
+	g.Expect(err).Should(BeNil())
+
+	for data, expected := range map[string]string{
+		`{"inputs":[{"name":"x","datatype":"FP32","shape":[1,3],"data":[1,2,3]}]}`:                                                     `invalid request: input tensor "x" dimension 1 is 3 but the model expects 2`,
+		`{"inputs":[{"name":"x","datatype":"INT64","shape":[1,2],"data":[1,2]}]}`:                                                      `invalid request: input tensor "x" has datatype INT64 but the model expects FP32`,
+		`{"inputs":[{"name":"y","datatype":"FP32","shape":[1,2],"data":[1,2]}]}`:                                                       `invalid request: input tensor "x" is missing`,
+		`{"inputs":[{"name":"x","datatype":"FP32","shape":[1,2],"data":[1,2]},{"name":"z","datatype":"FP32","shape":[1],"data":[1]}]}`: `invalid request: input tensor "z" is not an input of the model`,
+	} {
+		msg := &payload.BytesPayload{Msg: []byte(data), ContentType: "application/json"}
+		_, err := pp.ValidateRequest(graph, msg)
+		g.Expect(err).ToNot(BeNil(), data)
+		g.Expect(err.Error()).To(Equal(expected), data)
+	}
+
+	req := &inference.ModelInferRequest{Inputs: []*inference.ModelInferRequest_InferInputTensor{{Name: "x", Datatype: "FP32", Shape: []int64{2}}}}
+	_, err = pp.ValidateRequest(graph, &payload.ProtoPayload{Msg: req})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.(*validationError).GRPCStatus().Message()).To(Equal(`invalid request: input tensor "x" has 1 dimensions but the model expects 2`))
+}
+
+func TestCastV2Request(t *testing.T) {
+	g := NewGomegaWithT(t)
+	inputs := []MetadataTensor{{Name: "x", DataType: "INT32", Shape: []int{-1}}}
+	pp, graph := createValidationProcess(t, api.ProtocolV2, true, inputs)
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"id":"1","inputs":[{"name":"x","datatype":"FP64","shape":[2],"data":[1,2]}]}`), ContentType: "application/json"}
+	res, err := pp.ValidateRequest(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(res.GetPayload().([]byte))).To(MatchJSON(`{"id":"1","inputs":[{"name":"x","datatype":"INT32","shape":[2],"data":[1,2]}]}`))
+
+	msg = &payload.BytesPayload{Msg: []byte(`{"inputs":[{"name":"x","datatype":"FP64","shape":[2],"data":[1.5,2]}]}`), ContentType: "application/json"}
+	_, err = pp.ValidateRequest(graph, msg)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.Error()).To(Equal(`invalid request: input tensor "x" value 1.5 can not be cast to INT32`))
+
+	req := &inference.ModelInferRequest{Inputs: []*inference.ModelInferRequest_InferInputTensor{
+		{Name: "x", Datatype: "FP32", Shape: []int64{2}, Contents: &inference.InferTensorContents{Fp32Contents: []float32{3, 4}}},
+	}}
+	_, err = pp.ValidateRequest(graph, &payload.ProtoPayload{Msg: req})
+	g.Expect(err).Should(BeNil())
+	g.Expect(req.Inputs[0].Datatype).To(Equal("INT32"))
+	g.Expect(req.Inputs[0].Contents.IntContents).To(Equal([]int32{3, 4}))
+	g.Expect(req.Inputs[0].Contents.Fp32Contents).To(BeEmpty())
+
+	// Booleans and strings are never cast
+	msg = &payload.BytesPayload{Msg: []byte(`{"inputs":[{"name":"x","datatype":"BOOL","shape":[1],"data":[true]}]}`), ContentType: "application/json"}
+	_, err = pp.ValidateRequest(graph, msg)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestValidateRequestWithoutMetadata(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pp, graph := createValidationProcess(t, api.ProtocolSeldon, false, nil)
+	pp.Client = &test.SeldonMessageTestClient{}
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2,3]]}}`), ContentType: "application/json"}
+	res, err := pp.ValidateRequest(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).To(BeIdenticalTo(msg))
+}
+
+func TestValidateRequestWithBuiltInRoot(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pp, model := createValidationProcess(t, api.ProtocolSeldon, false, []interface{}{
+		map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"shape": []int{2}}},
+	})
+	combiner := v1.AVERAGE_COMBINER
+	graph := &v1.PredictiveUnit{
+		Name:           "combiner",
+		Implementation: &combiner,
+		Children:       []v1.PredictiveUnit{*model},
+	}
+
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2]]}}`), ContentType: "application/json"}
+	res, err := pp.ValidateRequest(graph, msg)
+	g.Expect(err).Should(BeNil())
+	g.Expect(res).To(BeIdenticalTo(msg))
+
+	msg = &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2,3]]}}`), ContentType: "application/json"}
+	_, err = pp.ValidateRequest(graph, msg)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.Error()).To(Equal("invalid request: request data dimension 1 is 3 but the model expects 2"))
+}
+
+// metadataCountingClient counts metadata calls, which wait for release, and fails them once their context is done.
+type metadataCountingClient struct {
+	test.SeldonMessageTestClient
+	calls   int32
+	release chan struct{}
+}
+
+func (c *metadataCountingClient) ModelMetadata(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.ModelMetadata, error) {
+	atomic.AddInt32(&c.calls, 1)
+	<-c.release
+	if err := ctx.Err(); err != nil {
+		return payload.ModelMetadata{}, err
+	}
+	return c.SeldonMessageTestClient.ModelMetadata(ctx, modelName, host, port, msg, meta)
+}
+
+func TestValidateRequestFetchesMetadataOnce(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pp, graph := createValidationProcess(t, api.ProtocolSeldon, false, []interface{}{
+		map[string]interface{}{"messagetype": "ndarray", "schema": map[string]interface{}{"shape": []int{2}}},
+	})
+	client := &metadataCountingClient{SeldonMessageTestClient: *pp.Client.(*test.SeldonMessageTestClient), release: make(chan struct{})}
+	pp.Client = client
+
+	// The first request is cancelled while waiting but the metadata is still fetched for the ones after it
+	cancelled := *pp
+	ctx, cancel := context.WithCancel(pp.Ctx)
+	cancel()
+	cancelled.Ctx = ctx
+	msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2,3]]}}`), ContentType: "application/json"}
+	_, err := cancelled.ValidateRequest(graph, msg)
+	g.Expect(err).To(MatchError(context.Canceled))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msg := &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1,2,3]]}}`), ContentType: "application/json"}
+			_, errs[i] = pp.ValidateRequest(graph, msg)
+		}(i)
+	}
+	close(client.release)
+	wg.Wait()
+
+	g.Expect(atomic.LoadInt32(&client.calls)).To(Equal(int32(1)))
+	for _, err := range errs {
+		g.Expect(err).ToNot(BeNil())
+		g.Expect(err.Error()).To(Equal("invalid request: request data dimension 1 is 3 but the model expects 2"))
+	}
+
+	// The inputs are fetched again for a new graph
+	SetActivePredictor(&v1.PredictorSpec{Name: "p", Graph: *graph})
+	t.Cleanup(func() {
+		activePredictorMutex.Lock()
+		defer activePredictorMutex.Unlock()
+		activePredictor = nil
+	})
+	_, err = pp.ValidateRequest(graph, msg)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(atomic.LoadInt32(&client.calls)).To(Equal(int32(2)))
+}
@@ -41,8 +41,8 @@ func addDefaultsToGraph(pu *PredictiveUnit) {
 		ty := MODEL
 		pu.Type = &ty
 	}
-	for i := 0; i < len(pu.Children); i++ {
-		addDefaultsToGraph(&pu.Children[i])
+	for _, sub := range GetSubUnits(pu) {
+		addDefaultsToGraph(sub)
 	}
 }
 
@@ -261,11 +261,11 @@ func (r *SeldonDeploymentSpec) DefaultSeldonDeployment(mldepName string, namespa
 
 				r.setContainerPredictiveUnitDefaults(compSpecIdx, httpPortNum, grpcPortNum, &nextMetricsPortNum, mldepName, namespace, &p, pu, con)
 				//Only set image default for non tensorflow graphs
-				if r.Protocol != ProtocolTensorflow {
+				if pu.GetProtocol(r.Protocol) != ProtocolTensorflow {
 					serverConfig := GetPrepackServerConfig(string(*pu.Implementation))
 					if serverConfig != nil {
 						if con.Image == "" {
-							con.Image = serverConfig.PrepackImageName(r.Protocol, pu)
+							con.Image = serverConfig.PrepackImageName(pu.GetProtocol(r.Protocol), pu)
 						}
 					}
 				}
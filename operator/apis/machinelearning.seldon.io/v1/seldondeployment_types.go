@@ -164,12 +164,35 @@ func getPredictorKeyAutoGenerated(mlDep *SeldonDeployment, p *PredictorSpec) str
 	}
 }
 
+// GetSubUnits returns the units directly below pu in the graph: its children followed by its fallback and shadow.
+func GetSubUnits(pu *PredictiveUnit) []*PredictiveUnit {
+	units := make([]*PredictiveUnit, 0, len(pu.Children)+2)
+	for i := 0; i < len(pu.Children); i++ {
+		units = append(units, &pu.Children[i])
+	}
+	if pu.Fallback != nil {
+		units = append(units, pu.Fallback)
+	}
+	if pu.Shadow != nil {
+		units = append(units, &pu.Shadow.Unit)
+	}
+	return units
+}
+
+// GetProtocol returns the protocol of the unit, which is the deployment's protocol unless the unit overrides it.
+func (pu *PredictiveUnit) GetProtocol(deploymentProtocol Protocol) Protocol {
+	if pu.Protocol != "" {
+		return pu.Protocol
+	}
+	return deploymentProtocol
+}
+
 func GetPredictiveUnit(pu *PredictiveUnit, name string) *PredictiveUnit {
 	if name == pu.Name {
 		return pu
 	} else {
-		for i := 0; i < len(pu.Children); i++ {
-			found := GetPredictiveUnit(&pu.Children[i], name)
+		for _, sub := range GetSubUnits(pu) {
+			found := GetPredictiveUnit(sub, name)
 			if found != nil {
 				return found
 			}
@@ -183,8 +206,8 @@ func GetEnginePredictiveUnit(pu *PredictiveUnit) *PredictiveUnit {
 	if pu.Endpoint != nil && pu.Endpoint.ServiceHost == "localhost" {
 		return pu
 	} else {
-		for i := 0; i < len(pu.Children); i++ {
-			found := GetEnginePredictiveUnit(&pu.Children[i])
+		for _, sub := range GetSubUnits(pu) {
+			found := GetEnginePredictiveUnit(sub)
 			if found != nil {
 				return found
 			}
@@ -196,8 +219,7 @@ func GetEnginePredictiveUnit(pu *PredictiveUnit) *PredictiveUnit {
 func GetPredictiveUnitList(p *PredictiveUnit) (list []*PredictiveUnit) {
 	list = append(list, p)
 
-	for i := 0; i < len(p.Children); i++ {
-		pu := &p.Children[i]
+	for _, pu := range GetSubUnits(p) {
 		list = append(list, GetPredictiveUnitList(pu)...)
 	}
 	return list
@@ -546,6 +568,9 @@ const (
 	SIMPLE_ROUTER          PredictiveUnitImplementation = "SIMPLE_ROUTER"
 	RANDOM_ABTEST          PredictiveUnitImplementation = "RANDOM_ABTEST"
 	AVERAGE_COMBINER       PredictiveUnitImplementation = "AVERAGE_COMBINER"
+	EPSILON_GREEDY         PredictiveUnitImplementation = "EPSILON_GREEDY"
+	THOMPSON_SAMPLING      PredictiveUnitImplementation = "THOMPSON_SAMPLING"
+	CONDITIONAL_ROUTER     PredictiveUnitImplementation = "CONDITIONAL_ROUTER"
 )
 
 type PredictiveUnitMethod string
@@ -602,6 +627,108 @@ type PredictiveUnit struct {
 	EnvSecretRefName        string                        `json:"envSecretRefName,omitempty" protobuf:"bytes,10,opt,name=envSecretRefName"`
 	StorageInitializerImage string                        `json:"storageInitializerImage,omitempty" protobuf:"bytes,11,opt,name=storageInitializerImage"`
 	Logger                  *Logger                       `json:"logger,omitempty" protobuf:"bytes,12,opt,name=logger"`
+	// Timeout in milliseconds for each call the executor makes to this unit
+	// +optional
+	TimeoutMs *int32 `json:"timeoutMs,omitempty" protobuf:"int32,13,opt,name=timeoutMs"`
+	// Retries for failed calls to this unit
+	// +optional
+	Retries *RetryPolicy `json:"retries,omitempty" protobuf:"bytes,14,opt,name=retries"`
+	// Unit called with the same input if this unit fails
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Fallback *PredictiveUnit `json:"fallback,omitempty" protobuf:"bytes,15,opt,name=fallback"`
+	// Circuit breaker that stops calls to this unit after repeated failures
+	// +optional
+	CircuitBreaker *CircuitBreaker `json:"circuitBreaker,omitempty" protobuf:"bytes,16,opt,name=circuitBreaker"`
+	// Maximum number of concurrent calls to this unit. Further calls are rejected.
+	// +optional
+	MaxInFlight *int32 `json:"maxInFlight,omitempty" protobuf:"int32,17,opt,name=maxInFlight"`
+	// Duplicate calls sent to this unit when it is slow to reply
+	// +optional
+	Hedging *HedgingPolicy `json:"hedging,omitempty" protobuf:"bytes,18,opt,name=hedging"`
+	// Cache of the responses of this unit to identical requests
+	// +optional
+	Cache *CachePolicy `json:"cache,omitempty" protobuf:"bytes,19,opt,name=cache"`
+	// Merging of concurrent prediction requests to this unit into batches
+	// +optional
+	Batching *BatchingPolicy `json:"batching,omitempty" protobuf:"bytes,20,opt,name=batching"`
+	// Protocol of this unit when it differs from the deployment's. Payloads are translated to and from it on calls to
+	// the unit.
+	// +optional
+	Protocol Protocol `json:"protocol,omitempty" protobuf:"bytes,21,opt,name=protocol"`
+	// Unit sent a copy of a sample of the requests to this unit. Its responses are logged and discarded.
+	// +optional
+	Shadow *ShadowUnit `json:"shadow,omitempty" protobuf:"bytes,22,opt,name=shadow"`
+}
+
+// RetryPolicy controls how the executor retries failed calls to a predictive unit
+type RetryPolicy struct {
+	// Maximum number of retries after the first attempt
+	MaxRetries int32 `json:"maxRetries" protobuf:"int32,1,opt,name=maxRetries"`
+	// Delay in milliseconds before the first retry, doubled for each further retry
+	// +optional
+	BackoffMs int32 `json:"backoffMs,omitempty" protobuf:"int32,2,opt,name=backoffMs"`
+	// Maximum delay in milliseconds between retries
+	// +optional
+	MaxBackoffMs int32 `json:"maxBackoffMs,omitempty" protobuf:"int32,3,opt,name=maxBackoffMs"`
+	// HTTP status codes or gRPC code names to retry. Defaults to 502, 503, 504 and UNAVAILABLE.
+	// Calls failing without a status, such as connection errors and timeouts, are always retried.
+	// +optional
+	RetryableStatus []string `json:"retryableStatus,omitempty" protobuf:"bytes,4,opt,name=retryableStatus"`
+}
+
+// CircuitBreaker controls when the executor stops calling a failing predictive unit
+type CircuitBreaker struct {
+	// Number of consecutive failed calls that opens the circuit
+	FailureThreshold int32 `json:"failureThreshold" protobuf:"int32,1,opt,name=failureThreshold"`
+	// Time in milliseconds the circuit stays open before trial calls are allowed. Defaults to 30000.
+	// +optional
+	OpenMs int32 `json:"openMs,omitempty" protobuf:"int32,2,opt,name=openMs"`
+	// Number of trial calls that must succeed to close the circuit again. Defaults to 1.
+	// +optional
+	HalfOpenRequests int32 `json:"halfOpenRequests,omitempty" protobuf:"int32,3,opt,name=halfOpenRequests"`
+}
+
+// HedgingPolicy controls when the executor sends a duplicate call to a slow predictive unit. The first reply is used
+// and the other calls are cancelled.
+type HedgingPolicy struct {
+	// Delay in milliseconds without a reply after which a duplicate call is sent
+	DelayMs int32 `json:"delayMs" protobuf:"int32,1,opt,name=delayMs"`
+	// Maximum number of duplicate calls, each sent after a further delay. Defaults to 1.
+	// +optional
+	MaxHedges int32 `json:"maxHedges,omitempty" protobuf:"int32,2,opt,name=maxHedges"`
+}
+
+// CachePolicy controls the executor's in-memory cache of the responses of a predictive unit
+type CachePolicy struct {
+	// Time in milliseconds a response is kept in the cache
+	TtlMs int32 `json:"ttlMs" protobuf:"int32,1,opt,name=ttlMs"`
+	// Maximum number of responses kept in the cache. The least recently used are evicted first. Defaults to 1000.
+	// +optional
+	MaxEntries int32 `json:"maxEntries,omitempty" protobuf:"int32,2,opt,name=maxEntries"`
+}
+
+// BatchingPolicy controls how the executor merges concurrent prediction requests to a model along their first
+// dimension into a single call
+type BatchingPolicy struct {
+	// Maximum number of rows in a batch
+	MaxBatchSize int32 `json:"maxBatchSize" protobuf:"int32,1,opt,name=maxBatchSize"`
+	// Maximum time in milliseconds a request waits for a batch to fill. Defaults to 10.
+	// +optional
+	MaxLatencyMs int32 `json:"maxLatencyMs,omitempty" protobuf:"int32,2,opt,name=maxLatencyMs"`
+}
+
+// ShadowUnit is a predictive unit called in the background with the same input as the unit it shadows. Its responses
+// are sent to the request logger and otherwise discarded.
+type ShadowUnit struct {
+	// Percentage of requests to send to the shadow. Defaults to 100.
+	// +optional
+	Percent *int32 `json:"percent,omitempty" protobuf:"int32,1,opt,name=percent"`
+	// The shadow unit
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Unit PredictiveUnit `json:"unit" protobuf:"bytes,2,opt,name=unit"`
 }
 
 type LoggerMode string
@@ -10,8 +10,11 @@ the Change License after the Change Date as each is defined in accordance with t
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
@@ -102,7 +105,7 @@ func (r *SeldonDeploymentSpec) checkPredictiveUnits(pu *PredictiveUnit, p *Predi
 		c := GetContainerForPredictiveUnit(p, pu.Name)
 
 		//Current non tensorflow serving prepack servers can not handle tensorflow protocol
-		if r.Protocol == ProtocolTensorflow && (*pu.Implementation == PrepackSklearnName || *pu.Implementation == PrepackXGBoostName || *pu.Implementation == PrepackMLFlowName || *pu.Implementation == PrepackHuggingFaceName) {
+		if pu.GetProtocol(r.Protocol) == ProtocolTensorflow && (*pu.Implementation == PrepackSklearnName || *pu.Implementation == PrepackXGBoostName || *pu.Implementation == PrepackMLFlowName || *pu.Implementation == PrepackHuggingFaceName) {
 			allErrs = append(allErrs, field.Invalid(fldPath, pu.Name, "Prepackaged server does not handle tensorflow protocol "+string(*pu.Implementation)))
 		}
 
@@ -127,10 +130,224 @@ func (r *SeldonDeploymentSpec) checkPredictiveUnits(pu *PredictiveUnit, p *Predi
 		}
 	}
 
+	allErrs = checkResilience(pu, fldPath, allErrs)
+
+	if pu.Protocol != "" {
+		allErrs = r.checkUnitProtocol(pu, fldPath, allErrs)
+	}
+
+	if pu.Implementation != nil && *pu.Implementation == CONDITIONAL_ROUTER {
+		allErrs = checkConditionalRouter(pu, fldPath, allErrs)
+	}
+
+	if pu.Implementation != nil && *pu.Implementation == EPSILON_GREEDY {
+		allErrs = checkEpsilonGreedy(pu, fldPath, allErrs)
+	}
+
 	for i := 0; i < len(pu.Children); i++ {
 		allErrs = r.checkPredictiveUnits(&pu.Children[i], p, fldPath.Index(i), allErrs)
 	}
 
+	if pu.Fallback != nil {
+		allErrs = r.checkPredictiveUnits(pu.Fallback, p, fldPath.Child("fallback"), allErrs)
+	}
+
+	if pu.Shadow != nil {
+		shadowPath := fldPath.Child("shadow")
+		if pu.Shadow.Percent != nil && (*pu.Shadow.Percent < 0 || *pu.Shadow.Percent > 100) {
+			allErrs = append(allErrs, field.Invalid(shadowPath.Child("percent"), *pu.Shadow.Percent, "Shadow percent must be between 0 and 100"))
+		}
+		allErrs = r.checkPredictiveUnits(&pu.Shadow.Unit, p, shadowPath.Child("unit"), allErrs)
+	}
+
+	return allErrs
+}
+
+func isV2Protocol(protocol Protocol) bool {
+	return protocol == ProtocolV2 || protocol == ProtocolKFServing
+}
+
+// Check a unit's protocol override can be translated to and from the deployment's protocol.
+func (r *SeldonDeploymentSpec) checkUnitProtocol(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	protocolPath := fldPath.Child("protocol")
+	if !(pu.Protocol == ProtocolSeldon || pu.Protocol == ProtocolTensorflow || pu.Protocol == ProtocolKFServing || pu.Protocol == ProtocolV2) {
+		return append(allErrs, field.Invalid(protocolPath, pu.Protocol, "Invalid protocol"))
+	}
+	deploymentProtocol := r.Protocol
+	if deploymentProtocol == "" {
+		deploymentProtocol = ProtocolSeldon
+	}
+	if pu.Protocol == deploymentProtocol || (isV2Protocol(pu.Protocol) && isV2Protocol(deploymentProtocol)) {
+		return allErrs
+	}
+	if !(pu.Protocol == ProtocolSeldon && isV2Protocol(deploymentProtocol)) && !(isV2Protocol(pu.Protocol) && deploymentProtocol == ProtocolSeldon) {
+		return append(allErrs, field.Invalid(protocolPath, pu.Protocol, "Units can only override the protocol to translate between the seldon and v2 protocols"))
+	}
+	// Routing decisions and combined payloads have no V2 form
+	if (pu.Type != nil && (*pu.Type == ROUTER || *pu.Type == COMBINER)) ||
+		(pu.Methods != nil && (hasUnitMethod(pu, ROUTE) || hasUnitMethod(pu, AGGREGATE))) {
+		allErrs = append(allErrs, field.Invalid(protocolPath, pu.Protocol, "Routers and combiners can not override the protocol"))
+	}
+	return allErrs
+}
+
+func hasUnitMethod(pu *PredictiveUnit, method PredictiveUnitMethod) bool {
+	for _, m := range *pu.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Check the rules and default route of a CONDITIONAL_ROUTER refer to existing children.
+func checkConditionalRouter(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	checkChild := func(path *field.Path, child int) {
+		if child < 0 || child >= len(pu.Children) {
+			allErrs = append(allErrs, field.Invalid(path, child, "Route must be the index of a child"))
+		}
+	}
+	foundRules := false
+	for i, param := range pu.Parameters {
+		path := fldPath.Child("parameters").Index(i).Child("value")
+		switch param.Name {
+		case "rules":
+			foundRules = true
+			var rules []struct {
+				When  string `json:"when"`
+				Child int    `json:"child"`
+			}
+			if err := json.Unmarshal([]byte(param.Value), &rules); err != nil {
+				allErrs = append(allErrs, field.Invalid(path, param.Value, "Rules must be a JSON list of objects with when and child fields"))
+				continue
+			}
+			for _, rule := range rules {
+				if rule.When == "" {
+					allErrs = append(allErrs, field.Invalid(path, param.Value, "Each rule needs a when expression"))
+				}
+				checkChild(path, rule.Child)
+			}
+		case "default":
+			if child, err := strconv.Atoi(param.Value); err != nil {
+				allErrs = append(allErrs, field.Invalid(path, param.Value, "Default route must be an integer"))
+			} else {
+				checkChild(path, child)
+			}
+		}
+	}
+	if !foundRules {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters"), pu.Name, "CONDITIONAL_ROUTER requires a rules parameter"))
+	}
+	return allErrs
+}
+
+// Check the epsilon of an EPSILON_GREEDY router is a probability.
+func checkEpsilonGreedy(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	for i, param := range pu.Parameters {
+		if param.Name != "epsilon" {
+			continue
+		}
+		if epsilon, err := strconv.ParseFloat(param.Value, 64); err != nil || epsilon < 0 || epsilon > 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("parameters").Index(i).Child("value"), param.Value, "Epsilon must be a number between 0 and 1"))
+		}
+	}
+	return allErrs
+}
+
+var grpcCodeNames = map[string]bool{
+	"CANCELLED":           true,
+	"UNKNOWN":             true,
+	"INVALID_ARGUMENT":    true,
+	"DEADLINE_EXCEEDED":   true,
+	"NOT_FOUND":           true,
+	"ALREADY_EXISTS":      true,
+	"PERMISSION_DENIED":   true,
+	"RESOURCE_EXHAUSTED":  true,
+	"FAILED_PRECONDITION": true,
+	"ABORTED":             true,
+	"OUT_OF_RANGE":        true,
+	"UNIMPLEMENTED":       true,
+	"INTERNAL":            true,
+	"UNAVAILABLE":         true,
+	"DATA_LOSS":           true,
+	"UNAUTHENTICATED":     true,
+}
+
+// Check the timeout, retry, circuit breaker, concurrency and hedging settings of a predictive unit.
+func checkResilience(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if pu.TimeoutMs != nil && *pu.TimeoutMs <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("timeoutMs"), *pu.TimeoutMs, "Timeout must be greater than 0"))
+	}
+
+	if pu.Retries != nil {
+		retriesPath := fldPath.Child("retries")
+		if pu.Retries.MaxRetries < 0 {
+			allErrs = append(allErrs, field.Invalid(retriesPath.Child("maxRetries"), pu.Retries.MaxRetries, "Retries can not be negative"))
+		}
+		if pu.Retries.BackoffMs < 0 {
+			allErrs = append(allErrs, field.Invalid(retriesPath.Child("backoffMs"), pu.Retries.BackoffMs, "Backoff can not be negative"))
+		}
+		if pu.Retries.MaxBackoffMs < 0 || (pu.Retries.MaxBackoffMs > 0 && pu.Retries.MaxBackoffMs < pu.Retries.BackoffMs) {
+			allErrs = append(allErrs, field.Invalid(retriesPath.Child("maxBackoffMs"), pu.Retries.MaxBackoffMs, "Max backoff must be at least the backoff"))
+		}
+		for i, status := range pu.Retries.RetryableStatus {
+			if code, err := strconv.Atoi(status); err == nil {
+				if code < 100 || code > 599 {
+					allErrs = append(allErrs, field.Invalid(retriesPath.Child("retryableStatus").Index(i), status, "Invalid HTTP status code"))
+				}
+			} else if !grpcCodeNames[strings.ToUpper(status)] {
+				allErrs = append(allErrs, field.Invalid(retriesPath.Child("retryableStatus").Index(i), status, "Status must be a HTTP status code or a gRPC code name"))
+			}
+		}
+	}
+
+	if pu.CircuitBreaker != nil {
+		breakerPath := fldPath.Child("circuitBreaker")
+		if pu.CircuitBreaker.FailureThreshold <= 0 {
+			allErrs = append(allErrs, field.Invalid(breakerPath.Child("failureThreshold"), pu.CircuitBreaker.FailureThreshold, "Failure threshold must be greater than 0"))
+		}
+		if pu.CircuitBreaker.OpenMs < 0 {
+			allErrs = append(allErrs, field.Invalid(breakerPath.Child("openMs"), pu.CircuitBreaker.OpenMs, "Open time can not be negative"))
+		}
+		if pu.CircuitBreaker.HalfOpenRequests < 0 {
+			allErrs = append(allErrs, field.Invalid(breakerPath.Child("halfOpenRequests"), pu.CircuitBreaker.HalfOpenRequests, "Half open requests can not be negative"))
+		}
+	}
+
+	if pu.MaxInFlight != nil && *pu.MaxInFlight <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxInFlight"), *pu.MaxInFlight, "Max in flight must be greater than 0"))
+	}
+
+	if pu.Hedging != nil {
+		hedgingPath := fldPath.Child("hedging")
+		if pu.Hedging.DelayMs <= 0 {
+			allErrs = append(allErrs, field.Invalid(hedgingPath.Child("delayMs"), pu.Hedging.DelayMs, "Hedging delay must be greater than 0"))
+		}
+		if pu.Hedging.MaxHedges < 0 {
+			allErrs = append(allErrs, field.Invalid(hedgingPath.Child("maxHedges"), pu.Hedging.MaxHedges, "Max hedges can not be negative"))
+		}
+	}
+
+	if pu.Cache != nil {
+		cachePath := fldPath.Child("cache")
+		if pu.Cache.TtlMs <= 0 {
+			allErrs = append(allErrs, field.Invalid(cachePath.Child("ttlMs"), pu.Cache.TtlMs, "Cache TTL must be greater than 0"))
+		}
+		if pu.Cache.MaxEntries < 0 {
+			allErrs = append(allErrs, field.Invalid(cachePath.Child("maxEntries"), pu.Cache.MaxEntries, "Cache max entries can not be negative"))
+		}
+	}
+
+	if pu.Batching != nil {
+		batchingPath := fldPath.Child("batching")
+		if pu.Batching.MaxBatchSize <= 0 {
+			allErrs = append(allErrs, field.Invalid(batchingPath.Child("maxBatchSize"), pu.Batching.MaxBatchSize, "Max batch size must be greater than 0"))
+		}
+		if pu.Batching.MaxLatencyMs < 0 {
+			allErrs = append(allErrs, field.Invalid(batchingPath.Child("maxLatencyMs"), pu.Batching.MaxLatencyMs, "Max batch latency can not be negative"))
+		}
+	}
+
 	return allErrs
 }
 
@@ -165,8 +382,8 @@ func checkTraffic(spec *SeldonDeploymentSpec, fldPath *field.Path, allErrs field
 
 func sizeOfGraph(p *PredictiveUnit) int {
 	count := 0
-	for _, child := range p.Children {
-		count = count + sizeOfGraph(&child)
+	for _, sub := range GetSubUnits(p) {
+		count = count + sizeOfGraph(sub)
 	}
 	return count + 1
 }
@@ -175,11 +392,26 @@ func collectTransports(pu *PredictiveUnit, transportsFound map[EndpointType]bool
 	if pu.Endpoint != nil && pu.Endpoint.Type != "" {
 		transportsFound[pu.Endpoint.Type] = true
 	}
-	for _, c := range pu.Children {
-		collectTransports(&c, transportsFound)
+	for _, sub := range GetSubUnits(pu) {
+		collectTransports(sub, transportsFound)
 	}
 }
 
+func checkSingleTransport(r *SeldonDeploymentSpec, transports map[EndpointType]bool, allErrs field.ErrorList) field.ErrorList {
+	if len(transports) > 1 {
+		fldPath := field.NewPath("spec")
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "Multiple endpoint.types found - can only have 1 type in graph with the tensorflow protocol. Please use spec.transport"))
+	} else if len(transports) == 1 && r.Transport != "" {
+		for k := range transports {
+			if (k == REST && r.Transport != TransportRest) || (k == GRPC && r.Transport != TransportGrpc) {
+				fldPath := field.NewPath("spec")
+				allErrs = append(allErrs, field.Invalid(fldPath, "", "Mixed transport types found. Remove graph endpoint.types if transport set at deployment level"))
+			}
+		}
+	}
+	return allErrs
+}
+
 const (
 	ENV_KAFKA_BROKER       = "KAFKA_BROKER"
 	ENV_KAFKA_INPUT_TOPIC  = "KAFKA_INPUT_TOPIC"
@@ -281,16 +513,9 @@ func (r *SeldonDeploymentSpec) ValidateSeldonDeployment() error {
 		allErrs = r.checkPredictiveUnits(&p.Graph, &p, field.NewPath("spec").Child("predictors").Index(i).Child("graph"), allErrs)
 	}
 
-	if len(transports) > 1 {
-		fldPath := field.NewPath("spec")
-		allErrs = append(allErrs, field.Invalid(fldPath, "", "Multiple endpoint.types found - can only have 1 type in graph. Please use spec.transport"))
-	} else if len(transports) == 1 && r.Transport != "" {
-		for k := range transports {
-			if (k == REST && r.Transport != TransportRest) || (k == GRPC && r.Transport != TransportGrpc) {
-				fldPath := field.NewPath("spec")
-				allErrs = append(allErrs, field.Invalid(fldPath, "", "Mixed transport types found. Remove graph endpoint.types if transport set at deployment level"))
-			}
-		}
+	// The executor converts payloads for nodes with another transport except for the tensorflow protocol
+	if r.Protocol == ProtocolTensorflow {
+		allErrs = checkSingleTransport(r, transports, allErrs)
 	}
 
 	allErrs = checkTraffic(r, field.NewPath("spec"), allErrs)
@@ -289,6 +289,7 @@ func TestValidateMixedTransport(t *testing.T) {
 	g := NewGomegaWithT(t)
 	impl := MODEL
 	spec := &SeldonDeploymentSpec{
+		Protocol:  ProtocolTensorflow,
 		Transport: TransportRest,
 		Predictors: []PredictorSpec{
 			{
@@ -343,6 +344,7 @@ func TestValidateMixedMultipleTransport(t *testing.T) {
 	g := NewGomegaWithT(t)
 	impl := MODEL
 	spec := &SeldonDeploymentSpec{
+		Protocol:  ProtocolTensorflow,
 		Transport: TransportRest,
 		Predictors: []PredictorSpec{
 			{
@@ -1559,3 +1561,400 @@ func TestSvcNameAnnotationNotDuplicate(t *testing.T) {
 	err = spec.ValidateSeldonDeployment()
 	g.Expect(err).To(BeNil())
 }
+
+func createGraphTestSpec(pu PredictiveUnit) *SeldonDeploymentSpec {
+	return &SeldonDeploymentSpec{
+		Predictors: []PredictorSpec{
+			{
+				Name: "p1",
+				ComponentSpecs: []*SeldonPodSpec{
+					{
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier",
+								},
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier-fallback",
+								},
+							},
+						},
+					},
+				},
+				Graph: pu,
+			},
+		},
+	}
+}
+
+func TestValidateResilience(t *testing.T) {
+	g := NewGomegaWithT(t)
+	timeout := int32(500)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name:      "classifier",
+		TimeoutMs: &timeout,
+		Retries: &RetryPolicy{
+			MaxRetries:      3,
+			BackoffMs:       10,
+			MaxBackoffMs:    100,
+			RetryableStatus: []string{"503", "UNAVAILABLE", "deadline_exceeded"},
+		},
+		Fallback: &PredictiveUnit{
+			Name: "classifier-fallback",
+		},
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+	g.Expect(spec.Predictors[0].Graph.Fallback.Endpoint).ToNot(BeNil())
+	g.Expect(spec.Predictors[0].Graph.Fallback.Endpoint.ServicePort).ToNot(Equal(int32(0)))
+}
+
+func TestValidateResilienceInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	timeout := int32(0)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name:      "classifier",
+		TimeoutMs: &timeout,
+		Retries: &RetryPolicy{
+			MaxRetries:      -1,
+			BackoffMs:       100,
+			MaxBackoffMs:    10,
+			RetryableStatus: []string{"700", "NOT_A_CODE"},
+		},
+		Fallback: &PredictiveUnit{
+			Name: "missing",
+		},
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Code).To(Equal(int32(422)))
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.timeoutMs",
+		"spec.predictors[0].graph.retries.maxRetries",
+		"spec.predictors[0].graph.retries.maxBackoffMs",
+		"spec.predictors[0].graph.retries.retryableStatus[0]",
+		"spec.predictors[0].graph.retries.retryableStatus[1]",
+		"spec.predictors[0].graph.fallback",
+	))
+}
+
+func TestValidateShadow(t *testing.T) {
+	g := NewGomegaWithT(t)
+	percent := int32(10)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name: "classifier",
+		Shadow: &ShadowUnit{
+			Percent: &percent,
+			Unit:    PredictiveUnit{Name: "classifier-fallback"},
+		},
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+	g.Expect(spec.Predictors[0].Graph.Shadow.Unit.Endpoint).ToNot(BeNil())
+	g.Expect(spec.Predictors[0].Graph.Shadow.Unit.Endpoint.ServicePort).ToNot(Equal(int32(0)))
+}
+
+func TestValidateShadowInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	percent := int32(150)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name: "classifier",
+		Shadow: &ShadowUnit{
+			Percent: &percent,
+			Unit:    PredictiveUnit{Name: "missing"},
+		},
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.shadow.percent",
+		"spec.predictors[0].graph.shadow.unit",
+	))
+}
+
+func TestValidateCircuitBreaker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	maxInFlight := int32(10)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name: "classifier",
+		CircuitBreaker: &CircuitBreaker{
+			FailureThreshold: 5,
+			OpenMs:           1000,
+			HalfOpenRequests: 2,
+		},
+		MaxInFlight: &maxInFlight,
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+}
+
+func TestValidateCircuitBreakerInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	maxInFlight := int32(0)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name: "classifier",
+		CircuitBreaker: &CircuitBreaker{
+			FailureThreshold: 0,
+			OpenMs:           -1,
+			HalfOpenRequests: -1,
+		},
+		MaxInFlight: &maxInFlight,
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.circuitBreaker.failureThreshold",
+		"spec.predictors[0].graph.circuitBreaker.openMs",
+		"spec.predictors[0].graph.circuitBreaker.halfOpenRequests",
+		"spec.predictors[0].graph.maxInFlight",
+	))
+}
+
+func createConditionalRouterSpec(params []Parameter) *SeldonDeploymentSpec {
+	impl := CONDITIONAL_ROUTER
+	return createGraphTestSpec(PredictiveUnit{
+		Name:           "router",
+		Implementation: &impl,
+		Parameters:     params,
+		Children: []PredictiveUnit{
+			{Name: "classifier"},
+			{Name: "classifier-fallback"},
+		},
+	})
+}
+
+func TestValidateConditionalRouter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createConditionalRouterSpec([]Parameter{
+		{Name: "rules", Type: STRING, Value: `[{"when": "headers['X-Tenant'] == 'acme'", "child": 1}]`},
+		{Name: "default", Type: INT, Value: "0"},
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+}
+
+func TestValidateConditionalRouterInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createConditionalRouterSpec([]Parameter{
+		{Name: "rules", Type: STRING, Value: `[{"when": "tags.tenant == 'acme'", "child": 2}, {"child": 0}]`},
+		{Name: "default", Type: INT, Value: "-1"},
+	})
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(serr.Status().Details.Causes).To(HaveLen(3))
+
+	spec = createConditionalRouterSpec([]Parameter{
+		{Name: "rules", Type: STRING, Value: `not json`},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+
+	spec = createConditionalRouterSpec(nil)
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestValidateEpsilonGreedy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	impl := EPSILON_GREEDY
+	for epsilon, valid := range map[string]bool{"0": true, "0.1": true, "1": true, "-0.1": false, "1.5": false, "often": false} {
+		spec := createGraphTestSpec(PredictiveUnit{
+			Name:           "router",
+			Implementation: &impl,
+			Parameters:     []Parameter{{Name: "epsilon", Type: FLOAT, Value: epsilon}},
+			Children: []PredictiveUnit{
+				{Name: "classifier"},
+				{Name: "classifier-fallback"},
+			},
+		})
+		spec.DefaultSeldonDeployment("mydep", "default")
+		err := spec.ValidateSeldonDeployment()
+		if valid {
+			g.Expect(err).To(BeNil(), epsilon)
+		} else {
+			g.Expect(err).ToNot(BeNil(), epsilon)
+		}
+	}
+}
+
+func TestValidateHedging(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name:    "classifier",
+		Hedging: &HedgingPolicy{DelayMs: 50, MaxHedges: 2},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+
+	spec = createGraphTestSpec(PredictiveUnit{
+		Name:    "classifier",
+		Hedging: &HedgingPolicy{DelayMs: 0, MaxHedges: -1},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.hedging.delayMs",
+		"spec.predictors[0].graph.hedging.maxHedges",
+	))
+}
+
+func TestValidateCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name:  "classifier",
+		Cache: &CachePolicy{TtlMs: 60000, MaxEntries: 100},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+
+	spec = createGraphTestSpec(PredictiveUnit{
+		Name:  "classifier",
+		Cache: &CachePolicy{TtlMs: 0, MaxEntries: -1},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.cache.ttlMs",
+		"spec.predictors[0].graph.cache.maxEntries",
+	))
+}
+
+func TestValidateBatching(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name:     "classifier",
+		Batching: &BatchingPolicy{MaxBatchSize: 32, MaxLatencyMs: 5},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+
+	spec = createGraphTestSpec(PredictiveUnit{
+		Name:     "classifier",
+		Batching: &BatchingPolicy{MaxBatchSize: 0, MaxLatencyMs: -1},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.batching.maxBatchSize",
+		"spec.predictors[0].graph.batching.maxLatencyMs",
+	))
+}
+
+func TestValidateUnitProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+	transformer := TRANSFORMER
+	spec := createGraphTestSpec(PredictiveUnit{
+		Name: "classifier",
+		Type: &transformer,
+		Children: []PredictiveUnit{
+			{Name: "classifier-fallback", Protocol: ProtocolV2},
+		},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+
+	router := ROUTER
+	spec = createGraphTestSpec(PredictiveUnit{
+		Name:     "classifier",
+		Type:     &router,
+		Protocol: ProtocolV2,
+		Children: []PredictiveUnit{
+			{Name: "classifier-fallback", Protocol: ProtocolTensorflow},
+		},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	var fields []string
+	for _, cause := range serr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	g.Expect(fields).To(ConsistOf(
+		"spec.predictors[0].graph.protocol",
+		"spec.predictors[0].graph[0].protocol",
+	))
+}
+
+func TestValidateMixedTransports(t *testing.T) {
+	g := NewGomegaWithT(t)
+	transformer := TRANSFORMER
+	createSpec := func() *SeldonDeploymentSpec {
+		return createGraphTestSpec(PredictiveUnit{
+			Name:     "classifier",
+			Type:     &transformer,
+			Endpoint: &Endpoint{Type: REST},
+			Children: []PredictiveUnit{
+				{Name: "classifier-fallback", Endpoint: &Endpoint{Type: GRPC}},
+			},
+		})
+	}
+	spec := createSpec()
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+
+	spec = createSpec()
+	spec.Protocol = ProtocolTensorflow
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+}
@@ -89,22 +89,23 @@ func (pi *PrePackedInitialiser) addTFServerContainer(mlDepSpec *machinelearningv
 
 	c := utils.GetContainerForDeployment(deploy, pu.Name)
 
+	protocol := pu.GetProtocol(mlDepSpec.Protocol)
 	var tfServingContainer *v1.Container
-	if mlDepSpec.Protocol == machinelearningv1.ProtocolTensorflow {
+	if protocol == machinelearningv1.ProtocolTensorflow {
 		tfServingContainer = c
 	} else {
-		c.Image = serverConfig.PrepackImageName(mlDepSpec.Protocol, pu)
+		c.Image = serverConfig.PrepackImageName(protocol, pu)
 		SetUriParamsForTFServingProxyContainer(pu, c)
 		tfServingContainer = utils.GetContainerForDeployment(deploy, constants.TFServingContainerName)
 	}
 
 	existing := tfServingContainer != nil
 	if !existing {
-		tfServingContainer = createTensorflowServingContainer(mlDepSpec, pu, mlDepSpec.Protocol == machinelearningv1.ProtocolTensorflow)
+		tfServingContainer = createTensorflowServingContainer(mlDepSpec, pu, protocol == machinelearningv1.ProtocolTensorflow)
 		deploy.Spec.Template.Spec.Containers = append(deploy.Spec.Template.Spec.Containers, *tfServingContainer)
 	} else {
 		// Update any missing fields
-		protoType := createTensorflowServingContainer(mlDepSpec, pu, mlDepSpec.Protocol == machinelearningv1.ProtocolTensorflow)
+		protoType := createTensorflowServingContainer(mlDepSpec, pu, protocol == machinelearningv1.ProtocolTensorflow)
 		if tfServingContainer.Image == "" {
 			tfServingContainer.Image = protoType.Image
 		}
@@ -207,7 +208,7 @@ func (pi *PrePackedInitialiser) addTritonServer(mlDepSpec *machinelearningv1.Sel
 		TerminationMessagePath:   "/dev/termination-log",
 		TerminationMessagePolicy: v1.TerminationMessageReadFile,
 	}
-	cServer.Image = serverConfig.PrepackImageName(mlDepSpec.Protocol, pu)
+	cServer.Image = serverConfig.PrepackImageName(pu.GetProtocol(mlDepSpec.Protocol), pu)
 
 	envSecretRefName := extractEnvSecretRefName(pu)
 	if noStorage {
@@ -308,7 +309,7 @@ func (pi *PrePackedInitialiser) addModelDefaultServers(mlDepSepc *machinelearnin
 	}
 
 	if c.Image == "" {
-		c.Image = serverConfig.PrepackImageName(mlDepSepc.Protocol, pu)
+		c.Image = serverConfig.PrepackImageName(pu.GetProtocol(mlDepSepc.Protocol), pu)
 	}
 
 	// Add parameters envvar - point at mount path because initContainer will download
@@ -448,7 +449,8 @@ func (pi *PrePackedInitialiser) addModelServersAndInitContainers(mlDep *machinel
 				}
 			default:
 				// If protocol is V2, try to add container with MLServer
-				if mlDep.Spec.Protocol == machinelearningv1.ProtocolKFServing || mlDep.Spec.Protocol == machinelearningv1.ProtocolV2 {
+				protocol := pu.GetProtocol(mlDep.Spec.Protocol)
+				if protocol == machinelearningv1.ProtocolKFServing || protocol == machinelearningv1.ProtocolV2 {
 					err := pi.addMLServerDefault(pu, deploy)
 					if err != nil {
 						return err
@@ -507,8 +509,8 @@ func (pi *PrePackedInitialiser) addModelServersAndInitContainers(mlDep *machinel
 		}
 	}
 
-	for i := 0; i < len(pu.Children); i++ {
-		if err := pi.addModelServersAndInitContainers(mlDep, p, c, &pu.Children[i], podSecurityContext, log); err != nil {
+	for _, sub := range machinelearningv1.GetSubUnits(pu) {
+		if err := pi.addModelServersAndInitContainers(mlDep, p, c, sub, podSecurityContext, log); err != nil {
 			return err
 		}
 	}